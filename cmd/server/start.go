@@ -64,6 +64,11 @@ func buildCLI() *cli.Command {
 				},
 				Action: start,
 			},
+			{
+				Name:   "vapid",
+				Usage:  "generate a VAPID key pair for web push",
+				Action: generateVAPIDKeys,
+			},
 		},
 	}
 }
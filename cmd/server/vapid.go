@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
+	"github.com/urfave/cli/v3"
+)
+
+// generateVAPIDKeys prints a new key pair as environment variables. Browsers stay subscribed to the key they
+// subscribed with, replacing it invalidates every Web Push recipient.
+func generateVAPIDKeys(ctx context.Context, cmd *cli.Command) error {
+	publicKey, privateKey, err := notify.GenerateVAPIDKeys()
+	if err != nil {
+		return fmt.Errorf("generate vapid keys: %w", err)
+	}
+
+	_, err = fmt.Fprintf(cmd.Writer, "RPN_NOTIFY_WEBPUSHPUBLICKEY=%s\nRPN_NOTIFY_WEBPUSHPRIVATEKEY=%s\n", publicKey, privateKey)
+	return err
+}
@@ -1,12 +1,16 @@
 package config
 
+import "time"
+
 type (
 	Config struct {
-		Temporal    Temporal    `koanf:"temporal" validate:"required"`
-		Reddit      Reddit      `koanf:"reddit" validate:"required"`
-		Persistence Persistence `koanf:"db" validate:"required"`
-		Mailer      Mailer      `koanf:"mailer" validate:"required"`
-		Server      Server      `koanf:"server" validate:"required"`
+		Temporal    Temporal       `koanf:"temporal" validate:"required"`
+		Reddit      Reddit         `koanf:"reddit" validate:"required"`
+		Persistence Persistence    `koanf:"db" validate:"required"`
+		Mailer      []MailerConfig `koanf:"mailer" validate:"required,min=1,dive"`
+		Email       Email          `koanf:"email" validate:"required"`
+		Server      Server         `koanf:"server" validate:"required"`
+		Notify      Notify         `koanf:"notify"`
 	}
 
 	Temporal struct {
@@ -29,12 +33,91 @@ type (
 		Database string `koanf:"dbname" validate:"required"`
 	}
 
-	Mailer struct {
-		Provider      string `koanf:"provider" validate:"required,oneof=gmail resend ses mailersend scaleway plunk"`
-		SenderEmail   string `koanf:"sender" validate:"required,email"`
-		SenderName    string `koanf:"sendername" validate:"required"`
+	// MailerConfig is one mail provider. The first entry of Config.Mailer sends every message, the others take over
+	// in order if it fails with a transient error. Every entry has its own sender and credentials, so the same
+	// provider can be configured more than once, e.g. two SMTP relays or SES in two regions.
+	MailerConfig struct {
+		// Name identifies the mailer in logs and the delivery history. It defaults to the provider and has to be set
+		// if a provider is configured more than once.
+		Name        string `koanf:"name"`
+		Provider    string `koanf:"provider" validate:"required,oneof=gmail smtp resend ses mailersend scaleway plunk file log"`
+		SenderEmail string `koanf:"sender" validate:"required,email"`
+		SenderName  string `koanf:"sendername" validate:"required"`
+		AppPassword string `koanf:"gmailapppassword" validate:"required_if=Provider gmail"`
+		// The mailer is skipped for FailoverCooldown after FailoverThreshold consecutive transient failures.
+		// Defaults to 3 failures and 5 minutes.
+		FailoverThreshold int           `koanf:"failoverthreshold" validate:"omitempty,min=1"`
+		FailoverCooldown  time.Duration `koanf:"failovercooldown" validate:"omitempty,min=0"`
+		// SMTP settings for a self-hosted relay. TLS defaults to starttls, Auth defaults to plain and is skipped
+		// entirely if no username is set.
+		SMTPHost     string `koanf:"smtphost" validate:"required_if=Provider smtp"`
+		SMTPPort     int    `koanf:"smtpport" validate:"required_if=Provider smtp,omitempty,min=1,max=65535"`
+		SMTPTLS      string `koanf:"smtptls" validate:"omitempty,oneof=none starttls implicit"`
+		SMTPAuth     string `koanf:"smtpauth" validate:"omitempty,oneof=none plain login crammd5"`
+		SMTPUsername string `koanf:"smtpusername"`
+		SMTPPassword string `koanf:"smtppassword" validate:"required_with=SMTPUsername"`
+		// Resend HTTP API. The base URL only needs to be set to point the client at something other than Resend.
+		ResendAPIKey  string `koanf:"resendapikey" validate:"required_if=Provider resend"`
+		ResendBaseURL string `koanf:"resendbaseurl" validate:"omitempty,url"`
+		// Amazon SES v2. The session token is only needed for temporary credentials, the endpoint defaults to the
+		// regional SES endpoint.
+		SESAccessKeyID     string `koanf:"sesaccesskeyid" validate:"required_if=Provider ses"`
+		SESSecretAccessKey string `koanf:"sessecretaccesskey" validate:"required_if=Provider ses"`
+		SESRegion          string `koanf:"sesregion" validate:"required_if=Provider ses"`
+		SESSessionToken    string `koanf:"sessessiontoken"`
+		SESEndpoint        string `koanf:"sesendpoint" validate:"omitempty,url"`
+		// MailerSend HTTP API.
+		MailerSendAPIKey  string `koanf:"mailersendapikey" validate:"required_if=Provider mailersend"`
+		MailerSendBaseURL string `koanf:"mailersendbaseurl" validate:"omitempty,url"`
+		// Scaleway Transactional Email. Region defaults to fr-par.
+		ScalewaySecretKey string `koanf:"scalewaysecretkey" validate:"required_if=Provider scaleway"`
+		ScalewayProjectID string `koanf:"scalewayprojectid" validate:"required_if=Provider scaleway"`
+		ScalewayRegion    string `koanf:"scalewayregion" validate:"omitempty,oneof=fr-par nl-ams pl-waw"`
+		ScalewayBaseURL   string `koanf:"scalewaybaseurl" validate:"omitempty,url"`
+		// Plunk HTTP API.
+		PlunkAPIKey  string `koanf:"plunkapikey" validate:"required_if=Provider plunk"`
+		PlunkBaseURL string `koanf:"plunkbaseurl" validate:"omitempty,url"`
+		// DKIM signing of outgoing mail. Enabled by setting a domain, the key is a PEM encoded RSA or Ed25519 private
+		// key, either inline or read from a file. Only applies to providers that send the raw message (gmail, smtp,
+		// ses, file, log), the HTTP API providers sign with the DKIM setup of their own dashboard.
+		DKIMDomain         string `koanf:"dkimdomain" validate:"omitempty,fqdn"`
+		DKIMSelector       string `koanf:"dkimselector" validate:"required_with=DKIMDomain"`
+		DKIMPrivateKey     string `koanf:"dkimprivatekey" validate:"excluded_with=DKIMPrivateKeyFile"`
+		DKIMPrivateKeyFile string `koanf:"dkimprivatekeyfile"`
+		// FileDir is the directory the file provider writes one .eml per message to. Meant for development and CI,
+		// just like the log provider, which only logs a summary of every message.
+		FileDir string `koanf:"filedir" validate:"required_if=Provider file"`
+	}
+
+	// Email holds the settings of the digest emails that don't depend on the mailer sending them.
+	Email struct {
 		SubjectPrefix string `koanf:"subjectprefix" validate:"required"`
-		AppPassword   string `koanf:"gmailapppassword" validate:"required_if=Provider gmail"`
+		// DeliveryMode controls how a digest with several recipients is sent: one message per recipient (individual,
+		// default), one message with all recipients in Bcc (bcc) or one message with all recipients in To (shared).
+		DeliveryMode string `koanf:"deliverymode" validate:"omitempty,oneof=individual bcc shared"`
+		// InlineThumbnails downloads the post thumbnails while sending and embeds them into the digest instead of
+		// hotlinking them from Reddit. Thumbnails above InlineThumbnailMaxSize bytes (default 256 KiB), beyond
+		// InlineThumbnailMaxTotal bytes per digest (default 2 MiB) or failing to download stay links.
+		InlineThumbnails        bool  `koanf:"inlinethumbnails"`
+		InlineThumbnailMaxSize  int64 `koanf:"inlinethumbnailmaxsize" validate:"omitempty,min=1"`
+		InlineThumbnailMaxTotal int64 `koanf:"inlinethumbnailmaxtotal" validate:"omitempty,min=1"`
+		// TemplateDir overrides the embedded email templates with the *.html files of a directory. The files are
+		// parsed on top of the defaults, so they only need to define the templates they change, e.g. just "post".
+		TemplateDir string `koanf:"templatedir" validate:"omitempty,dir"`
+		// Bounce and complaint webhooks of the app service. Each endpoint stays disabled until it's configured: SES
+		// notifications are only accepted from the listed SNS topics, Resend and MailerSend need their signing secret.
+		SESTopicARNs            List   `koanf:"sestopicarns" validate:"omitempty,dive,startswith=arn:"`
+		ResendWebhookSecret     string `koanf:"resendwebhooksecret" validate:"omitempty,startswith=whsec_"`
+		MailerSendWebhookSecret string `koanf:"mailersendwebhooksecret"`
+	}
+
+	Notify struct {
+		// VAPID key pair of the Web Push channel, both base64url encoded as printed by the vapid command. Web Push
+		// stays disabled until it's configured. The subject is a mailto: or https: URL push services can reach the
+		// operator at.
+		WebPushPublicKey  string `koanf:"webpushpublickey" validate:"required_with=WebPushPrivateKey"`
+		WebPushPrivateKey string `koanf:"webpushprivatekey" validate:"required_with=WebPushPublicKey"`
+		WebPushSubject    string `koanf:"webpushsubject" validate:"required_with=WebPushPrivateKey,omitempty,url"`
 	}
 
 	Server struct {
@@ -0,0 +1,18 @@
+package config
+
+import "strings"
+
+// List is a comma-separated configuration value, e.g. RPN_EMAIL_SESTOPICARNS=arn:a,arn:b. Environment variables can't
+// express lists natively, so the value is split when it's unmarshalled.
+type List []string
+
+func (l *List) UnmarshalText(text []byte) error {
+	values := make(List, 0)
+	for value := range strings.SplitSeq(string(text), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	*l = values
+	return nil
+}
@@ -6,20 +6,30 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/v2"
+	"slices"
+	"strconv"
 	"strings"
 )
 
+// indexedSections are configured as list of sections, one per index, e.g. RPN_MAILER_0_PROVIDER and
+// RPN_MAILER_1_PROVIDER.
+var indexedSections = []string{"mailer"}
+
 func LoadConfig(ctx context.Context, validate *validator.Validate) (*Config, error) {
 	k := koanf.New(".")
-
 	if err := k.Load(env.Provider("RPN_", ".", func(s string) string {
 		return strings.Replace(strings.ToLower(strings.TrimPrefix(s, "RPN_")), "_", ".", -1)
 	}), nil); err != nil {
 		return nil, fmt.Errorf("load config: %w", err)
 	}
 
-	var config Config
+	for _, section := range indexedSections {
+		if err := indexSection(k, section); err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+	}
 
+	var config Config
 	if err := k.Unmarshal("", &config); err != nil {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
@@ -30,3 +40,34 @@ func LoadConfig(ctx context.Context, validate *validator.Validate) (*Config, err
 
 	return &config, nil
 }
+
+// indexSection replaces the map of an indexed section with a list of its entries, ordered by index. Environment
+// variables can only express lists through their names, the indexes don't have to be consecutive.
+func indexSection(k *koanf.Koanf, section string) error {
+	entries, ok := k.Get(section).(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		if index, err := strconv.Atoi(key); err != nil || index < 0 {
+			return fmt.Errorf("%s.%s: %s is a list, its settings have to be prefixed with an index, e.g. RPN_%s_0_%s",
+				section, key, section, strings.ToUpper(section), strings.ToUpper(key))
+		}
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		i, _ := strconv.Atoi(a)
+		j, _ := strconv.Atoi(b)
+		return i - j
+	})
+
+	list := make([]any, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, entries[key])
+	}
+
+	k.Delete(section)
+	return k.Set(section, list)
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testEnv is the smallest configuration that validates, without any mailer.
+var testEnv = map[string]string{
+	"RPN_TEMPORAL_HOSTPORT":      "localhost:7233",
+	"RPN_TEMPORAL_NAMESPACE":     "default",
+	"RPN_REDDIT_CLIENTID":        "client",
+	"RPN_REDDIT_CLIENTSECRET":    "secret",
+	"RPN_REDDIT_REDIRECTURI":     "http://localhost:8080/callback",
+	"RPN_REDDIT_USERAGENT":       "reddit-post-notifier",
+	"RPN_DB_USER":                "rpn",
+	"RPN_DB_PWD":                 "rpn",
+	"RPN_DB_HOST":                "localhost",
+	"RPN_DB_PORT":                "5432",
+	"RPN_DB_DBNAME":              "rpn",
+	"RPN_EMAIL_SUBJECTPREFIX":    "[Reddit]",
+	"RPN_SERVER_HOST":            "localhost",
+	"RPN_SERVER_PORT":            "8080",
+	"RPN_EMAIL_SESTOPICARNS":     "arn:aws:sns:eu-west-1:123456789012:bounces, arn:aws:sns:eu-west-1:123456789012:complaints",
+	"RPN_EMAIL_DELIVERYMODE":     "bcc",
+	"RPN_EMAIL_TEMPLATEDIR":      ".",
+	"RPN_EMAIL_INLINETHUMBNAILS": "true",
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		providers []string
+		wantErr   string
+	}{
+		{
+			name: "single mailer",
+			env: map[string]string{
+				"RPN_MAILER_0_PROVIDER":   "log",
+				"RPN_MAILER_0_SENDER":     "digest@example.com",
+				"RPN_MAILER_0_SENDERNAME": "Digest",
+			},
+			providers: []string{"log"},
+		},
+		{
+			name: "failover chain ordered by index",
+			env: map[string]string{
+				"RPN_MAILER_10_PROVIDER":    "file",
+				"RPN_MAILER_10_SENDER":      "digest@example.com",
+				"RPN_MAILER_10_SENDERNAME":  "Digest",
+				"RPN_MAILER_10_FILEDIR":     "/tmp",
+				"RPN_MAILER_2_PROVIDER":     "resend",
+				"RPN_MAILER_2_SENDER":       "digest@example.com",
+				"RPN_MAILER_2_SENDERNAME":   "Digest",
+				"RPN_MAILER_2_RESENDAPIKEY": "re_123",
+				"RPN_MAILER_0_PROVIDER":     "log",
+				"RPN_MAILER_0_SENDER":       "digest@example.com",
+				"RPN_MAILER_0_SENDERNAME":   "Digest",
+			},
+			providers: []string{"log", "resend", "file"},
+		},
+		{
+			name:    "no mailer",
+			env:     map[string]string{},
+			wantErr: "Config.Mailer",
+		},
+		{
+			name: "mailer without index",
+			env: map[string]string{
+				"RPN_MAILER_PROVIDER": "log",
+			},
+			wantErr: "RPN_MAILER_0_PROVIDER",
+		},
+		{
+			name: "settings of the provider missing",
+			env: map[string]string{
+				"RPN_MAILER_0_PROVIDER":   "log",
+				"RPN_MAILER_0_SENDER":     "digest@example.com",
+				"RPN_MAILER_0_SENDERNAME": "Digest",
+				"RPN_MAILER_1_PROVIDER":   "resend",
+				"RPN_MAILER_1_SENDER":     "digest@example.com",
+				"RPN_MAILER_1_SENDERNAME": "Digest",
+			},
+			wantErr: "Config.Mailer[1].ResendAPIKey",
+		},
+		{
+			name: "unknown provider",
+			env: map[string]string{
+				"RPN_MAILER_0_PROVIDER":   "carrier-pigeon",
+				"RPN_MAILER_0_SENDER":     "digest@example.com",
+				"RPN_MAILER_0_SENDERNAME": "Digest",
+			},
+			wantErr: "Config.Mailer[0].Provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range testEnv {
+				t.Setenv(k, v)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			config, err := LoadConfig(context.Background(), validator.New(validator.WithRequiredStructEnabled()))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var providers []string
+			for _, mailer := range config.Mailer {
+				providers = append(providers, mailer.Provider)
+			}
+			if !slices.Equal(providers, tt.providers) {
+				t.Errorf("providers = %v, want %v", providers, tt.providers)
+			}
+
+			email := config.Email
+			if email.SubjectPrefix != "[Reddit]" || email.DeliveryMode != "bcc" || email.TemplateDir != "." ||
+				!email.InlineThumbnails {
+				t.Errorf("email = %+v", email)
+			}
+			if len(email.SESTopicARNs) != 2 || email.SESTopicARNs[1] != "arn:aws:sns:eu-west-1:123456789012:complaints" {
+				t.Errorf("topic ARNs = %v", email.SESTopicARNs)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dkimSignedHeaders are signed if present in the message, in this order. From is mandatory and always present.
+var dkimSignedHeaders = []string{
+	"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type",
+	"Content-Transfer-Encoding", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+type (
+	// dkimSigner signs encoded messages according to RFC 6376 with relaxed/relaxed canonicalization. RSA keys sign with
+	// rsa-sha256, Ed25519 keys with ed25519-sha256 (RFC 8463).
+	dkimSigner struct {
+		domain    string
+		selector  string
+		key       crypto.Signer
+		algorithm string
+		now       func() time.Time
+	}
+
+	// dkimMailer signs every message before handing it to a provider that sends it unchanged.
+	dkimMailer struct {
+		config *config.MailerConfig
+		signer *dkimSigner
+		mailer Mailer
+	}
+)
+
+func newDKIMSigner(config *config.MailerConfig) (*dkimSigner, error) {
+	if config.DKIMSelector == "" {
+		return nil, errors.New("dkim: selector is required")
+	}
+
+	data := []byte(config.DKIMPrivateKey)
+	if config.DKIMPrivateKeyFile != "" {
+		var err error
+		if data, err = os.ReadFile(config.DKIMPrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("dkim: read private key: %w", err)
+		}
+	}
+
+	// Keys passed through the environment usually have their newlines escaped.
+	if !bytes.Contains(data, []byte("\n")) {
+		data = bytes.ReplaceAll(data, []byte(`\n`), []byte("\n"))
+	}
+
+	key, err := parseDKIMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+
+	signer := &dkimSigner{
+		domain:   config.DKIMDomain,
+		selector: config.DKIMSelector,
+		key:      key,
+		now:      time.Now,
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		signer.algorithm = "ed25519-sha256"
+	}
+
+	return signer, nil
+}
+
+func parseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return k, nil
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func newDKIMMailer(config *config.MailerConfig, signer *dkimSigner, mailer Mailer) *dkimMailer {
+	return &dkimMailer{
+		config: config,
+		signer: signer,
+		mailer: mailer,
+	}
+}
+
+func (d *dkimMailer) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	encoded, err := msg.encoded(sender(d.config))
+	if err != nil {
+		return nil, invalidMessage("dkim", err)
+	}
+
+	signed, err := d.signer.sign(encoded.raw)
+	if err != nil {
+		return nil, invalidMessage("dkim", err)
+	}
+
+	m := *msg
+	m.signed = &encodedMessage{
+		raw:       signed,
+		messageID: encoded.messageID,
+	}
+
+	return d.mailer.SendMail(ctx, &m)
+}
+
+func (d *dkimMailer) features() Feature {
+	if s, ok := d.mailer.(featureSupporter); ok {
+		return s.features()
+	}
+	return 0
+}
+
+// sign returns the message with a DKIM-Signature header prepended.
+func (s *dkimSigner) sign(raw []byte) ([]byte, error) {
+	headerEnd := bytes.Index(raw, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, errors.New("message has no header/body separator")
+	}
+
+	headers := splitHeaders(raw[:headerEnd+2])
+	bodyHash := sha256.Sum256(relaxedBody(raw[headerEnd+4:]))
+
+	// Headers that occur more than once are signed from the bottom up, RFC 6376 section 5.4.2. The encoder never
+	// writes one of the signed headers twice, so only the last occurrence is signed.
+	var (
+		names  []string
+		signed bytes.Buffer
+	)
+	for _, name := range dkimSignedHeaders {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if !strings.EqualFold(headers[i].name, name) {
+				continue
+			}
+			names = append(names, strings.ToLower(name))
+			signed.WriteString(relaxedHeader(headers[i].name, headers[i].value))
+			break
+		}
+	}
+
+	// The header is folded before signing, the signature covers it exactly as it is sent, minus the value of b=.
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed;\r\n d=%s; s=%s; t=%s;\r\n h=%s;\r\n bh=%s;\r\n b=",
+		s.algorithm,
+		s.domain,
+		s.selector,
+		strconv.FormatInt(s.now().Unix(), 10),
+		foldHeaderNames(names),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	signed.WriteString(strings.TrimSuffix(relaxedHeader("DKIM-Signature", value), "\r\n"))
+
+	digest := sha256.Sum256(signed.Bytes())
+
+	var (
+		signature []byte
+		err       error
+	)
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		// RFC 8463 signs the SHA-256 hash with PureEdDSA, not the data itself.
+		signature = ed25519.Sign(key, digest[:])
+	default:
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sign message: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("DKIM-Signature: ")
+	out.WriteString(value)
+
+	b := base64.StdEncoding.EncodeToString(signature)
+	for len(b) > 72 {
+		out.WriteString(b[:72])
+		out.WriteString("\r\n ")
+		b = b[72:]
+	}
+	out.WriteString(b)
+	out.WriteString("\r\n")
+	out.Write(raw)
+
+	return out.Bytes(), nil
+}
+
+// foldHeaderNames joins the names of the h= tag, folding after a colon before a line exceeds 78 characters.
+func foldHeaderNames(names []string) string {
+	var b strings.Builder
+	lineLen := len(" h=")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(':')
+			lineLen++
+			if lineLen+len(name)+1 > 78 {
+				b.WriteString("\r\n ")
+				lineLen = 1
+			}
+		}
+		b.WriteString(name)
+		lineLen += len(name)
+	}
+	return b.String()
+}
+
+type rawHeader struct {
+	name  string
+	value string
+}
+
+// splitHeaders splits a header section into its fields, keeping folded values as they are.
+func splitHeaders(section []byte) []*rawHeader {
+	var headers []*rawHeader
+
+	for _, line := range strings.SplitAfter(string(section), "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1].value += line
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers = append(headers, &rawHeader{name: name, value: value})
+	}
+
+	return headers
+}
+
+// relaxedHeader canonicalizes a header field with the relaxed algorithm of RFC 6376 section 3.4.2.
+func relaxedHeader(name, value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// relaxedBody canonicalizes a body with the relaxed algorithm of RFC 6376 section 3.4.4.
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	var out bytes.Buffer
+	empty := 0
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, isWSP)
+		if line == "" {
+			empty++
+			continue
+		}
+
+		for ; empty > 0; empty-- {
+			out.WriteString("\r\n")
+		}
+
+		fields := strings.FieldsFunc(line, isWSP)
+		if isWSP(rune(line[0])) {
+			out.WriteString(" ")
+		}
+		out.WriteString(strings.Join(fields, " "))
+		out.WriteString("\r\n")
+	}
+
+	return out.Bytes()
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
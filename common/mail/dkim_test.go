@@ -0,0 +1,334 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The Ed25519 key and signed message of RFC 8463 appendix A.
+const (
+	rfc8463Seed      = "nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+	rfc8463PublicKey = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	rfc8463Message   = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+		" d=football.example.com; i=@football.example.com;\r\n" +
+		" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+		" subject : date : message-id : from : subject : date;\r\n" +
+		" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+		" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+		" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+		"From: Joe SixPack <joe@football.example.com>\r\n" +
+		"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+		"Subject: Is dinner ready?\r\n" +
+		"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+		"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+		"\r\n" +
+		"Hi.\r\n" +
+		"\r\n" +
+		"We lost the game.  Are you hungry yet?\r\n" +
+		"\r\n" +
+		"Joe.\r\n"
+)
+
+var dkimSignatureB = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+
+// verifyDKIM verifies the first DKIM-Signature of a message like a receiver would, RFC 6376 section 6.1.3. Only
+// relaxed/relaxed canonicalization is supported.
+func verifyDKIM(message []byte, key crypto.PublicKey) error {
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return errors.New("no header/body separator")
+	}
+	headers := splitHeaders(message[:headerEnd+2])
+
+	var signature *rawHeader
+	for _, h := range headers {
+		if strings.EqualFold(h.name, "DKIM-Signature") {
+			signature = h
+			break
+		}
+	}
+	if signature == nil {
+		return errors.New("no DKIM-Signature")
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(signature.value, ";") {
+		name, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), "")
+	}
+	if tags["c"] != "relaxed/relaxed" {
+		return fmt.Errorf("unsupported canonicalization %q", tags["c"])
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(message[headerEnd+4:]))
+	if got := base64.StdEncoding.EncodeToString(bodyHash[:]); got != tags["bh"] {
+		return fmt.Errorf("body hash %s doesn't match bh=%s", got, tags["bh"])
+	}
+
+	// Every name is matched from the bottom up, a name listed more often than the header occurs contributes nothing.
+	var data bytes.Buffer
+	used := make(map[*rawHeader]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if h := headers[i]; h != signature && !used[h] && strings.EqualFold(h.name, name) {
+				used[h] = true
+				data.WriteString(relaxedHeader(h.name, h.value))
+				break
+			}
+		}
+	}
+	unsigned := dkimSignatureB.ReplaceAllString(signature.value, "$1$2")
+	data.WriteString(strings.TrimSuffix(relaxedHeader(signature.name, unsigned), "\r\n"))
+	digest := sha256.Sum256(data.Bytes())
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if tags["a"] != "ed25519-sha256" || !ed25519.Verify(k, digest[:], sig) {
+			return errors.New("ed25519 signature doesn't verify")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if tags["a"] != "rsa-sha256" {
+			return fmt.Errorf("unexpected algorithm %q", tags["a"])
+		}
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	default:
+		return fmt.Errorf("unsupported key %T", key)
+	}
+}
+
+func rfc8463Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	seed, err := base64.StdEncoding.DecodeString(rfc8463Seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+
+	if got := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)); got != rfc8463PublicKey {
+		t.Fatalf("public key = %s, want %s", got, rfc8463PublicKey)
+	}
+
+	return key
+}
+
+// TestVerifyDKIMRFC8463 checks the canonicalization, and the verifier the other tests rely on, against the example
+// signature of RFC 8463.
+func TestVerifyDKIMRFC8463(t *testing.T) {
+	key := rfc8463Key(t)
+
+	if err := verifyDKIM([]byte(rfc8463Message), key.Public()); err != nil {
+		t.Fatalf("RFC 8463 example doesn't verify: %v", err)
+	}
+
+	tampered := strings.Replace(rfc8463Message, "hungry", "thirsty", 1)
+	if err := verifyDKIM([]byte(tampered), key.Public()); err == nil {
+		t.Error("tampered body verifies")
+	}
+}
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	// The examples of RFC 6376 section 3.4.5.
+	headers := splitHeaders([]byte("A: X\r\nB : Y\t\r\n\tZ  \r\n"))
+	var got strings.Builder
+	for _, h := range headers {
+		got.WriteString(relaxedHeader(h.name, h.value))
+	}
+	if want := "a:X\r\nb:Y Z\r\n"; got.String() != want {
+		t.Errorf("relaxed headers = %q, want %q", got.String(), want)
+	}
+
+	bodies := []struct {
+		body string
+		want string
+	}{
+		{body: " C \r\nD \t E\r\n\r\n\r\n", want: " C\r\nD E\r\n"},
+		{body: "trailing whitespace \t\r\n", want: "trailing whitespace\r\n"},
+		{body: "inner\r\n\r\nempty line\r\n", want: "inner\r\n\r\nempty line\r\n"},
+		{body: "\r\n\r\n", want: ""},
+		{body: "", want: ""},
+	}
+	for _, tt := range bodies {
+		if got := string(relaxedBody([]byte(tt.body))); got != tt.want {
+			t.Errorf("relaxedBody(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDKIMSignerSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := rfc8463Key(t)
+
+	from := &mail.Address{Name: "Digest", Address: "digest@example.com"}
+	messages := []struct {
+		name string
+		msg  *Message
+	}{
+		{
+			name: "plain",
+			msg:  &Message{To: []string{"someone@example.com"}, Subject: "Digest", Text: "Hello"},
+		},
+		{
+			name: "folded subject",
+			msg: &Message{
+				To:      []string{"someone@example.com"},
+				Subject: strings.Repeat("Neue Beiträge in r/golang ", 6),
+				HTML:    "<p>Hello</p>",
+				Text:    "Hello",
+			},
+		},
+		{
+			name: "trailing whitespace in the body",
+			msg: &Message{
+				To:   []string{"someone@example.com"},
+				Text: "Hello  \t\r\n\r\nsecond paragraph \r\n\r\n\r\n",
+			},
+		},
+	}
+
+	keys := []struct {
+		name   string
+		key    crypto.Signer
+		public crypto.PublicKey
+		alg    string
+	}{
+		{name: "rsa", key: rsaKey, public: &rsaKey.PublicKey, alg: "rsa-sha256"},
+		{name: "ed25519", key: edKey, public: edKey.Public(), alg: "ed25519-sha256"},
+	}
+
+	for _, k := range keys {
+		for _, m := range messages {
+			t.Run(k.name+"/"+m.name, func(t *testing.T) {
+				signer := &dkimSigner{
+					domain:    "example.com",
+					selector:  "digest",
+					key:       k.key,
+					algorithm: k.alg,
+					now: func() time.Time {
+						return time.Unix(1760688000, 0)
+					},
+				}
+
+				raw, err := m.msg.encode(from, "<id@example.com>", time.Unix(1760688000, 0))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				signed, err := signer.sign(raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.HasSuffix(signed, raw) {
+					t.Error("signing changed the message")
+				}
+				header, _, _ := bytes.Cut(signed, []byte("\r\n"))
+				if want := "DKIM-Signature: v=1; a=" + k.alg + "; c=relaxed/relaxed;"; string(header) != want {
+					t.Errorf("first header line = %q, want %q", header, want)
+				}
+				for _, line := range strings.Split(string(signed[:bytes.Index(signed, raw)]), "\r\n") {
+					if len(line) > 78 {
+						t.Errorf("signature line exceeds 78 characters: %q", line)
+					}
+				}
+
+				if err = verifyDKIM(signed, k.public); err != nil {
+					t.Fatalf("signature doesn't verify: %v\n%s", err, signed)
+				}
+
+				// Relaxed canonicalization tolerates whitespace changes in transit, but not changed content.
+				reformatted := bytes.Replace(signed, []byte("Subject: "), []byte("Subject:  \t"), 1)
+				reformatted = append(reformatted, "  \r\n\r\n"...)
+				if err = verifyDKIM(reformatted, k.public); err != nil {
+					t.Errorf("whitespace changes break the signature: %v", err)
+				}
+
+				tampered := bytes.Replace(signed, []byte("To: "), []byte("To: attacker@example.com, "), 1)
+				if err = verifyDKIM(tampered, k.public); err == nil {
+					t.Error("tampered header verifies")
+				}
+			})
+		}
+	}
+}
+
+func TestNewDKIMSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8 := func(key any) string {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+	pkcs1 := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+
+	tests := []struct {
+		name      string
+		selector  string
+		key       string
+		algorithm string
+		wantErr   bool
+	}{
+		{name: "pkcs1 rsa", selector: "digest", key: pkcs1, algorithm: "rsa-sha256"},
+		{name: "pkcs8 rsa", selector: "digest", key: pkcs8(rsaKey), algorithm: "rsa-sha256"},
+		{name: "pkcs8 ed25519", selector: "digest", key: pkcs8(rfc8463Key(t)), algorithm: "ed25519-sha256"},
+		{name: "escaped newlines", selector: "digest", key: strings.ReplaceAll(pkcs1, "\n", `\n`), algorithm: "rsa-sha256"},
+		{name: "ecdsa", selector: "digest", key: pkcs8(ecKey), wantErr: true},
+		{name: "not pem", selector: "digest", key: "secret", wantErr: true},
+		{name: "no selector", key: pkcs1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := newDKIMSigner(&config.MailerConfig{
+				DKIMDomain:     "example.com",
+				DKIMSelector:   tt.selector,
+				DKIMPrivateKey: tt.key,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newDKIMSigner() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && signer.algorithm != tt.algorithm {
+				t.Errorf("algorithm = %q, want %q", signer.algorithm, tt.algorithm)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SendError is returned by providers that can tell a transient failure (rate limits, outages) apart from a permanent
+// one (invalid credentials, rejected payload). Callers decide on retries based on Retryable and RetryAfter.
+type SendError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	Retryable  bool
+	RetryAfter time.Duration
+	// header of the failed response, for providers that put the error details there.
+	header http.Header
+}
+
+func (e *SendError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+}
+
+// invalidMessage marks errors caused by the message itself. Sending the same message again will fail the same way.
+func invalidMessage(provider string, err error) *SendError {
+	return &SendError{
+		Provider: provider,
+		Message:  err.Error(),
+	}
+}
+
+// unsupported is returned if a message uses a feature the provider's API has no equivalent for.
+func unsupported(provider, feature string) *SendError {
+	return &SendError{
+		Provider: provider,
+		Message:  feature + " not supported",
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= http.StatusInternalServerError
+	}
+}
+
+// parseRetryAfter understands both forms of the Retry-After header, delay in seconds and HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,215 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailoverThreshold = 3
+	defaultFailoverCooldown  = 5 * time.Minute
+
+	// smtpAuthRequired and smtpAuthFailed are the SMTP reply codes for rejected credentials, the equivalent of 401.
+	smtpAuthRequired = 530
+	smtpAuthFailed   = 535
+)
+
+type (
+	// failoverMailer tries providers in order and moves on to the next one if a provider fails with a transient
+	// error. Each provider has its own circuit breaker, so a provider that keeps failing is skipped for a while
+	// instead of adding its timeout to every single message.
+	failoverMailer struct {
+		providers []*failoverProvider
+	}
+
+	failoverProvider struct {
+		name    string
+		mailer  Mailer
+		breaker *circuitBreaker
+	}
+
+	// circuitBreaker opens after threshold consecutive failures. Once cooldown has passed it lets a single attempt
+	// through (half-open), which either closes it again or restarts the cooldown.
+	circuitBreaker struct {
+		mu        sync.Mutex
+		threshold int
+		cooldown  time.Duration
+		failures  int
+		openUntil time.Time
+		probing   bool
+		now       func() time.Time
+	}
+)
+
+func newFailoverMailer(providers []*failoverProvider) *failoverMailer {
+	return &failoverMailer{
+		providers: providers,
+	}
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultFailoverThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultFailoverCooldown
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (f *failoverMailer) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	var (
+		lastErr   error
+		retryIn   time.Duration
+		attempts  int
+		retryable bool
+		failures  []string
+	)
+
+	for _, p := range f.providers {
+		if wait, ok := p.breaker.allow(); !ok {
+			if retryIn == 0 || wait < retryIn {
+				retryIn = wait
+			}
+			// The provider is tried again once its breaker closes.
+			retryable = true
+			continue
+		}
+		attempts++
+
+		out, err := p.mailer.SendMail(ctx, msg)
+		if err == nil {
+			p.breaker.success()
+			out.Provider = p.name
+			return out, nil
+		}
+
+		if !isProviderFailure(err) {
+			// The message itself was rejected, another provider won't accept it either.
+			p.breaker.success()
+			return nil, err
+		}
+
+		p.breaker.failure()
+		lastErr = err
+		failures = append(failures, err.Error())
+
+		target, ok := errors.AsType[*SendError](err)
+		if !ok || target.Retryable {
+			retryable = true
+			if ok && target.RetryAfter > 0 && (retryIn == 0 || target.RetryAfter < retryIn) {
+				retryIn = target.RetryAfter
+			}
+		}
+
+		slog.Warn("mail provider failed, trying next provider", slog.String("provider", p.name), slog.Any("error", err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if attempts == 0 {
+		return nil, &SendError{
+			Provider:   "failover",
+			Message:    "all mail providers are unavailable",
+			Retryable:  true,
+			RetryAfter: retryIn,
+		}
+	}
+
+	if !retryable {
+		return nil, lastErr
+	}
+	if target, ok := errors.AsType[*SendError](lastErr); len(failures) == 1 && (!ok || target.Retryable) {
+		return nil, lastErr
+	}
+
+	// The error of the last provider alone could be permanent, e.g. rejected credentials, while another provider
+	// only had an outage. The message is worth another attempt as long as any provider may accept it later.
+	return nil, &SendError{
+		Provider:   "failover",
+		Message:    strings.Join(failures, "; "),
+		Retryable:  true,
+		RetryAfter: retryIn,
+	}
+}
+
+func (f *failoverMailer) features() Feature {
+	features := ^Feature(0)
+	for _, p := range f.providers {
+		s, ok := p.mailer.(featureSupporter)
+		if !ok {
+			return 0
+		}
+		features &= s.features()
+	}
+	return features
+}
+
+// isProviderFailure reports whether err is the provider's fault rather than the message's. Besides transient errors
+// that includes rejected credentials, as they are specific to one provider.
+func isProviderFailure(err error) bool {
+	target, ok := errors.AsType[*SendError](err)
+	if !ok {
+		return true
+	}
+
+	switch target.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, smtpAuthRequired, smtpAuthFailed:
+		return true
+	}
+
+	return target.Retryable
+}
+
+// allow reports whether a request may be sent. If not, it also returns how long the breaker stays open.
+func (b *circuitBreaker) allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return 0, true
+	}
+
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return b.openUntil.Sub(now), false
+	}
+
+	if b.probing {
+		return b.cooldown, false
+	}
+
+	b.probing = true
+	return 0, true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,238 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// scriptedMailer fails with the next error of errs on every call, or succeeds once they run out.
+type scriptedMailer struct {
+	errs  []error
+	calls int
+}
+
+func (m *scriptedMailer) SendMail(context.Context, *Message) (*SendMailOutput, error) {
+	m.calls++
+	if len(m.errs) == 0 {
+		return &SendMailOutput{MessageID: "id"}, nil
+	}
+
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return nil, err
+}
+
+// testClock is a manually advanced clock for circuit breakers.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &testClock{now: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)}
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = clock.Now
+
+	mustAllow := func(want bool) {
+		t.Helper()
+		if _, ok := b.allow(); ok != want {
+			t.Fatalf("allow() = %t, want %t", ok, want)
+		}
+	}
+
+	mustAllow(true)
+	b.failure()
+	mustAllow(true)
+	b.failure()
+
+	// Open after the second consecutive failure.
+	if wait, ok := b.allow(); ok || wait != time.Minute {
+		t.Fatalf("allow() = %s, %t, want open for a minute", wait, ok)
+	}
+
+	// Half-open after the cooldown, only a single probe gets through.
+	clock.now = clock.now.Add(time.Minute)
+	mustAllow(true)
+	mustAllow(false)
+
+	// A failed probe restarts the cooldown.
+	b.failure()
+	mustAllow(false)
+	clock.now = clock.now.Add(time.Minute)
+	mustAllow(true)
+
+	// A successful probe closes the breaker again.
+	b.success()
+	mustAllow(true)
+	b.failure()
+	mustAllow(true)
+}
+
+func TestFailoverMailerSendMail(t *testing.T) {
+	var (
+		outage       = &SendError{Provider: "a", StatusCode: http.StatusServiceUnavailable, Message: "outage", Retryable: true}
+		rateLimited  = &SendError{Provider: "a", StatusCode: http.StatusTooManyRequests, Message: "slow down", Retryable: true, RetryAfter: 30 * time.Second}
+		unauthorized = &SendError{Provider: "b", StatusCode: http.StatusUnauthorized, Message: "invalid key"}
+		smtpAuth     = &SendError{Provider: "smtp", StatusCode: smtpAuthFailed, Message: "535 5.7.8 authentication failed"}
+		rejected     = &SendError{Provider: "a", StatusCode: http.StatusUnprocessableEntity, Message: "invalid recipient"}
+	)
+
+	tests := []struct {
+		name       string
+		errs       [][]error
+		provider   string
+		calls      []int
+		wantErr    error
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name:     "first provider",
+			errs:     [][]error{nil, nil},
+			provider: "a",
+			calls:    []int{1, 0},
+		},
+		{
+			name:     "fail over on outage",
+			errs:     [][]error{{outage}, nil},
+			provider: "b",
+			calls:    []int{1, 1},
+		},
+		{
+			name:     "fail over on rejected credentials",
+			errs:     [][]error{{unauthorized}, nil},
+			provider: "b",
+			calls:    []int{1, 1},
+		},
+		{
+			name:     "fail over on smtp authentication failure",
+			errs:     [][]error{{smtpAuth}, nil},
+			provider: "b",
+			calls:    []int{1, 1},
+		},
+		{
+			name:    "rejected message isn't tried elsewhere",
+			errs:    [][]error{{rejected}, nil},
+			calls:   []int{1, 0},
+			wantErr: rejected,
+		},
+		{
+			name:       "retryable if any provider failed retryably",
+			errs:       [][]error{{rateLimited}, {unauthorized}},
+			calls:      []int{1, 1},
+			retryable:  true,
+			retryAfter: 30 * time.Second,
+		},
+		{
+			name:    "permanent if every provider failed permanently",
+			errs:    [][]error{{smtpAuth}, {unauthorized}},
+			calls:   []int{1, 1},
+			wantErr: unauthorized,
+		},
+		{
+			name:      "single retryable failure is returned as is",
+			errs:      [][]error{{outage}},
+			calls:     []int{1},
+			wantErr:   outage,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				providers []*failoverProvider
+				mailers   []*scriptedMailer
+			)
+			for i, errs := range tt.errs {
+				m := &scriptedMailer{errs: errs}
+				mailers = append(mailers, m)
+				providers = append(providers, &failoverProvider{
+					name:    string(rune('a' + i)),
+					mailer:  m,
+					breaker: newCircuitBreaker(0, 0),
+				})
+			}
+
+			out, err := newFailoverMailer(providers).SendMail(context.Background(), &Message{})
+
+			for i, m := range mailers {
+				if m.calls != tt.calls[i] {
+					t.Errorf("provider %d called %d times, want %d", i, m.calls, tt.calls[i])
+				}
+			}
+
+			if tt.provider != "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out.Provider != tt.provider {
+					t.Errorf("provider = %q, want %q", out.Provider, tt.provider)
+				}
+				return
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			target, ok := errors.AsType[*SendError](err)
+			if !ok {
+				t.Fatalf("expected *SendError, got %v", err)
+			}
+			if target.Retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", target.Retryable, tt.retryable)
+			}
+			if target.RetryAfter != tt.retryAfter {
+				t.Errorf("retry after = %s, want %s", target.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestFailoverMailerSkipsOpenBreaker(t *testing.T) {
+	clock := &testClock{now: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)}
+
+	broken := &scriptedMailer{errs: []error{
+		&SendError{Provider: "a", Message: "connection refused", Retryable: true},
+	}}
+	breaker := newCircuitBreaker(1, time.Minute)
+	breaker.now = clock.Now
+
+	backup := &scriptedMailer{errs: []error{
+		&SendError{Provider: "b", StatusCode: http.StatusForbidden, Message: "suspended"},
+		&SendError{Provider: "b", StatusCode: http.StatusForbidden, Message: "suspended"},
+	}}
+
+	mailer := newFailoverMailer([]*failoverProvider{
+		{name: "a", mailer: broken, breaker: breaker},
+		{name: "b", mailer: backup, breaker: newCircuitBreaker(5, time.Minute)},
+	})
+
+	if _, err := mailer.SendMail(context.Background(), &Message{}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The first provider is skipped now, the permanent error of the second one is still retryable since the first
+	// provider gets another chance once its breaker closes.
+	_, err := mailer.SendMail(context.Background(), &Message{})
+	if broken.calls != 1 || backup.calls != 2 {
+		t.Fatalf("calls = %d, %d, want 1, 2", broken.calls, backup.calls)
+	}
+	checkSendError(t, err, 0, true, time.Minute)
+
+	clock.now = clock.now.Add(time.Minute)
+	out, err := mailer.SendMail(context.Background(), &Message{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Provider != "a" {
+		t.Errorf("provider = %q, want a", out.Provider)
+	}
+}
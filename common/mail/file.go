@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileMailer writes every message as .eml file instead of sending it, so the exact output can be inspected in a mail
+// client or diffed in CI.
+type fileMailer struct {
+	config *config.MailerConfig
+	dir    string
+}
+
+func newFileMailer(config *config.MailerConfig) (Mailer, error) {
+	if config.FileDir == "" {
+		return nil, errors.New("file: directory is required")
+	}
+
+	if err := os.MkdirAll(config.FileDir, 0o755); err != nil {
+		return nil, fmt.Errorf("file: create directory: %w", err)
+	}
+
+	return &fileMailer{
+		config: config,
+		dir:    config.FileDir,
+	}, nil
+}
+
+func (f *fileMailer) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	encoded, err := msg.encoded(sender(f.config))
+	if err != nil {
+		return nil, invalidMessage("file", err)
+	}
+
+	// The encoded message never discloses Bcc recipients. The file is never delivered, so they're kept as header to
+	// show who would have received it.
+	var buf bytes.Buffer
+	if len(msg.Bcc) > 0 {
+		writeHeader(&buf, "Bcc", formatAddressList(msg.Bcc))
+	}
+	buf.Write(encoded.raw)
+
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(suffix[:]))
+
+	// Written to a temporary file first, so anything watching the directory never picks up a partial message.
+	tmp, err := os.CreateTemp(f.dir, ".*.eml.tmp")
+	if err != nil {
+		return nil, fileError(err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return nil, fileError(err)
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, fileError(err)
+	}
+
+	path := filepath.Join(f.dir, name)
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return nil, fileError(err)
+	}
+
+	slog.Debug("mail written to file", slog.String("path", path), slog.String("message_id", encoded.messageID))
+
+	return &SendMailOutput{
+		MessageID: encoded.messageID,
+	}, nil
+}
+
+func (f *fileMailer) features() Feature {
+	return FeatureBCC | FeatureInline | featureRaw
+}
+
+func fileError(err error) error {
+	return &SendError{
+		Provider: "file",
+		Message:  err.Error(),
+		// A full disk or a permission problem won't go away by retrying right away, but might after an operator
+		// stepped in.
+		Retryable: true,
+	}
+}
+
+// logMailer only logs a summary of every message. Nothing is sent or stored.
+type logMailer struct {
+	config *config.MailerConfig
+}
+
+func newLogMailer(config *config.MailerConfig) (Mailer, error) {
+	return &logMailer{
+		config: config,
+	}, nil
+}
+
+func (l *logMailer) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	// Encoded anyway, so a message that a real provider would reject fails here as well.
+	encoded, err := msg.encoded(sender(l.config))
+	if err != nil {
+		return nil, invalidMessage("log", err)
+	}
+
+	attachments := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, a.Filename)
+	}
+
+	slog.InfoContext(ctx, "mail",
+		slog.String("message_id", encoded.messageID),
+		slog.String("from", sender(l.config).String()),
+		slog.Any("to", msg.To),
+		slog.Any("cc", msg.Cc),
+		slog.Any("bcc", msg.Bcc),
+		slog.String("subject", msg.Subject),
+		slog.Int("text_length", len(msg.Text)),
+		slog.Int("html_length", len(msg.HTML)),
+		slog.Any("attachments", attachments),
+		slog.Int("size", len(encoded.raw)),
+	)
+
+	return &SendMailOutput{
+		MessageID: encoded.messageID,
+	}, nil
+}
+
+func (l *logMailer) features() Feature {
+	return FeatureBCC | FeatureInline | featureRaw
+}
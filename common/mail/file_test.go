@@ -0,0 +1,137 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewFileMailer(t *testing.T) {
+	if _, err := newFileMailer(&config.MailerConfig{}); err == nil {
+		t.Error("expected an error without directory")
+	}
+
+	dir := filepath.Join(t.TempDir(), "outbox", "nested")
+	if _, err := newFileMailer(&config.MailerConfig{FileDir: dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("directory wasn't created: %v", err)
+	}
+}
+
+func TestFileMailerSendMail(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *Message
+		bcc     string
+		wantErr bool
+	}{
+		{
+			name: "message",
+			msg:  &Message{To: []string{"someone@example.com"}, Subject: "Digest", Text: "Hello"},
+		},
+		{
+			name: "bcc is kept",
+			msg:  &Message{To: []string{"someone@example.com"}, Bcc: []string{"hidden@example.com"}, Subject: "Digest", Text: "Hello"},
+			bcc:  "<hidden@example.com>",
+		},
+		{
+			name:    "invalid message",
+			msg:     &Message{Subject: "Digest"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			mailer, err := newFileMailer(&config.MailerConfig{SenderEmail: "digest@example.com", FileDir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := mailer.SendMail(context.Background(), tt.msg)
+
+			entries, _ := os.ReadDir(dir)
+			if tt.wantErr {
+				checkSendError(t, err, 0, false, 0)
+				if len(entries) != 0 {
+					t.Errorf("expected no files, got %d", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+				t.Fatalf("expected a single .eml file, got %v", entries)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := mail.ReadMessage(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("parse file: %v", err)
+			}
+			if got := parsed.Header.Get("Message-Id"); got != out.MessageID {
+				t.Errorf("message id = %q, want %q", got, out.MessageID)
+			}
+			if got := parsed.Header.Get("Subject"); got != tt.msg.Subject {
+				t.Errorf("subject = %q, want %q", got, tt.msg.Subject)
+			}
+			if got := parsed.Header.Get("Bcc"); got != tt.bcc {
+				t.Errorf("bcc = %q, want %q", got, tt.bcc)
+			}
+		})
+	}
+}
+
+func TestLogMailerSendMail(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+
+	mailer, err := newLogMailer(&config.MailerConfig{SenderEmail: "digest@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := mailer.SendMail(context.Background(), &Message{
+		To:          []string{"someone@example.com"},
+		Subject:     "Digest",
+		Text:        "Hello",
+		Attachments: []*Attachment{{Filename: "posts.csv", Data: []byte("id")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logged := buf.String()
+	for _, want := range []string{"message_id=" + out.MessageID, "subject=Digest", "to=[someone@example.com]", "attachments=[posts.csv]", "text_length=5"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log doesn't contain %q: %s", want, logged)
+		}
+	}
+	if strings.Contains(logged, "Hello") {
+		t.Errorf("log contains the message body: %s", logged)
+	}
+
+	if _, err = mailer.SendMail(context.Background(), &Message{Subject: "Digest"}); err == nil {
+		t.Error("expected an error for a message without recipients")
+	}
+}
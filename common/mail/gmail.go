@@ -2,40 +2,40 @@ package mail
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"github.com/forbiddencoding/reddit-post-notifier/common/config"
-	"net/smtp"
-	"strings"
 )
 
 type gMailClient struct {
-	config *config.Mailer
+	smtp *smtpClient
 }
 
-func newGMailClient(config *config.Mailer) (Mailer, error) {
+func newGMailClient(config *config.MailerConfig) (Mailer, error) {
+	if config.AppPassword == "" {
+		return nil, errors.New("gmail: app password is required")
+	}
+
+	client, err := newSMTPClientWithOptions(config, &smtpOptions{
+		host:     "smtp.gmail.com",
+		port:     587,
+		tlsMode:  smtpTLSStartTLS,
+		authMode: smtpAuthPlain,
+		username: config.SenderEmail,
+		password: config.AppPassword,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &gMailClient{
-		config: config,
+		smtp: client,
 	}, nil
 }
 
-func (g *gMailClient) SendMail(ctx context.Context, to []string, subject string, content string) error {
-	auth := smtp.PlainAuth("", g.config.SenderEmail, g.config.AppPassword, "smtp.gmail.com")
-
-	headers := make(map[string]string)
-	headers["From"] = g.config.SenderEmail
-	headers["To"] = strings.Join(to, ", ")
-	headers["Subject"] = fmt.Sprintf("%s %s", g.config.SubjectPrefix, subject)
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = `text/html; charset="UTF-8"`
-
-	var message strings.Builder
-	for k, v := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	message.WriteString("\r\n" + content)
+func (g *gMailClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	return g.smtp.SendMail(ctx, msg)
+}
 
-	if err := smtp.SendMail("smtp.gmail.com:587", auth, g.config.SenderEmail, to, []byte(message.String())); err != nil {
-		return err
-	}
-	return nil
+func (g *gMailClient) features() Feature {
+	return FeatureBCC | FeatureInline | featureRaw
 }
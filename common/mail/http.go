@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxErrorBodySize = 4 << 10
+
+// apiClient is the shared transport for the HTTP based providers. It takes care of JSON encoding, status code
+// classification and turning non-2xx responses into a *SendError.
+type apiClient struct {
+	provider   string
+	baseURL    string
+	httpClient *http.Client
+	// sign is called right before the request is sent, with the final headers and payload in place.
+	sign func(req *http.Request, payload []byte) error
+}
+
+func newAPIClient(provider, baseURL, defaultBaseURL string) *apiClient {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	return &apiClient{
+		provider: provider,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// do sends in as JSON and decodes the response into out. The response header is returned for providers that pass
+// message IDs as headers.
+func (c *apiClient) do(ctx context.Context, method, path string, header http.Header, in any, out any) (http.Header, error) {
+	var (
+		payload []byte
+		body    io.Reader
+		err     error
+	)
+	if in != nil {
+		if payload, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("%s: marshal request: %w", c.provider, err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: create request: %w", c.provider, err)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if c.sign != nil {
+		if err = c.sign(req, payload); err != nil {
+			return nil, fmt.Errorf("%s: sign request: %w", c.provider, err)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Network level failures are always worth another attempt.
+		return nil, &SendError{
+			Provider:  c.provider,
+			Message:   err.Error(),
+			Retryable: true,
+		}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+		return nil, &SendError{
+			Provider:   c.provider,
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+			Retryable:  isRetryableStatus(resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			header:     resp.Header,
+		}
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.Header, nil
+	}
+
+	// The provider accepted the message at this point. Failing here would only make the caller retry and deliver a
+	// duplicate, so a malformed response body is logged instead.
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		slog.Warn("decode provider response", slog.String("provider", c.provider), slog.Any("error", err))
+	}
+
+	return resp.Header, nil
+}
@@ -3,20 +3,160 @@ package mail
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"log/slog"
+	"net/mail"
+	"slices"
 )
 
 type Mailer interface {
-	SendMail(ctx context.Context, to []string, subject string, content string) error
+	SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error)
+}
+
+// Feature is an optional capability a provider may or may not support.
+type Feature uint
+
+const (
+	// FeatureBCC means Bcc recipients are delivered without being disclosed to the other recipients.
+	FeatureBCC Feature = 1 << iota
+	// FeatureInline means attachments with a ContentID are embedded into the HTML body instead of being attached.
+	FeatureInline
+
+	// featureRaw means the provider sends the MIME encoding of the message as is, which DKIM signing relies on.
+	featureRaw
+)
+
+// featureSupporter is implemented by providers to announce their optional capabilities.
+type featureSupporter interface {
+	features() Feature
+}
+
+// Supports reports whether m supports all features in f.
+func Supports(m Mailer, f Feature) bool {
+	s, ok := m.(featureSupporter)
+	if !ok {
+		return false
+	}
+	return s.features()&f == f
 }
 
 var ErrUnsupportedMailer = errors.New("unsupported mailer")
 
-func New(ctx context.Context, config *config.Mailer) (Mailer, error) {
-	switch config.Provider {
+// New creates the mailer chain of the configured mailers. The first one sends every message, if more are configured
+// the returned mailer fails over to them in order.
+func New(ctx context.Context, configs []config.MailerConfig) (Mailer, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no mailer configured")
+	}
+
+	providers := make([]*failoverProvider, 0, len(configs))
+	for i := range configs {
+		conf := &configs[i]
+
+		name := conf.Name
+		if name == "" {
+			name = conf.Provider
+		}
+		if slices.ContainsFunc(providers, func(p *failoverProvider) bool { return p.name == name }) {
+			return nil, fmt.Errorf("mailer %d: name %q is used by an earlier mailer, set a name for each of them", i, name)
+		}
+
+		mailer, err := newMailer(conf)
+		if err != nil {
+			return nil, fmt.Errorf("mailer %s: %w", name, err)
+		}
+
+		providers = append(providers, &failoverProvider{
+			name:    name,
+			mailer:  mailer,
+			breaker: newCircuitBreaker(conf.FailoverThreshold, conf.FailoverCooldown),
+		})
+	}
+
+	if len(providers) == 1 {
+		return &namedMailer{name: providers[0].name, mailer: providers[0].mailer}, nil
+	}
+
+	return newFailoverMailer(providers), nil
+}
+
+// newMailer creates the provider of config, signing its messages if DKIM is configured.
+func newMailer(config *config.MailerConfig) (Mailer, error) {
+	mailer, err := newProvider(config.Provider, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DKIMDomain == "" {
+		return mailer, nil
+	}
+
+	if !Supports(mailer, featureRaw) {
+		slog.Warn("mail provider builds its own MIME message, skipping DKIM signing", slog.String("provider", config.Provider))
+		return mailer, nil
+	}
+
+	signer, err := newDKIMSigner(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDKIMMailer(config, signer, mailer), nil
+}
+
+func newProvider(name string, config *config.MailerConfig) (Mailer, error) {
+	switch name {
 	case "gmail":
 		return newGMailClient(config)
+	case "smtp":
+		return newSMTPClient(config)
+	case "resend":
+		return newResendClient(config)
+	case "ses":
+		return newSESClient(config)
+	case "mailersend":
+		return newMailerSendClient(config)
+	case "scaleway":
+		return newScalewayClient(config)
+	case "plunk":
+		return newPlunkClient(config)
+	case "file":
+		return newFileMailer(config)
+	case "log":
+		return newLogMailer(config)
 	default:
 		return nil, ErrUnsupportedMailer
 	}
 }
+
+// namedMailer records the provider name on the output of a single provider setup, the same way the failover mailer
+// does.
+type namedMailer struct {
+	name   string
+	mailer Mailer
+}
+
+func (n *namedMailer) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	out, err := n.mailer.SendMail(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	out.Provider = n.name
+	return out, nil
+}
+
+func (n *namedMailer) features() Feature {
+	if s, ok := n.mailer.(featureSupporter); ok {
+		return s.features()
+	}
+	return 0
+}
+
+// sender is the From address of every outgoing message.
+func sender(config *config.MailerConfig) *mail.Address {
+	return &mail.Address{
+		Name:    config.SenderName,
+		Address: config.SenderEmail,
+	}
+}
@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+const mailerSendDefaultBaseURL = "https://api.mailersend.com"
+
+type (
+	mailerSendClient struct {
+		config *config.MailerConfig
+		api    *apiClient
+	}
+
+	mailerSendAddress struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+
+	mailerSendAttachment struct {
+		Content     string `json:"content"`
+		Filename    string `json:"filename"`
+		Disposition string `json:"disposition"`
+		ID          string `json:"id,omitempty"`
+	}
+
+	mailerSendHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	mailerSendEmailRequest struct {
+		From        mailerSendAddress       `json:"from"`
+		To          []mailerSendAddress     `json:"to,omitempty"`
+		Cc          []mailerSendAddress     `json:"cc,omitempty"`
+		Bcc         []mailerSendAddress     `json:"bcc,omitempty"`
+		ReplyTo     *mailerSendAddress      `json:"reply_to,omitempty"`
+		Subject     string                  `json:"subject"`
+		HTML        string                  `json:"html,omitempty"`
+		Text        string                  `json:"text,omitempty"`
+		Headers     []*mailerSendHeader     `json:"headers,omitempty"`
+		Attachments []*mailerSendAttachment `json:"attachments,omitempty"`
+	}
+)
+
+func newMailerSendClient(config *config.MailerConfig) (Mailer, error) {
+	if config.MailerSendAPIKey == "" {
+		return nil, errors.New("mailersend: api key is required")
+	}
+
+	return &mailerSendClient{
+		config: config,
+		api:    newAPIClient("mailersend", config.MailerSendBaseURL, mailerSendDefaultBaseURL),
+	}, nil
+}
+
+func (m *mailerSendClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	if err := msg.validate(); err != nil {
+		return nil, invalidMessage("mailersend", err)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+m.config.MailerSendAPIKey)
+
+	req := &mailerSendEmailRequest{
+		From: mailerSendAddress{
+			Email: m.config.SenderEmail,
+			Name:  m.config.SenderName,
+		},
+		To:      mailerSendAddresses(msg.To),
+		Cc:      mailerSendAddresses(msg.Cc),
+		Bcc:     mailerSendAddresses(msg.Bcc),
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+	}
+
+	if msg.ReplyTo != "" {
+		req.ReplyTo = &mailerSendAddress{Email: msg.ReplyTo}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(msg.Headers)) {
+		req.Headers = append(req.Headers, &mailerSendHeader{Name: k, Value: msg.Headers[k]})
+	}
+
+	for _, a := range msg.Attachments {
+		attachment := &mailerSendAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			Filename:    a.Filename,
+			Disposition: "attachment",
+		}
+		if a.ContentID != "" {
+			attachment.Disposition, attachment.ID = "inline", a.ContentID
+		}
+		req.Attachments = append(req.Attachments, attachment)
+	}
+
+	// MailerSend answers with 202 Accepted and an empty body, the message ID is only available as a response header.
+	res, err := m.api.do(ctx, http.MethodPost, "/v1/email", header, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendMailOutput{
+		MessageID: res.Get("X-Message-Id"),
+	}, nil
+}
+
+func (m *mailerSendClient) features() Feature {
+	return FeatureBCC | FeatureInline
+}
+
+func mailerSendAddresses(list []string) []mailerSendAddress {
+	addresses := make([]mailerSendAddress, 0, len(list))
+	for _, address := range list {
+		addresses = append(addresses, mailerSendAddress{Email: address})
+	}
+	return addresses
+}
@@ -0,0 +1,152 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// apiResponse is the canned answer of a provider API in the tests of the HTTP based providers.
+type apiResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// serveAPI answers every request with res, after checking it with check if given.
+func serveAPI(t *testing.T, res *apiResponse, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+
+		for k, v := range res.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// checkSendError fails the test unless err is a *SendError with the given classification.
+func checkSendError(t *testing.T, err error, status int, retryable bool, retryAfter time.Duration) {
+	t.Helper()
+
+	target, ok := errors.AsType[*SendError](err)
+	if !ok {
+		t.Fatalf("expected *SendError, got %v", err)
+	}
+	if target.StatusCode != status {
+		t.Errorf("status code = %d, want %d", target.StatusCode, status)
+	}
+	if target.Retryable != retryable {
+		t.Errorf("retryable = %t, want %t", target.Retryable, retryable)
+	}
+	if target.RetryAfter != retryAfter {
+		t.Errorf("retry after = %s, want %s", target.RetryAfter, retryAfter)
+	}
+}
+
+func TestMailerSendClientSendMail(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   *apiResponse
+		messageID  string
+		wantErr    bool
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name: "accepted",
+			response: &apiResponse{
+				status: http.StatusAccepted,
+				header: map[string]string{"X-Message-Id": "5e42957d51f1d94a1070a733"},
+			},
+			messageID: "5e42957d51f1d94a1070a733",
+		},
+		{
+			name: "rate limited",
+			response: &apiResponse{
+				status: http.StatusTooManyRequests,
+				header: map[string]string{"Retry-After": "30"},
+				body:   `{"message":"Too Many Attempts."}`,
+			},
+			wantErr:    true,
+			retryable:  true,
+			retryAfter: 30 * time.Second,
+		},
+		{
+			name: "validation error",
+			response: &apiResponse{
+				status: http.StatusUnprocessableEntity,
+				body:   `{"message":"The from.email must be verified.","errors":{"from.email":["The from.email must be verified."]}}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unauthenticated",
+			response: &apiResponse{
+				status: http.StatusUnauthorized,
+				body:   `{"message":"Unauthenticated."}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "outage",
+			response: &apiResponse{
+				status: http.StatusServiceUnavailable,
+			},
+			wantErr:   true,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveAPI(t, tt.response, func(r *http.Request) {
+				if r.URL.Path != "/v1/email" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer mlsn.test" {
+					t.Errorf("unexpected authorization %q", got)
+				}
+			})
+
+			client, err := newMailerSendClient(&config.MailerConfig{
+				SenderEmail:       "digest@example.com",
+				SenderName:        "Digest",
+				MailerSendAPIKey:  "mlsn.test",
+				MailerSendBaseURL: srv.URL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := client.SendMail(context.Background(), &Message{
+				To:      []string{"someone@example.com"},
+				Subject: "Digest",
+				Text:    "Hello",
+			})
+			if tt.wantErr {
+				checkSendError(t, err, tt.response.status, tt.retryable, tt.retryAfter)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.MessageID != tt.messageID {
+				t.Errorf("message id = %q, want %q", out.MessageID, tt.messageID)
+			}
+		})
+	}
+}
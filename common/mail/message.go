@@ -0,0 +1,431 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// Message is a provider independent email. Providers that talk SMTP or accept raw messages use the MIME encoding
+	// produced by encode, the HTTP API providers map the fields onto their JSON payloads.
+	Message struct {
+		To      []string
+		Cc      []string
+		Bcc     []string
+		ReplyTo string
+		Subject string
+		// Text and HTML are sent as multipart/alternative if both are set.
+		Text string
+		HTML string
+		// Headers are additional header fields. Fields that are derived from the other Message fields (From, To,
+		// Subject, Content-Type, ...) can't be overridden.
+		Headers     map[string]string
+		Attachments []*Attachment
+		// IdempotencyKey is passed to providers that deduplicate requests, so a retried send doesn't result in a
+		// second email. It is not part of the encoded message.
+		IdempotencyKey string
+
+		// signed is the encoded message after DKIM signing. Providers that send raw messages have to send it
+		// unchanged, any re-encoding would break the signature.
+		signed *encodedMessage
+	}
+
+	encodedMessage struct {
+		raw       []byte
+		messageID string
+	}
+
+	Attachment struct {
+		Filename    string
+		ContentType string
+		Data        []byte
+		// ContentID makes the attachment an inline part of the HTML body, referenced as "cid:<ContentID>". Only
+		// providers supporting FeatureInline keep it inline, see Supports.
+		ContentID string
+	}
+
+	SendMailOutput struct {
+		// MessageID is the ID assigned by the provider, or the Message-ID header for providers that don't assign one.
+		MessageID string
+		// Provider is the name of the provider that accepted the message.
+		Provider string
+	}
+)
+
+var reservedHeaders = map[string]struct{}{
+	"From":                      {},
+	"To":                        {},
+	"Cc":                        {},
+	"Bcc":                       {},
+	"Reply-To":                  {},
+	"Subject":                   {},
+	"Date":                      {},
+	"Message-Id":                {},
+	"Mime-Version":              {},
+	"Content-Type":              {},
+	"Content-Transfer-Encoding": {},
+}
+
+// validate checks the parts of a message that would otherwise produce a malformed or ambiguous encoding.
+func (m *Message) validate() error {
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, address := range list {
+			if _, err := mail.ParseAddress(address); err != nil {
+				return fmt.Errorf("invalid recipient %q: %w", address, err)
+			}
+		}
+	}
+
+	if m.ReplyTo != "" {
+		if _, err := mail.ParseAddress(m.ReplyTo); err != nil {
+			return fmt.Errorf("invalid reply-to %q: %w", m.ReplyTo, err)
+		}
+	}
+
+	for k, v := range m.Headers {
+		if _, ok := reservedHeaders[textproto.CanonicalMIMEHeaderKey(k)]; ok {
+			return fmt.Errorf("header %q can't be set explicitly", k)
+		}
+		if k == "" || strings.ContainsAny(k, ": \t\r\n") || strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("invalid header %q", k)
+		}
+	}
+
+	for _, a := range m.Attachments {
+		if a.Filename == "" {
+			return errors.New("attachment without filename")
+		}
+		if a.ContentID != "" {
+			if m.HTML == "" {
+				return errors.New("inline attachment without html body")
+			}
+			if strings.ContainsAny(a.ContentID, "<> \t\r\n") {
+				return fmt.Errorf("invalid content id %q", a.ContentID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// recipients returns the envelope recipients, including Bcc which never shows up in the encoded headers.
+func (m *Message) recipients() []string {
+	rcpt := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	rcpt = append(rcpt, m.To...)
+	rcpt = append(rcpt, m.Cc...)
+	rcpt = append(rcpt, m.Bcc...)
+	return rcpt
+}
+
+func (a *Attachment) contentType() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	if ext := strings.LastIndexByte(a.Filename, '.'); ext >= 0 {
+		if t := mime.TypeByExtension(a.Filename[ext:]); t != "" {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
+
+// encoded returns the signed message if there is one, otherwise the message is encoded with a new Message-ID.
+func (m *Message) encoded(from *mail.Address) (*encodedMessage, error) {
+	if m.signed != nil {
+		return m.signed, nil
+	}
+
+	messageID := newMessageID(from.Address)
+
+	raw, err := m.encode(from, messageID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &encodedMessage{
+		raw:       raw,
+		messageID: messageID,
+	}, nil
+}
+
+type mimePart struct {
+	header  textproto.MIMEHeader
+	body    []byte
+	subtype string
+	parts   []*mimePart
+}
+
+// encode renders the message as RFC 5322 message with MIME body. The structure is
+//
+//	multipart/mixed                 (only with attachments)
+//	├── multipart/alternative       (only with both text and html)
+//	│   ├── text/plain
+//	│   └── multipart/related       (only with inline attachments)
+//	│       ├── text/html
+//	│       └── inline attachments...
+//	└── attachments...
+func (m *Message) encode(from *mail.Address, messageID string, date time.Time) ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	root, err := m.rootPart()
+	if err != nil {
+		return nil, err
+	}
+
+	rootHeader, body, err := root.render()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "From", from.String())
+	if len(m.To) > 0 {
+		writeHeader(&buf, "To", formatAddressList(m.To))
+	}
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", formatAddressList(m.Cc))
+	}
+	if m.ReplyTo != "" {
+		writeHeader(&buf, "Reply-To", formatAddressList([]string{m.ReplyTo}))
+	}
+	writeHeader(&buf, "Subject", encodeHeaderWord(m.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	for _, k := range slices.Sorted(maps.Keys(m.Headers)) {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(k), encodeHeaderWord(m.Headers[k]))
+	}
+
+	for _, k := range []string{"Content-Type", "Content-Transfer-Encoding", "Content-Disposition"} {
+		if v := rootHeader.Get(k); v != "" {
+			writeHeader(&buf, k, v)
+		}
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+func (m *Message) rootPart() (*mimePart, error) {
+	var alternatives []*mimePart
+
+	if m.Text != "" {
+		text, err := quotedPrintablePart("text/plain", m.Text)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, text)
+	}
+
+	var attachments []*mimePart
+	if m.HTML != "" {
+		html, err := quotedPrintablePart("text/html", m.HTML)
+		if err != nil {
+			return nil, err
+		}
+
+		related := &mimePart{subtype: "related", parts: []*mimePart{html}}
+		for _, a := range m.Attachments {
+			if a.ContentID != "" {
+				related.parts = append(related.parts, attachmentPart(a))
+			}
+		}
+		if len(related.parts) > 1 {
+			html = related
+		}
+
+		alternatives = append(alternatives, html)
+	}
+
+	for _, a := range m.Attachments {
+		if a.ContentID == "" {
+			attachments = append(attachments, attachmentPart(a))
+		}
+	}
+
+	var body *mimePart
+	switch len(alternatives) {
+	case 0:
+		text, err := quotedPrintablePart("text/plain", "")
+		if err != nil {
+			return nil, err
+		}
+		body = text
+	case 1:
+		body = alternatives[0]
+	default:
+		body = &mimePart{subtype: "alternative", parts: alternatives}
+	}
+
+	if len(attachments) == 0 {
+		return body, nil
+	}
+
+	return &mimePart{subtype: "mixed", parts: append([]*mimePart{body}, attachments...)}, nil
+}
+
+func (p *mimePart) render() (textproto.MIMEHeader, []byte, error) {
+	if len(p.parts) == 0 {
+		return p.header, p.body, nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	for _, child := range p.parts {
+		header, body, err := child.render()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, err = pw.Write(body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+p.subtype, map[string]string{
+		"boundary": w.Boundary(),
+	}))
+
+	return header, buf.Bytes(), nil
+}
+
+func quotedPrintablePart(contentType, content string) (*mimePart, error) {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"charset": "UTF-8"}))
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	return &mimePart{header: header, body: buf.Bytes()}, nil
+}
+
+func attachmentPart(a *Attachment) *mimePart {
+	mediaType, params, err := mime.ParseMediaType(a.contentType())
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = a.Filename
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if a.ContentID != "" {
+		header.Set("Content-ID", "<"+a.ContentID+">")
+		header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Filename}))
+	} else {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	}
+
+	return &mimePart{header: header, body: base64Lines(a.Data)}
+}
+
+// base64Lines encodes data as base64 wrapped at 76 characters, as required by RFC 2045.
+func base64Lines(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
+
+func formatAddressList(list []string) string {
+	formatted := make([]string, 0, len(list))
+	for _, address := range list {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			// validate rejects these before we get here.
+			continue
+		}
+		formatted = append(formatted, parsed.String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// encodeHeaderWord applies RFC 2047 encoding if the value isn't plain printable ASCII.
+func encodeHeaderWord(value string) string {
+	return mime.QEncoding.Encode("UTF-8", value)
+}
+
+// writeHeader writes a header field, folding encoded words onto continuation lines so long subjects stay within the
+// recommended line length of RFC 5322.
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+
+	lineLen := len(key) + 2
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 {
+			if lineLen+1+len(word) > 78 {
+				buf.WriteString("\r\n")
+				lineLen = 0
+			}
+			buf.WriteString(" ")
+			lineLen++
+		}
+		buf.WriteString(word)
+		lineLen += len(word)
+	}
+
+	buf.WriteString("\r\n")
+}
+
+// newMessageID generates a globally unique Message-ID in the domain of the sender.
+func newMessageID(sender string) string {
+	domain := "localhost"
+	if at := strings.LastIndexByte(sender, '@'); at >= 0 && at < len(sender)-1 {
+		domain = sender[at+1:]
+	}
+
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b[:]), domain)
+}
@@ -0,0 +1,384 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeTestMessage encodes msg and parses it back, so tests check what a receiving client would see.
+func encodeTestMessage(t *testing.T, msg *Message) (*mail.Message, []byte) {
+	t.Helper()
+
+	from := &mail.Address{Name: "Digest", Address: "digest@example.com"}
+	raw, err := msg.encode(from, "<id@example.com>", time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line exceeds 998 characters: %q", line)
+		}
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse encoded message: %v\n%s", err, raw)
+	}
+
+	return parsed, raw
+}
+
+// mimeTree renders the structure of a MIME entity as e.g. "multipart/alternative[text/plain,text/html]". Parsing
+// the parts also checks that every boundary is declared and closed.
+func mimeTree(t *testing.T, contentType string, body io.Reader) string {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("parse content type %q: %v", contentType, err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return mediaType
+	}
+	if params["boundary"] == "" {
+		t.Fatalf("%s without boundary", mediaType)
+	}
+
+	var children []string
+	r := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := r.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part of %s: %v", mediaType, err)
+		}
+		children = append(children, mimeTree(t, part.Header.Get("Content-Type"), part))
+	}
+
+	return mediaType + "[" + strings.Join(children, ",") + "]"
+}
+
+func TestMessageEncodeStructure(t *testing.T) {
+	inline := &Attachment{Filename: "thumb.png", ContentType: "image/png", Data: []byte("png"), ContentID: "thumb1"}
+	attachment := &Attachment{Filename: "posts.csv", Data: []byte("id\n1")}
+
+	tests := []struct {
+		name string
+		msg  *Message
+		want string
+	}{
+		{
+			name: "empty body",
+			msg:  &Message{},
+			want: "text/plain",
+		},
+		{
+			name: "text",
+			msg:  &Message{Text: "Hello"},
+			want: "text/plain",
+		},
+		{
+			name: "html",
+			msg:  &Message{HTML: "<p>Hello</p>"},
+			want: "text/html",
+		},
+		{
+			name: "alternative",
+			msg:  &Message{Text: "Hello", HTML: "<p>Hello</p>"},
+			want: "multipart/alternative[text/plain,text/html]",
+		},
+		{
+			name: "inline image",
+			msg:  &Message{Text: "Hello", HTML: `<img src="cid:thumb1">`, Attachments: []*Attachment{inline}},
+			want: "multipart/alternative[text/plain,multipart/related[text/html,image/png]]",
+		},
+		{
+			name: "attachment",
+			msg:  &Message{Text: "Hello", Attachments: []*Attachment{attachment}},
+			want: "multipart/mixed[text/plain,text/csv]",
+		},
+		{
+			name: "everything",
+			msg: &Message{
+				Text:        "Hello",
+				HTML:        `<img src="cid:thumb1">`,
+				Attachments: []*Attachment{inline, attachment},
+			},
+			want: "multipart/mixed[multipart/alternative[text/plain,multipart/related[text/html,image/png]],text/csv]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.To = []string{"someone@example.com"}
+
+			parsed, _ := encodeTestMessage(t, tt.msg)
+
+			got := mimeTree(t, parsed.Header.Get("Content-Type"), parsed.Body)
+			if got, _, _ = strings.Cut(got, ";"); got != tt.want {
+				t.Errorf("structure = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageEncodeQuotedPrintable(t *testing.T) {
+	text := "Neue Beiträge für dich: 1 + 1 = 2\r\n" + strings.Repeat("lang ", 40) + "\r\nEnde."
+
+	parsed, raw := encodeTestMessage(t, &Message{To: []string{"someone@example.com"}, Text: text})
+
+	if got := parsed.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Fatalf("content transfer encoding = %q", got)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+		t.Errorf("content type = %q", got)
+	}
+
+	_, body, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+	for _, line := range strings.Split(string(body), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("quoted-printable line exceeds 76 characters: %q", line)
+		}
+		for _, r := range line {
+			if r > 127 {
+				t.Fatalf("unencoded non-ASCII character in %q", line)
+			}
+		}
+	}
+	if !bytes.Contains(body, []byte("Beitr=C3=A4ge")) || !bytes.Contains(body, []byte("1 + 1 =3D 2")) {
+		t.Errorf("unexpected encoding:\n%s", body)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != text {
+		t.Errorf("decoded body = %q, want %q", decoded, text)
+	}
+}
+
+func TestMessageEncodeHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		headers map[string]string
+		encoded bool
+	}{
+		{
+			name:    "ascii",
+			subject: "3 new posts in r/golang",
+		},
+		{
+			name:    "non-ascii",
+			subject: "Neue Beiträge in r/golang",
+			encoded: true,
+		},
+		{
+			name:    "long non-ascii",
+			subject: strings.Repeat("Grüße aus r/de ", 12),
+			encoded: true,
+		},
+		{
+			name:    "custom header",
+			subject: "Digest",
+			headers: map[string]string{"X-Schedule": "Ärger im Paradies"},
+		},
+	}
+
+	var decoder mime.WordDecoder
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, raw := encodeTestMessage(t, &Message{
+				To:      []string{"Jörg <joerg@example.com>"},
+				Subject: tt.subject,
+				Headers: tt.headers,
+				Text:    "Hello",
+			})
+
+			header, _, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+			for _, line := range strings.Split(string(header), "\r\n") {
+				// A single encoded word can't be folded, only lines with several words have to fit.
+				value := line
+				if _, v, ok := strings.Cut(line, ": "); ok {
+					value = v
+				}
+				if len(line) > 78 && strings.Contains(strings.TrimSpace(value), " ") {
+					t.Errorf("header line exceeds 78 characters: %q", line)
+				}
+			}
+
+			if tt.encoded != strings.Contains(string(header), "Subject: =?UTF-8?q?") {
+				t.Errorf("subject encoded = %t, want %t:\n%s", !tt.encoded, tt.encoded, header)
+			}
+
+			subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != tt.subject {
+				t.Errorf("subject = %q, want %q", subject, tt.subject)
+			}
+
+			for k, v := range tt.headers {
+				got, err := decoder.DecodeHeader(parsed.Header.Get(k))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+
+			to, err := parsed.Header.AddressList("To")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(to) != 1 || to[0].Name != "Jörg" || to[0].Address != "joerg@example.com" {
+				t.Errorf("to = %v", to)
+			}
+		})
+	}
+}
+
+func TestMessageEncodeInlineAttachment(t *testing.T) {
+	data := bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, 40)
+
+	parsed, _ := encodeTestMessage(t, &Message{
+		To:   []string{"someone@example.com"},
+		HTML: `<img src="cid:thumb-1o2abcd@digest">`,
+		Attachments: []*Attachment{
+			{Filename: "thumb.png", ContentType: "image/png", Data: data, ContentID: "thumb-1o2abcd@digest"},
+		},
+	})
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := multipart.NewReader(parsed.Body, params["boundary"])
+	if _, err = r.NextRawPart(); err != nil {
+		t.Fatalf("html part: %v", err)
+	}
+
+	part, err := r.NextRawPart()
+	if err != nil {
+		t.Fatalf("inline part: %v", err)
+	}
+
+	if got := part.Header.Get("Content-ID"); got != "<thumb-1o2abcd@digest>" {
+		t.Errorf("content id = %q", got)
+	}
+	if got := part.Header.Get("Content-Disposition"); got != "inline; filename=thumb.png" {
+		t.Errorf("content disposition = %q", got)
+	}
+	if got := part.Header.Get("Content-Type"); got != "image/png; name=thumb.png" {
+		t.Errorf("content type = %q", got)
+	}
+	if got := part.Header.Get("Content-Transfer-Encoding"); got != "base64" {
+		t.Errorf("content transfer encoding = %q", got)
+	}
+
+	encoded, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("base64 line exceeds 76 characters: %q", line)
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded attachment differs from data")
+	}
+
+	if _, err = r.NextRawPart(); err != io.EOF {
+		t.Errorf("expected end of multipart/related, got %v", err)
+	}
+}
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *Message
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			msg:  &Message{To: []string{"someone@example.com"}, Headers: map[string]string{"List-Id": "digest"}},
+		},
+		{
+			name:    "no recipients",
+			msg:     &Message{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid recipient",
+			msg:     &Message{Bcc: []string{"not an address"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid reply-to",
+			msg:     &Message{To: []string{"someone@example.com"}, ReplyTo: "nope"},
+			wantErr: true,
+		},
+		{
+			name:    "reserved header",
+			msg:     &Message{To: []string{"someone@example.com"}, Headers: map[string]string{"subject": "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "header injection",
+			msg:     &Message{To: []string{"someone@example.com"}, Headers: map[string]string{"X-Tag": "a\r\nBcc: b@example.com"}},
+			wantErr: true,
+		},
+		{
+			name: "inline attachment without html",
+			msg: &Message{
+				To:          []string{"someone@example.com"},
+				Text:        "Hello",
+				Attachments: []*Attachment{{Filename: "a.png", ContentID: "a"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid content id",
+			msg: &Message{
+				To:          []string{"someone@example.com"},
+				HTML:        "<p>Hello</p>",
+				Attachments: []*Attachment{{Filename: "a.png", ContentID: "<a>"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "attachment without filename",
+			msg:     &Message{To: []string{"someone@example.com"}, Attachments: []*Attachment{{Data: []byte("x")}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.msg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"html"
+	"net/http"
+)
+
+const plunkDefaultBaseURL = "https://api.useplunk.com"
+
+type (
+	plunkClient struct {
+		config *config.MailerConfig
+		api    *apiClient
+	}
+
+	plunkSendRequest struct {
+		To      []string          `json:"to"`
+		Subject string            `json:"subject"`
+		Body    string            `json:"body"`
+		From    string            `json:"from,omitempty"`
+		Name    string            `json:"name,omitempty"`
+		Reply   string            `json:"reply,omitempty"`
+		Headers map[string]string `json:"headers,omitempty"`
+	}
+
+	plunkSendResponse struct {
+		Success bool `json:"success"`
+	}
+)
+
+func newPlunkClient(config *config.MailerConfig) (Mailer, error) {
+	if config.PlunkAPIKey == "" {
+		return nil, errors.New("plunk: api key is required")
+	}
+
+	return &plunkClient{
+		config: config,
+		api:    newAPIClient("plunk", config.PlunkBaseURL, plunkDefaultBaseURL),
+	}, nil
+}
+
+// SendMail maps the message onto Plunk's send endpoint. Plunk only takes a single HTML body and sends one email per
+// address in To, so Cc, Bcc and attachments are rejected instead of being silently dropped.
+func (p *plunkClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	if err := msg.validate(); err != nil {
+		return nil, invalidMessage("plunk", err)
+	}
+	if len(msg.Cc) > 0 || len(msg.Bcc) > 0 {
+		return nil, unsupported("plunk", "cc/bcc")
+	}
+	if len(msg.Attachments) > 0 {
+		return nil, unsupported("plunk", "attachments")
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.config.PlunkAPIKey)
+
+	body := msg.HTML
+	if body == "" {
+		body = html.EscapeString(msg.Text)
+	}
+
+	var res plunkSendResponse
+	if _, err := p.api.do(ctx, http.MethodPost, "/v1/send", header, &plunkSendRequest{
+		To:      msg.To,
+		Subject: msg.Subject,
+		Body:    body,
+		From:    p.config.SenderEmail,
+		Name:    p.config.SenderName,
+		Reply:   msg.ReplyTo,
+		Headers: msg.Headers,
+	}, &res); err != nil {
+		return nil, err
+	}
+
+	return &SendMailOutput{}, nil
+}
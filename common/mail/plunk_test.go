@@ -0,0 +1,137 @@
+package mail
+
+import (
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPlunkClientSendMail(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        *Message
+		response   *apiResponse
+		wantErr    bool
+		status     int
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name: "accepted",
+			response: &apiResponse{
+				status: http.StatusOK,
+				body:   `{"success":true,"emails":[],"timestamp":"2026-10-17T08:00:00.000Z"}`,
+			},
+		},
+		{
+			name: "rate limited",
+			response: &apiResponse{
+				status: http.StatusTooManyRequests,
+				header: map[string]string{"Retry-After": "5"},
+				body:   `{"code":429,"error":"Too Many Requests","message":"Rate limit exceeded"}`,
+			},
+			wantErr:    true,
+			status:     http.StatusTooManyRequests,
+			retryable:  true,
+			retryAfter: 5 * time.Second,
+		},
+		{
+			name: "unverified sender",
+			response: &apiResponse{
+				status: http.StatusBadRequest,
+				body:   `{"code":400,"error":"Bad Request","message":"Verify your domain before you start sending"}`,
+			},
+			wantErr: true,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "invalid api key",
+			response: &apiResponse{
+				status: http.StatusUnauthorized,
+				body:   `{"code":401,"error":"Unauthorized","message":"Incorrect Bearer token specified"}`,
+			},
+			wantErr: true,
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name: "outage",
+			response: &apiResponse{
+				status: http.StatusBadGateway,
+			},
+			wantErr:   true,
+			status:    http.StatusBadGateway,
+			retryable: true,
+		},
+		{
+			name: "bcc",
+			msg: &Message{
+				Bcc:     []string{"someone@example.com"},
+				Subject: "Digest",
+				Text:    "Hello",
+			},
+			wantErr: true,
+		},
+		{
+			name: "attachments",
+			msg: &Message{
+				To:          []string{"someone@example.com"},
+				Subject:     "Digest",
+				HTML:        `<img src="cid:thumbnail">`,
+				Attachments: []*Attachment{{Filename: "thumbnail.png", Data: []byte{0x89}, ContentID: "thumbnail"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Messages Plunk can't represent are rejected before anything is sent.
+			response := tt.response
+			if response == nil {
+				response = &apiResponse{status: http.StatusOK}
+			}
+			srv := serveAPI(t, response, func(r *http.Request) {
+				if tt.response == nil {
+					t.Error("unexpected request")
+				}
+				if r.URL.Path != "/v1/send" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer sk_test" {
+					t.Errorf("unexpected authorization %q", got)
+				}
+			})
+
+			client, err := newPlunkClient(&config.MailerConfig{
+				SenderEmail:  "digest@example.com",
+				SenderName:   "Digest",
+				PlunkAPIKey:  "sk_test",
+				PlunkBaseURL: srv.URL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg := tt.msg
+			if msg == nil {
+				msg = &Message{
+					To:      []string{"someone@example.com"},
+					Subject: "Digest",
+					Text:    "Hello",
+				}
+			}
+
+			_, err = client.SendMail(context.Background(), msg)
+			if tt.wantErr {
+				checkSendError(t, err, tt.status, tt.retryable, tt.retryAfter)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+)
+
+const resendDefaultBaseURL = "https://api.resend.com"
+
+type (
+	resendClient struct {
+		config *config.MailerConfig
+		api    *apiClient
+	}
+
+	resendAttachment struct {
+		Filename    string `json:"filename"`
+		Content     string `json:"content"`
+		ContentType string `json:"content_type,omitempty"`
+		ContentID   string `json:"content_id,omitempty"`
+	}
+
+	resendSendEmailRequest struct {
+		From        string              `json:"from"`
+		To          []string            `json:"to,omitempty"`
+		Cc          []string            `json:"cc,omitempty"`
+		Bcc         []string            `json:"bcc,omitempty"`
+		ReplyTo     string              `json:"reply_to,omitempty"`
+		Subject     string              `json:"subject"`
+		HTML        string              `json:"html,omitempty"`
+		Text        string              `json:"text,omitempty"`
+		Headers     map[string]string   `json:"headers,omitempty"`
+		Attachments []*resendAttachment `json:"attachments,omitempty"`
+	}
+
+	resendSendEmailResponse struct {
+		ID string `json:"id"`
+	}
+
+	resendErrorResponse struct {
+		StatusCode int    `json:"statusCode"`
+		Name       string `json:"name"`
+		Message    string `json:"message"`
+	}
+)
+
+func newResendClient(config *config.MailerConfig) (Mailer, error) {
+	if config.ResendAPIKey == "" {
+		return nil, errors.New("resend: api key is required")
+	}
+
+	return &resendClient{
+		config: config,
+		api:    newAPIClient("resend", config.ResendBaseURL, resendDefaultBaseURL),
+	}, nil
+}
+
+func (r *resendClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	if err := msg.validate(); err != nil {
+		return nil, invalidMessage("resend", err)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+r.config.ResendAPIKey)
+	if msg.IdempotencyKey != "" {
+		header.Set("Idempotency-Key", msg.IdempotencyKey)
+	}
+
+	req := &resendSendEmailRequest{
+		From:    sender(r.config).String(),
+		To:      msg.To,
+		Cc:      msg.Cc,
+		Bcc:     msg.Bcc,
+		ReplyTo: msg.ReplyTo,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+		Headers: msg.Headers,
+	}
+
+	for _, a := range msg.Attachments {
+		req.Attachments = append(req.Attachments, &resendAttachment{
+			Filename:    a.Filename,
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			ContentType: a.contentType(),
+			ContentID:   a.ContentID,
+		})
+	}
+
+	var res resendSendEmailResponse
+	if _, err := r.api.do(ctx, http.MethodPost, "/emails", header, req, &res); err != nil {
+		return nil, resendError(err)
+	}
+
+	return &SendMailOutput{
+		MessageID: res.ID,
+	}, nil
+}
+
+func (r *resendClient) features() Feature {
+	return FeatureBCC | FeatureInline
+}
+
+// resendError refines the generic status code classification with the error name reported by Resend. Exhausted
+// daily or monthly quotas are answered with 429, but retrying within the activity's retry window won't help.
+func resendError(err error) error {
+	target, ok := errors.AsType[*SendError](err)
+	if !ok || target.StatusCode == 0 {
+		return err
+	}
+
+	var body resendErrorResponse
+	if json.Unmarshal([]byte(target.Message), &body) != nil || body.Name == "" {
+		return err
+	}
+
+	target.Message = fmt.Sprintf("%s: %s", body.Name, body.Message)
+
+	switch body.Name {
+	case "daily_quota_exceeded", "monthly_quota_exceeded":
+		target.Retryable = false
+	}
+
+	return target
+}
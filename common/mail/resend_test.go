@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResendClientSendMail(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     map[string]string
+		body       string
+		messageID  string
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name:      "accepted",
+			status:    http.StatusOK,
+			body:      `{"id":"49a3999c-0ce1-4ea6-ab68-afcd6dc2e794"}`,
+			messageID: "49a3999c-0ce1-4ea6-ab68-afcd6dc2e794",
+		},
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"Retry-After": "2"},
+			body:       `{"statusCode":429,"name":"rate_limit_exceeded","message":"Too many requests."}`,
+			retryable:  true,
+			retryAfter: 2 * time.Second,
+		},
+		{
+			name:   "daily quota exceeded",
+			status: http.StatusTooManyRequests,
+			body:   `{"statusCode":429,"name":"daily_quota_exceeded","message":"You have reached your daily email sending quota."}`,
+		},
+		{
+			name:   "monthly quota exceeded",
+			status: http.StatusTooManyRequests,
+			body:   `{"statusCode":429,"name":"monthly_quota_exceeded","message":"You have reached your monthly email sending quota."}`,
+		},
+		{
+			name:   "validation error",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"statusCode":422,"name":"validation_error","message":"Invalid from field."}`,
+		},
+		{
+			name:   "invalid api key",
+			status: http.StatusForbidden,
+			body:   `{"statusCode":403,"name":"invalid_api_key","message":"API key is invalid"}`,
+		},
+		{
+			name:      "outage",
+			status:    http.StatusBadGateway,
+			body:      `<html>Bad Gateway</html>`,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/emails" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer re_test" {
+					t.Errorf("unexpected authorization %q", got)
+				}
+				if got := r.Header.Get("Idempotency-Key"); got != "digest/1" {
+					t.Errorf("unexpected idempotency key %q", got)
+				}
+
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client, err := newResendClient(&config.MailerConfig{
+				SenderEmail:   "digest@example.com",
+				SenderName:    "Digest",
+				ResendAPIKey:  "re_test",
+				ResendBaseURL: srv.URL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := client.SendMail(context.Background(), &Message{
+				To:             []string{"someone@example.com"},
+				Subject:        "Digest",
+				Text:           "Hello",
+				IdempotencyKey: "digest/1",
+			})
+			if tt.status == http.StatusOK {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out.MessageID != tt.messageID {
+					t.Errorf("message id = %q, want %q", out.MessageID, tt.messageID)
+				}
+				return
+			}
+
+			target, ok := errors.AsType[*SendError](err)
+			if !ok {
+				t.Fatalf("expected *SendError, got %v", err)
+			}
+			if target.StatusCode != tt.status {
+				t.Errorf("status code = %d, want %d", target.StatusCode, tt.status)
+			}
+			if target.Retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", target.Retryable, tt.retryable)
+			}
+			if target.RetryAfter != tt.retryAfter {
+				t.Errorf("retry after = %s, want %s", target.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+const (
+	scalewayDefaultBaseURL = "https://api.scaleway.com"
+	scalewayDefaultRegion  = "fr-par"
+)
+
+type (
+	scalewayClient struct {
+		config *config.MailerConfig
+		api    *apiClient
+		region string
+	}
+
+	scalewayAddress struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+
+	scalewayAttachment struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+
+	scalewayHeader struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	scalewayEmailRequest struct {
+		From              scalewayAddress       `json:"from"`
+		To                []scalewayAddress     `json:"to,omitempty"`
+		Cc                []scalewayAddress     `json:"cc,omitempty"`
+		Bcc               []scalewayAddress     `json:"bcc,omitempty"`
+		Subject           string                `json:"subject"`
+		HTML              string                `json:"html"`
+		Text              string                `json:"text"`
+		ProjectID         string                `json:"project_id"`
+		Attachments       []*scalewayAttachment `json:"attachments,omitempty"`
+		AdditionalHeaders []*scalewayHeader     `json:"additional_headers,omitempty"`
+	}
+
+	scalewayEmailResponse struct {
+		Emails []struct {
+			ID string `json:"id"`
+		} `json:"emails"`
+	}
+)
+
+func newScalewayClient(config *config.MailerConfig) (Mailer, error) {
+	if config.ScalewaySecretKey == "" || config.ScalewayProjectID == "" {
+		return nil, errors.New("scaleway: secret key and project id are required")
+	}
+
+	region := config.ScalewayRegion
+	if region == "" {
+		region = scalewayDefaultRegion
+	}
+
+	return &scalewayClient{
+		config: config,
+		api:    newAPIClient("scaleway", config.ScalewayBaseURL, scalewayDefaultBaseURL),
+		region: region,
+	}, nil
+}
+
+func (s *scalewayClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	if err := msg.validate(); err != nil {
+		return nil, invalidMessage("scaleway", err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Auth-Token", s.config.ScalewaySecretKey)
+
+	req := &scalewayEmailRequest{
+		From: scalewayAddress{
+			Email: s.config.SenderEmail,
+			Name:  s.config.SenderName,
+		},
+		To:        scalewayAddresses(msg.To),
+		Cc:        scalewayAddresses(msg.Cc),
+		Bcc:       scalewayAddresses(msg.Bcc),
+		Subject:   msg.Subject,
+		HTML:      msg.HTML,
+		Text:      msg.Text,
+		ProjectID: s.config.ScalewayProjectID,
+	}
+
+	// TEM has no dedicated Reply-To field, it's passed along with the other additional headers.
+	if msg.ReplyTo != "" {
+		req.AdditionalHeaders = append(req.AdditionalHeaders, &scalewayHeader{Key: "Reply-To", Value: msg.ReplyTo})
+	}
+	for _, k := range slices.Sorted(maps.Keys(msg.Headers)) {
+		req.AdditionalHeaders = append(req.AdditionalHeaders, &scalewayHeader{Key: k, Value: msg.Headers[k]})
+	}
+
+	for _, a := range msg.Attachments {
+		req.Attachments = append(req.Attachments, &scalewayAttachment{
+			Name:    a.Filename,
+			Type:    a.contentType(),
+			Content: base64.StdEncoding.EncodeToString(a.Data),
+		})
+	}
+
+	var res scalewayEmailResponse
+	if _, err := s.api.do(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("/transactional-email/v1alpha1/regions/%s/emails", s.region),
+		header,
+		req,
+		&res,
+	); err != nil {
+		return nil, err
+	}
+
+	// TEM creates one email per recipient, the first ID is enough to look the message up.
+	out := &SendMailOutput{}
+	if len(res.Emails) > 0 {
+		out.MessageID = res.Emails[0].ID
+	}
+
+	return out, nil
+}
+
+func (s *scalewayClient) features() Feature {
+	return FeatureBCC
+}
+
+func scalewayAddresses(list []string) []scalewayAddress {
+	addresses := make([]scalewayAddress, 0, len(list))
+	for _, address := range list {
+		addresses = append(addresses, scalewayAddress{Email: address})
+	}
+	return addresses
+}
@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestScalewayClientSendMail(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   *apiResponse
+		messageID  string
+		wantErr    bool
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{
+			name: "accepted",
+			response: &apiResponse{
+				status: http.StatusOK,
+				body:   `{"emails":[{"id":"6c4b3e2f-0d4a-4f3b-9a8e-1b2c3d4e5f60"},{"id":"7d5c4f30-1e5b-405c-ab9f-2c3d4e5f6071"}]}`,
+			},
+			messageID: "6c4b3e2f-0d4a-4f3b-9a8e-1b2c3d4e5f60",
+		},
+		{
+			name: "quota exceeded",
+			response: &apiResponse{
+				status: http.StatusTooManyRequests,
+				header: map[string]string{"Retry-After": "60"},
+				body:   `{"message":"quota exceeded","type":"quotas_exceeded"}`,
+			},
+			wantErr:    true,
+			retryable:  true,
+			retryAfter: time.Minute,
+		},
+		{
+			name: "invalid argument",
+			response: &apiResponse{
+				status: http.StatusBadRequest,
+				body:   `{"message":"invalid argument(s)","type":"invalid_arguments"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "permission denied",
+			response: &apiResponse{
+				status: http.StatusForbidden,
+				body:   `{"message":"insufficient permissions","type":"permissions_denied"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "outage",
+			response: &apiResponse{
+				status: http.StatusInternalServerError,
+			},
+			wantErr:   true,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveAPI(t, tt.response, func(r *http.Request) {
+				if r.URL.Path != "/transactional-email/v1alpha1/regions/nl-ams/emails" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if got := r.Header.Get("X-Auth-Token"); got != "scw-secret" {
+					t.Errorf("unexpected auth token %q", got)
+				}
+			})
+
+			client, err := newScalewayClient(&config.MailerConfig{
+				SenderEmail:       "digest@example.com",
+				SenderName:        "Digest",
+				ScalewaySecretKey: "scw-secret",
+				ScalewayProjectID: "project",
+				ScalewayRegion:    "nl-ams",
+				ScalewayBaseURL:   srv.URL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := client.SendMail(context.Background(), &Message{
+				To:      []string{"someone@example.com", "someone-else@example.com"},
+				Subject: "Digest",
+				Text:    "Hello",
+			})
+			if tt.wantErr {
+				checkSendError(t, err, tt.response.status, tt.retryable, tt.retryAfter)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.MessageID != tt.messageID {
+				t.Errorf("message id = %q, want %q", out.MessageID, tt.messageID)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	sesClient struct {
+		config *config.MailerConfig
+		api    *apiClient
+	}
+
+	// sesSendEmailRequest always uses raw content. It's the only SES content type that supports attachments and
+	// custom headers, and it means SES receives the exact MIME message every other provider would produce.
+	sesSendEmailRequest struct {
+		FromEmailAddress string `json:"FromEmailAddress"`
+		Destination      struct {
+			ToAddresses  []string `json:"ToAddresses,omitempty"`
+			CcAddresses  []string `json:"CcAddresses,omitempty"`
+			BccAddresses []string `json:"BccAddresses,omitempty"`
+		} `json:"Destination"`
+		Content struct {
+			Raw struct {
+				Data []byte `json:"Data"`
+			} `json:"Raw"`
+		} `json:"Content"`
+	}
+
+	sesSendEmailResponse struct {
+		MessageID string `json:"MessageId"`
+	}
+
+	sesErrorResponse struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+)
+
+func newSESClient(config *config.MailerConfig) (Mailer, error) {
+	if config.SESAccessKeyID == "" || config.SESSecretAccessKey == "" || config.SESRegion == "" {
+		return nil, errors.New("ses: access key, secret and region are required")
+	}
+
+	api := newAPIClient("ses", config.SESEndpoint, fmt.Sprintf("https://email.%s.amazonaws.com", config.SESRegion))
+
+	signer := &sigV4Signer{
+		accessKeyID:     config.SESAccessKeyID,
+		secretAccessKey: config.SESSecretAccessKey,
+		sessionToken:    config.SESSessionToken,
+		region:          config.SESRegion,
+		service:         "ses",
+		now:             time.Now,
+	}
+	api.sign = signer.Sign
+
+	return &sesClient{
+		config: config,
+		api:    api,
+	}, nil
+}
+
+func (s *sesClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	from := sender(s.config)
+
+	encoded, err := msg.encoded(from)
+	if err != nil {
+		return nil, invalidMessage("ses", err)
+	}
+
+	var req sesSendEmailRequest
+	req.FromEmailAddress = from.String()
+	req.Destination.ToAddresses = msg.To
+	req.Destination.CcAddresses = msg.Cc
+	req.Destination.BccAddresses = msg.Bcc
+	req.Content.Raw.Data = encoded.raw
+
+	var res sesSendEmailResponse
+	if _, err = s.api.do(ctx, http.MethodPost, "/v2/email/outbound-emails", nil, &req, &res); err != nil {
+		return nil, sesError(err)
+	}
+
+	return &SendMailOutput{
+		MessageID: res.MessageID,
+	}, nil
+}
+
+func (s *sesClient) features() Feature {
+	return FeatureBCC | FeatureInline | featureRaw
+}
+
+// sesError classifies SES errors by their exception type. SES throttles with TooManyRequestsException (429), which is
+// already retryable by status code, but sending quota and account level errors come back as 400 and must not be
+// retried while some throttling related exceptions don't use 429 at all.
+func sesError(err error) error {
+	target, ok := errors.AsType[*SendError](err)
+	if !ok || target.StatusCode == 0 {
+		return err
+	}
+
+	var body sesErrorResponse
+	_ = json.Unmarshal([]byte(target.Message), &body)
+
+	// The type is in the X-Amzn-ErrorType header, the body only repeats it for some errors.
+	errorType := sesErrorType(target.header.Get("X-Amzn-ErrorType"))
+	if errorType == "" {
+		errorType = sesErrorType(body.Type)
+	}
+	if errorType == "" {
+		return err
+	}
+
+	message := body.Message
+	if message == "" {
+		message = target.Message
+	}
+	target.Message = fmt.Sprintf("%s: %s", errorType, message)
+
+	switch errorType {
+	case "TooManyRequestsException", "ThrottlingException":
+		target.Retryable = true
+	case "LimitExceededException", "AccountSuspendedException", "SendingPausedException", "MailFromDomainNotVerifiedException", "MessageRejected":
+		target.Retryable = false
+	}
+
+	return target
+}
+
+// sesErrorType strips the namespace and the documentation URL from an error type, e.g.
+// "com.amazonaws.sesv2#MessageRejected" or "MessageRejected:http://internal.amazon.com/coral/com.amazonaws.sesv2/".
+func sesErrorType(value string) string {
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.LastIndexByte(value, '#'); i >= 0 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,150 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSESClientSendMail(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  *apiResponse
+		messageID string
+		wantErr   bool
+		retryable bool
+		message   string
+	}{
+		{
+			name: "accepted",
+			response: &apiResponse{
+				status: http.StatusOK,
+				body:   `{"MessageId":"010001812345abcd-0a1b2c3d-4e5f-6789-abcd-ef0123456789-000000"}`,
+			},
+			messageID: "010001812345abcd-0a1b2c3d-4e5f-6789-abcd-ef0123456789-000000",
+		},
+		{
+			name: "throttled without 429",
+			response: &apiResponse{
+				status: http.StatusBadRequest,
+				header: map[string]string{"X-Amzn-ErrorType": "ThrottlingException:http://internal.amazon.com/coral/com.amazon.coral.availability/"},
+				body:   `{"message":"Maximum sending rate exceeded."}`,
+			},
+			wantErr:   true,
+			retryable: true,
+			message:   "ThrottlingException: Maximum sending rate exceeded.",
+		},
+		{
+			name: "rejected by header",
+			response: &apiResponse{
+				status: http.StatusBadRequest,
+				header: map[string]string{"X-Amzn-ErrorType": "MessageRejected:http://internal.amazon.com/coral/com.amazonaws.sesv2/"},
+				body:   `{"message":"Email address is not verified."}`,
+			},
+			wantErr: true,
+			message: "MessageRejected: Email address is not verified.",
+		},
+		{
+			name: "quota by body",
+			response: &apiResponse{
+				status: http.StatusBadRequest,
+				body:   `{"__type":"com.amazonaws.sesv2#LimitExceededException","message":"Daily message quota exceeded."}`,
+			},
+			wantErr: true,
+			message: "LimitExceededException: Daily message quota exceeded.",
+		},
+		{
+			name: "account paused",
+			response: &apiResponse{
+				status: http.StatusServiceUnavailable,
+				header: map[string]string{"X-Amzn-ErrorType": "SendingPausedException"},
+				body:   `{"message":"Sending is paused for this account."}`,
+			},
+			wantErr: true,
+			message: "SendingPausedException: Sending is paused for this account.",
+		},
+		{
+			name: "outage",
+			response: &apiResponse{
+				status: http.StatusInternalServerError,
+				body:   `internal error`,
+			},
+			wantErr:   true,
+			retryable: true,
+			message:   "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveAPI(t, tt.response, func(r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v2/email/outbound-emails" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				auth := r.Header.Get("Authorization")
+				if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+					!strings.Contains(auth, "/eu-west-1/ses/aws4_request, SignedHeaders=") {
+					t.Errorf("unexpected authorization %q", auth)
+				}
+				if r.Header.Get("X-Amz-Date") == "" {
+					t.Error("missing x-amz-date")
+				}
+				if got := r.Header.Get("X-Amz-Security-Token"); got != "session" {
+					t.Errorf("security token = %q", got)
+				}
+
+				var req sesSendEmailRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatalf("decode request: %v", err)
+				}
+				if req.FromEmailAddress != `"Digest" <digest@example.com>` {
+					t.Errorf("from = %q", req.FromEmailAddress)
+				}
+				if len(req.Destination.ToAddresses) != 1 || req.Destination.ToAddresses[0] != "someone@example.com" {
+					t.Errorf("to = %v", req.Destination.ToAddresses)
+				}
+				if !strings.Contains(string(req.Content.Raw.Data), "Subject: Digest\r\n") {
+					t.Errorf("raw message without subject:\n%s", req.Content.Raw.Data)
+				}
+			})
+
+			client, err := newSESClient(&config.MailerConfig{
+				SenderEmail:        "digest@example.com",
+				SenderName:         "Digest",
+				SESAccessKeyID:     "AKIDEXAMPLE",
+				SESSecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+				SESSessionToken:    "session",
+				SESRegion:          "eu-west-1",
+				SESEndpoint:        srv.URL,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := client.SendMail(context.Background(), &Message{
+				To:      []string{"someone@example.com"},
+				Subject: "Digest",
+				Text:    "Hello",
+			})
+			if tt.wantErr {
+				checkSendError(t, err, tt.response.status, tt.retryable, 0)
+				if target, ok := errors.AsType[*SendError](err); ok && target.Message != tt.message {
+					t.Errorf("message = %q, want %q", target.Message, tt.message)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.MessageID != tt.messageID {
+				t.Errorf("message id = %q, want %q", out.MessageID, tt.messageID)
+			}
+		})
+	}
+}
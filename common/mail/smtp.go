@@ -0,0 +1,265 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+const (
+	smtpTLSNone     = "none"
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "implicit"
+
+	smtpAuthNone    = "none"
+	smtpAuthPlain   = "plain"
+	smtpAuthLogin   = "login"
+	smtpAuthCRAMMD5 = "crammd5"
+)
+
+type (
+	smtpOptions struct {
+		host     string
+		port     int
+		tlsMode  string
+		authMode string
+		username string
+		password string
+	}
+
+	smtpClient struct {
+		config  *config.MailerConfig
+		options *smtpOptions
+	}
+)
+
+func newSMTPClient(config *config.MailerConfig) (Mailer, error) {
+	return newSMTPClientWithOptions(config, &smtpOptions{
+		host:     config.SMTPHost,
+		port:     config.SMTPPort,
+		tlsMode:  config.SMTPTLS,
+		authMode: config.SMTPAuth,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+	})
+}
+
+func newSMTPClientWithOptions(config *config.MailerConfig, options *smtpOptions) (*smtpClient, error) {
+	if options.host == "" {
+		return nil, errors.New("smtp: host is required")
+	}
+	if options.tlsMode == "" {
+		options.tlsMode = smtpTLSStartTLS
+	}
+	if options.authMode == "" {
+		options.authMode = smtpAuthPlain
+	}
+	if options.port == 0 {
+		switch options.tlsMode {
+		case smtpTLSImplicit:
+			options.port = 465
+		default:
+			options.port = 587
+		}
+	}
+
+	switch options.tlsMode {
+	case smtpTLSNone, smtpTLSStartTLS, smtpTLSImplicit:
+	default:
+		return nil, fmt.Errorf("smtp: unsupported tls mode %q", options.tlsMode)
+	}
+
+	switch options.authMode {
+	case smtpAuthNone, smtpAuthPlain, smtpAuthLogin, smtpAuthCRAMMD5:
+	default:
+		return nil, fmt.Errorf("smtp: unsupported auth mechanism %q", options.authMode)
+	}
+
+	return &smtpClient{
+		config:  config,
+		options: options,
+	}, nil
+}
+
+func (s *smtpClient) SendMail(ctx context.Context, msg *Message) (*SendMailOutput, error) {
+	encoded, err := msg.encoded(sender(s.config))
+	if err != nil {
+		return nil, invalidMessage("smtp", err)
+	}
+
+	if err = s.send(ctx, s.config.SenderEmail, msg.recipients(), encoded.raw); err != nil {
+		return nil, smtpError(err)
+	}
+
+	return &SendMailOutput{
+		MessageID: encoded.messageID,
+	}, nil
+}
+
+func (s *smtpClient) features() Feature {
+	return FeatureBCC | FeatureInline | featureRaw
+}
+
+// send delivers an already encoded message. net/smtp has no notion of a context, so the deadline of ctx is applied to
+// the underlying connection and the connection is closed if ctx is cancelled mid-transaction.
+func (s *smtpClient) send(ctx context.Context, from string, to []string, message []byte) error {
+	addr := net.JoinHostPort(s.options.host, strconv.Itoa(s.options.port))
+	tlsConfig := &tls.Config{ServerName: s.options.host}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	dialer := &net.Dialer{}
+	if s.options.tlsMode == smtpTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: dial %s: %w", addr, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.options.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: handshake: %w", err)
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if s.options.tlsMode == smtpTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp: starttls: %w", err)
+		}
+	}
+
+	if auth := s.auth(); auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support AUTH")
+		}
+		if err = c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp: auth: %w", err)
+		}
+	}
+
+	if err = c.Mail(from); err != nil {
+		return fmt.Errorf("smtp: mail from: %w", err)
+	}
+
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp: rcpt to %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: data: %w", err)
+	}
+
+	if _, err = w.Write(message); err != nil {
+		return fmt.Errorf("smtp: write message: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("smtp: close data: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// smtpError classifies by SMTP reply code. 5xx replies are permanent rejections (unknown mailbox, auth failure), 4xx
+// replies and connection errors are transient.
+func smtpError(err error) error {
+	sendErr := &SendError{
+		Provider:  "smtp",
+		Message:   err.Error(),
+		Retryable: true,
+	}
+
+	if target, ok := errors.AsType[*textproto.Error](err); ok {
+		sendErr.StatusCode = target.Code
+		sendErr.Retryable = target.Code < 500
+	}
+
+	return sendErr
+}
+
+func (s *smtpClient) auth() smtp.Auth {
+	if s.options.username == "" {
+		return nil
+	}
+
+	switch s.options.authMode {
+	case smtpAuthPlain:
+		return smtp.PlainAuth("", s.options.username, s.options.password, s.options.host)
+	case smtpAuthLogin:
+		return &loginAuth{
+			username: s.options.username,
+			password: s.options.password,
+			host:     s.options.host,
+		}
+	case smtpAuthCRAMMD5:
+		return smtp.CRAMMD5Auth(s.options.username, s.options.password)
+	default:
+		return nil
+	}
+}
+
+// loginAuth implements the non-standard but widely deployed AUTH LOGIN mechanism, which net/smtp does not ship.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same policy as smtp.PlainAuth: never send credentials in clear text unless talking to localhost.
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch prompt := strings.ToLower(strings.TrimSpace(string(fromServer))); {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,190 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestSMTPError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		retryable  bool
+	}{
+		{
+			name:      "connection error",
+			err:       fmt.Errorf("smtp: dial: %w", errors.New("connection refused")),
+			retryable: true,
+		},
+		{
+			name:       "mailbox unavailable",
+			err:        fmt.Errorf("smtp: rcpt to: %w", &textproto.Error{Code: 550, Msg: "mailbox unavailable"}),
+			statusCode: 550,
+		},
+		{
+			name:       "authentication failed",
+			err:        fmt.Errorf("smtp: auth: %w", &textproto.Error{Code: 535, Msg: "authentication failed"}),
+			statusCode: 535,
+		},
+		{
+			name:       "greylisted",
+			err:        fmt.Errorf("smtp: rcpt to: %w", &textproto.Error{Code: 451, Msg: "try again later"}),
+			statusCode: 451,
+			retryable:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := errors.AsType[*SendError](smtpError(tt.err))
+			if !ok {
+				t.Fatalf("expected *SendError, got %T", smtpError(tt.err))
+			}
+			if target.StatusCode != tt.statusCode {
+				t.Errorf("status code = %d, want %d", target.StatusCode, tt.statusCode)
+			}
+			if target.Retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", target.Retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestSMTPClientSendMail(t *testing.T) {
+	tests := []struct {
+		name      string
+		rcptReply string
+		wantErr   bool
+		retryable bool
+	}{
+		{
+			name:      "accepted",
+			rcptReply: "250 OK",
+		},
+		{
+			name:      "rejected",
+			rcptReply: "550 5.1.1 mailbox unavailable",
+			wantErr:   true,
+		},
+		{
+			name:      "deferred",
+			rcptReply: "451 4.7.1 try again later",
+			wantErr:   true,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serveSMTP(t, tt.rcptReply)
+
+			client, err := newSMTPClientWithOptions(&config.MailerConfig{
+				SenderEmail: "digest@example.com",
+				SenderName:  "Digest",
+			}, &smtpOptions{
+				host:     "127.0.0.1",
+				port:     addr.Port,
+				tlsMode:  smtpTLSNone,
+				authMode: smtpAuthNone,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := client.SendMail(context.Background(), &Message{
+				To:      []string{"someone@example.com"},
+				Subject: "Digest",
+				Text:    "Hello",
+			})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out.MessageID == "" {
+					t.Error("expected a message id")
+				}
+				return
+			}
+
+			target, ok := errors.AsType[*SendError](err)
+			if !ok {
+				t.Fatalf("expected *SendError, got %v", err)
+			}
+			if target.Retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", target.Retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+// serveSMTP runs a minimal SMTP server for a single connection, answering RCPT TO with rcptReply.
+func serveSMTP(t *testing.T, rcptReply string) *net.TCPAddr {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = fmt.Fprintf(conn, "%s\r\n", line)
+		}
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO"):
+				reply(rcptReply)
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					line, err = r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr)
+}
@@ -0,0 +1,261 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of a single Discord message, see https://discord.com/developers/docs/resources/message#embed-object-embed-limits.
+const (
+	discordMaxEmbeds         = 10
+	discordMaxEmbedChars     = 6000
+	discordMaxTitleChars     = 256
+	discordMaxRateLimitWait  = 5 * time.Second
+	discordMaxRateLimitTries = 3
+	// discordColor is the Reddit orange.
+	discordColor = 0xFF4500
+)
+
+type (
+	discordNotifier struct {
+		client *webhookClient
+	}
+
+	discordMessage struct {
+		Content         string                 `json:"content,omitempty"`
+		Username        string                 `json:"username,omitempty"`
+		Embeds          []*discordEmbed        `json:"embeds,omitempty"`
+		AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+	}
+
+	// discordAllowedMentions is always sent empty, so a post title can't ping @everyone.
+	discordAllowedMentions struct {
+		Parse []string `json:"parse"`
+	}
+
+	discordEmbed struct {
+		Title       string               `json:"title"`
+		URL         string               `json:"url,omitempty"`
+		Description string               `json:"description,omitempty"`
+		Color       int                  `json:"color"`
+		Timestamp   string               `json:"timestamp,omitempty"`
+		Thumbnail   *discordEmbedImage   `json:"thumbnail,omitempty"`
+		Footer      *discordEmbedFooter  `json:"footer,omitempty"`
+		Fields      []*discordEmbedField `json:"fields,omitempty"`
+	}
+
+	discordEmbedImage struct {
+		URL string `json:"url"`
+	}
+
+	discordEmbedFooter struct {
+		Text string `json:"text"`
+	}
+
+	discordEmbedField struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+
+	discordMessageResponse struct {
+		ID string `json:"id"`
+	}
+)
+
+func newDiscordNotifier() *discordNotifier {
+	return &discordNotifier{
+		client: newWebhookClient(ChannelDiscord),
+	}
+}
+
+func (d *discordNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	messages := discordMessages(digest)
+
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelDiscord,
+		}
+		result.MessageID, result.Err = d.send(ctx, target.Address, messages)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send posts all messages of a digest to one webhook and returns the ID of the first one. Discord announces its
+// remaining budget per webhook, so the next message waits for the bucket to reset instead of running into a 429. A wait
+// the activity has no time left for fails the delivery with a retryable error instead, unless part of the digest went
+// out already.
+func (d *discordNotifier) send(ctx context.Context, webhookURL string, messages []*discordMessage) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", &Error{Channel: ChannelDiscord, Message: "invalid webhook url"}
+	}
+	query := u.Query()
+	query.Set("wait", "true")
+	u.RawQuery = query.Encode()
+
+	var firstID string
+	for i, msg := range messages {
+		var (
+			res    discordMessageResponse
+			header http.Header
+		)
+
+		for attempt := 1; ; attempt++ {
+			header, err = d.client.post(ctx, u.String(), nil, msg, &res)
+			if err == nil {
+				break
+			}
+
+			// Short rate limits are waited out right here, so the remaining messages of the digest still go out in
+			// this attempt. Anything longer is left to the activity retry.
+			target, ok := errors.AsType[*Error](err)
+			if !ok || target.StatusCode != http.StatusTooManyRequests || attempt >= discordMaxRateLimitTries ||
+				target.RetryAfter > discordMaxRateLimitWait {
+				return firstID, d.client.partialSend(i, len(messages), err)
+			}
+			if err = d.client.wait(ctx, max(target.RetryAfter, time.Second)); err != nil {
+				return firstID, d.client.partialSend(i, len(messages), err)
+			}
+		}
+
+		if firstID == "" {
+			firstID = res.ID
+		}
+
+		if i < len(messages)-1 && header.Get("X-RateLimit-Remaining") == "0" {
+			if err = d.client.wait(ctx, parseRetryAfter(header.Get("X-RateLimit-Reset-After"))); err != nil {
+				return firstID, d.client.partialSend(i+1, len(messages), err)
+			}
+		}
+	}
+
+	return firstID, nil
+}
+
+// discordMessages renders the digest as messages with at most ten embeds each, one embed per post.
+func discordMessages(digest *Digest) []*discordMessage {
+	if len(digest.Posts) == 0 {
+		return []*discordMessage{{
+			Content: fmt.Sprintf("No new posts for **%s**.", escapeDiscord(digest.Keyword)),
+		}}
+	}
+
+	var (
+		messages []*discordMessage
+		current  *discordMessage
+		chars    int
+	)
+	for _, post := range digest.Posts {
+		embed := &discordEmbed{
+			Title:       truncate(post.Title, discordMaxTitleChars),
+			URL:         post.Permalink,
+			Description: fmt.Sprintf("r/%s • ⬆ %d • ⬇ %d", post.Subreddit, post.Ups, post.Downs),
+			Color:       discordColor,
+		}
+
+		if created, err := time.Parse(time.RFC822, post.Created); err == nil {
+			embed.Timestamp = created.UTC().Format(time.RFC3339)
+		}
+
+		var flags []string
+		if post.NSFW {
+			flags = append(flags, "NSFW")
+		}
+		if post.Spoiler {
+			flags = append(flags, "Spoiler")
+		}
+
+		// Discord doesn't blur embed thumbnails, so flagged posts go without one, like the blurred thumbnail in the
+		// email.
+		if len(flags) > 0 {
+			embed.Footer = &discordEmbedFooter{Text: strings.Join(flags, " • ")}
+		} else if strings.HasPrefix(post.Thumbnail, "https://") {
+			embed.Thumbnail = &discordEmbedImage{URL: post.Thumbnail}
+		}
+
+		size := embedChars(embed)
+		if current == nil || len(current.Embeds) == discordMaxEmbeds || chars+size > discordMaxEmbedChars {
+			current = &discordMessage{}
+			messages = append(messages, current)
+			chars = 0
+		}
+		current.Embeds = append(current.Embeds, embed)
+		chars += size
+	}
+
+	messages[0].Content = fmt.Sprintf("%d new posts for **%s**", len(digest.Posts), escapeDiscord(digest.Keyword))
+
+	return messages
+}
+
+// embedChars counts the characters Discord counts against the limit of a message.
+func embedChars(e *discordEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
+
+func escapeDiscord(s string) string {
+	return strings.NewReplacer("*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`).Replace(s)
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// validateDiscordWebhookURL accepts webhook URLs as copied from the channel settings, including the ptb and canary
+// hosts.
+func validateDiscordWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid discord webhook url: %w", err)
+	}
+
+	switch u.Host {
+	case "discord.com", "discordapp.com", "ptb.discord.com", "canary.discord.com":
+	default:
+		return fmt.Errorf("invalid discord webhook url: unexpected host %q", u.Host)
+	}
+
+	if u.Scheme != "https" || !strings.HasPrefix(u.Path, "/api/") || !strings.Contains(u.Path, "/webhooks/") {
+		return fmt.Errorf("invalid discord webhook url")
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiscordMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		posts     int
+		subreddit string
+		embeds    []int
+	}{
+		{name: "no posts", posts: 0, embeds: []int{0}},
+		{name: "single post", posts: 1, embeds: []int{1}},
+		{name: "full message", posts: 10, embeds: []int{10}},
+		{name: "split at embed limit", posts: 11, embeds: []int{10, 1}},
+		{name: "three messages", posts: 25, embeds: []int{10, 10, 5}},
+		// Every embed counts about 1000 characters, the sixth would exceed the limit of a message.
+		{name: "split at character limit", posts: 8, subreddit: strings.Repeat("a", 980), embeds: []int{5, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := testPosts(tt.posts)
+			for i := range posts {
+				if tt.subreddit != "" {
+					posts[i].Subreddit = tt.subreddit
+				}
+			}
+
+			messages := discordMessages(&Digest{Keyword: "example", Posts: posts})
+
+			var embeds []int
+			for _, msg := range messages {
+				chars := 0
+				for _, embed := range msg.Embeds {
+					chars += embedChars(embed)
+				}
+				if len(msg.Embeds) > discordMaxEmbeds || chars > discordMaxEmbedChars {
+					t.Errorf("message has %d embeds and %d characters", len(msg.Embeds), chars)
+				}
+				embeds = append(embeds, len(msg.Embeds))
+			}
+			if !slices.Equal(embeds, tt.embeds) {
+				t.Errorf("embeds per message = %v, want %v", embeds, tt.embeds)
+			}
+
+			if messages[0].Content == "" {
+				t.Error("first message has no content")
+			}
+			for _, msg := range messages[1:] {
+				if msg.Content != "" {
+					t.Errorf("continuation has content %q", msg.Content)
+				}
+			}
+		})
+	}
+}
+
+// discordResponse is the answer of the fake webhook to one request.
+type discordResponse struct {
+	status int
+	header map[string]string
+}
+
+// serveDiscordWebhook answers the requests in order with responses and the message ID of the request for every
+// success. It returns the server and the number of requests it received.
+func serveDiscordWebhook(t *testing.T, responses ...discordResponse) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n > len(responses) {
+			t.Errorf("unexpected request %d", n)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("wait") != "true" {
+			t.Errorf("request without wait=true: %s", r.URL)
+		}
+
+		res := responses[n-1]
+		for k, v := range res.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(res.status)
+		if res.status == http.StatusOK {
+			_, _ = fmt.Fprintf(w, `{"id":"message%d"}`, n)
+		} else {
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.1,"global":false}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestDiscordNotifierSend(t *testing.T) {
+	ok := discordResponse{status: http.StatusOK}
+	rateLimited := discordResponse{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"}}
+
+	tests := []struct {
+		name      string
+		messages  int
+		responses []discordResponse
+		messageID string
+		requests  int32
+		wantErr   bool
+	}{
+		{
+			name:      "single message",
+			messages:  1,
+			responses: []discordResponse{ok},
+			messageID: "message1",
+			requests:  1,
+		},
+		{
+			name:     "bucket exhausted",
+			messages: 2,
+			responses: []discordResponse{
+				{status: http.StatusOK, header: map[string]string{
+					"X-RateLimit-Remaining":   "0",
+					"X-RateLimit-Reset-After": "0",
+				}},
+				ok,
+			},
+			messageID: "message1",
+			requests:  2,
+		},
+		{
+			name:      "rate limit waited out",
+			messages:  1,
+			responses: []discordResponse{rateLimited, ok},
+			messageID: "message2",
+			requests:  2,
+		},
+		{
+			name:     "long rate limit",
+			messages: 1,
+			responses: []discordResponse{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "60"}},
+			},
+			requests: 1,
+			wantErr:  true,
+		},
+		{
+			name:      "rate limit tries exhausted",
+			messages:  1,
+			responses: []discordResponse{rateLimited, rateLimited, rateLimited},
+			requests:  discordMaxRateLimitTries,
+			wantErr:   true,
+		},
+		{
+			name:      "first message rejected",
+			messages:  2,
+			responses: []discordResponse{{status: http.StatusBadRequest}},
+			requests:  1,
+			wantErr:   true,
+		},
+		{
+			// A retry would post the first message again.
+			name:      "partially sent",
+			messages:  2,
+			responses: []discordResponse{ok, {status: http.StatusBadRequest}},
+			messageID: "message1",
+			requests:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := serveDiscordWebhook(t, tt.responses...)
+
+			messages := make([]*discordMessage, 0, tt.messages)
+			for i := range tt.messages {
+				messages = append(messages, &discordMessage{Content: fmt.Sprintf("message %d", i)})
+			}
+
+			id, err := newDiscordNotifier().send(context.Background(), srv.URL+"/api/webhooks/1/token", messages)
+			if tt.wantErr {
+				if _, ok := errors.AsType[*Error](err); !ok {
+					t.Errorf("expected *Error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if id != tt.messageID {
+				t.Errorf("message id = %q, want %q", id, tt.messageID)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("requests = %d, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestValidateDiscordWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://discord.com/api/webhooks/123/token"},
+		{url: "https://discordapp.com/api/webhooks/123/token"},
+		{url: "https://ptb.discord.com/api/webhooks/123/token"},
+		{url: "https://canary.discord.com/api/v10/webhooks/123/token"},
+		{url: "http://discord.com/api/webhooks/123/token", wantErr: true},
+		{url: "https://example.com/api/webhooks/123/token", wantErr: true},
+		{url: "https://discord.com.example.com/api/webhooks/123/token", wantErr: true},
+		{url: "https://discord.com/webhooks/123/token", wantErr: true},
+		{url: "https://discord.com/api/channels/123", wantErr: true},
+		{url: "discord.com/api/webhooks/123/token", wantErr: true},
+		{url: "://discord.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateDiscordWebhookURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDiscordWebhookURL() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is returned by channels that can tell a transient failure (rate limits, outages) apart from a permanent one
+// (deleted webhook, rejected payload). Callers decide on retries based on Retryable and RetryAfter.
+type Error struct {
+	Channel    string
+	StatusCode int
+	Message    string
+	Retryable  bool
+	RetryAfter time.Duration
+	// Gone is set if the target doesn't exist anymore, e.g. an expired push subscription. Gone errors are never
+	// retryable.
+	Gone bool
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %s", e.Channel, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Channel, e.Message)
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= http.StatusInternalServerError
+	}
+}
+
+// parseRetryAfter understands both forms of the Retry-After header, delay in seconds and HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type (
+	gotifyNotifier struct {
+		client *webhookClient
+	}
+
+	// gotifySettings are the settings of a Gotify recipient.
+	gotifySettings struct {
+		Mode string `json:"mode"`
+		// Token is the token of the application the digest is published as.
+		Token string `json:"token"`
+		// Priority is 0 to 10, the default priority of the application if unset.
+		Priority *int `json:"priority"`
+	}
+
+	// gotifyMessage is the message of https://gotify.net/api-docs#/message/createMessage. The extras make clients
+	// render the message as Markdown and open the post when tapped.
+	gotifyMessage struct {
+		Title    string         `json:"title"`
+		Message  string         `json:"message"`
+		Priority *int           `json:"priority,omitempty"`
+		Extras   map[string]any `json:"extras,omitempty"`
+	}
+
+	gotifyResponse struct {
+		ID int64 `json:"id"`
+	}
+)
+
+func newGotifyNotifier() *gotifyNotifier {
+	return &gotifyNotifier{
+		client: newPublicWebhookClient(ChannelGotify),
+	}
+}
+
+func (g *gotifyNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelGotify,
+		}
+		result.MessageID, result.Err = g.send(ctx, digest, target)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send creates a message per notification of the digest and returns the ID of the first one.
+func (g *gotifyNotifier) send(ctx context.Context, digest *Digest, target *Target) (string, error) {
+	settings, err := parseGotifySettings(target.Settings)
+	if err != nil {
+		return "", &Error{Channel: ChannelGotify, Message: err.Error()}
+	}
+
+	endpoint, err := gotifyMessageURL(target.Address)
+	if err != nil {
+		return "", &Error{Channel: ChannelGotify, Message: err.Error()}
+	}
+
+	// The token goes into a header, in the query it would end up in the logs of every proxy in between.
+	header := http.Header{}
+	header.Set("X-Gotify-Key", settings.Token)
+
+	var firstID string
+	for _, notification := range pushNotifications(digest, settings.Mode, true, pushMaxMessageBytes) {
+		extras := map[string]any{
+			"client::display": map[string]any{"contentType": "text/markdown"},
+		}
+
+		onNotification := make(map[string]any)
+		if notification.Click != "" {
+			onNotification["click"] = map[string]any{"url": notification.Click}
+		}
+		if notification.Image != "" {
+			onNotification["bigImageUrl"] = notification.Image
+		}
+		if len(onNotification) > 0 {
+			extras["client::notification"] = onNotification
+		}
+
+		var res gotifyResponse
+		if _, err = g.client.post(ctx, endpoint, header, &gotifyMessage{
+			Title:    notification.Title,
+			Message:  notification.Message,
+			Priority: settings.Priority,
+			Extras:   extras,
+		}, &res); err != nil {
+			return "", err
+		}
+
+		if firstID == "" {
+			firstID = strconv.FormatInt(res.ID, 10)
+		}
+	}
+
+	return firstID, nil
+}
+
+// gotifyMessageURL returns the message endpoint of the server at raw. Servers hosted under a path keep it.
+func gotifyMessageURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid gotify server url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", errors.New("invalid gotify server url: expected an http or https url")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/message"
+	u.RawQuery, u.Fragment = "", ""
+
+	return u.String(), nil
+}
+
+func parseGotifySettings(raw json.RawMessage) (*gotifySettings, error) {
+	var settings gotifySettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid gotify settings: %w", err)
+	}
+
+	if err := validatePushMode(settings.Mode); err != nil {
+		return nil, fmt.Errorf("invalid gotify settings: %w", err)
+	}
+	if settings.Token == "" {
+		return nil, errors.New("invalid gotify settings: token is required")
+	}
+	if settings.Priority != nil && (*settings.Priority < 0 || *settings.Priority > 10) {
+		return nil, errors.New("invalid gotify settings: priority must be between 0 and 10")
+	}
+
+	return &settings, nil
+}
+
+func validateGotifyTarget(target *Target) error {
+	if _, err := gotifyMessageURL(target.Address); err != nil {
+		return err
+	}
+
+	_, err := parseGotifySettings(target.Settings)
+	return err
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGotifyMessageURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		endpoint string
+		wantErr  bool
+	}{
+		{url: "https://gotify.example.com", endpoint: "https://gotify.example.com/message"},
+		{url: "https://gotify.example.com/", endpoint: "https://gotify.example.com/message"},
+		{url: "https://example.com/gotify/?token=x", endpoint: "https://example.com/gotify/message"},
+		{url: "gotify.example.com", wantErr: true},
+		{url: "ftp://gotify.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			endpoint, err := gotifyMessageURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("gotifyMessageURL() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if endpoint != tt.endpoint {
+				t.Errorf("gotifyMessageURL() = %q, want %q", endpoint, tt.endpoint)
+			}
+		})
+	}
+}
+
+func TestParseGotifySettings(t *testing.T) {
+	tests := []struct {
+		settings string
+		wantErr  bool
+	}{
+		{settings: `{"token":"AbCdEf"}`},
+		{settings: `{"token":"AbCdEf","mode":"post","priority":0}`},
+		{settings: `{"token":"AbCdEf","priority":10}`},
+		{settings: ``, wantErr: true},
+		{settings: `{"token":""}`, wantErr: true},
+		{settings: `{"token":"AbCdEf","priority":11}`, wantErr: true},
+		{settings: `{"token":"AbCdEf","mode":"digest"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.settings, func(t *testing.T) {
+			_, err := parseGotifySettings(json.RawMessage(tt.settings))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseGotifySettings() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGotifyNotifierSend(t *testing.T) {
+	var messages []gotifyMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gotify/message" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.URL.RawQuery != "" {
+			t.Errorf("token in query %q", r.URL.RawQuery)
+		}
+		if got := r.Header.Get("X-Gotify-Key"); got != "AbCdEf" {
+			t.Errorf("X-Gotify-Key = %q", got)
+		}
+
+		var msg gotifyMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, msg)
+
+		_, _ = w.Write([]byte(`{"id":25,"appid":5,"message":"…","title":"…","priority":4}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	notifier := newGotifyNotifier()
+	// The public client refuses the loopback address of the test server.
+	notifier.client = newWebhookClient(ChannelGotify)
+
+	posts := testPosts(2)
+	posts[0].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+
+	id, err := notifier.send(context.Background(), &Digest{Keyword: "example", Posts: posts}, &Target{
+		Channel:  ChannelGotify,
+		Address:  srv.URL + "/gotify",
+		Settings: json.RawMessage(`{"token":"AbCdEf","mode":"post","priority":4}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "25" {
+		t.Errorf("message id = %q, want 25", id)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Priority == nil || *msg.Priority != 4 {
+			t.Errorf("message %d has priority %v", i, msg.Priority)
+		}
+		notification, _ := msg.Extras["client::notification"].(map[string]any)
+		click, _ := notification["click"].(map[string]any)
+		if click["url"] != posts[i].Permalink {
+			t.Errorf("message %d opens %v, want the post", i, click["url"])
+		}
+		if _, ok := notification["bigImageUrl"]; ok != (i == 0) {
+			t.Errorf("message %d has image %t", i, ok)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxErrorBodySize = 4 << 10
+
+// errPrivateAddress is returned when a user supplied destination resolves to an address of the internal network.
+var errPrivateAddress = errors.New("destination is not a public address")
+
+// webhookClient is the shared transport of the HTTP based channels. It takes care of JSON encoding, status code
+// classification and turning non-2xx responses into an *Error.
+type webhookClient struct {
+	channel    string
+	httpClient *http.Client
+}
+
+func newWebhookClient(channel string) *webhookClient {
+	return &webhookClient{
+		channel: channel,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// newPublicWebhookClient is a webhookClient for destinations chosen by the user, like self-hosted servers. It refuses
+// to connect to private, loopback and link-local addresses. The check runs on the resolved address at dial time, so
+// neither DNS nor a redirect can point the request into the internal network. Proxies from the environment are
+// ignored, they would be the only address checked.
+func newPublicWebhookClient(channel string) *webhookClient {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicAddressOnly,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	c := newWebhookClient(channel)
+	c.httpClient.Transport = transport
+	return c
+}
+
+// publicAddressOnly is the dialer control of newPublicWebhookClient.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("%w: %s", errPrivateAddress, ip)
+	}
+
+	return nil
+}
+
+// post sends in as JSON to url and decodes the response into out, if given. The response header is returned for
+// channels that report rate limits in headers.
+func (c *webhookClient) post(ctx context.Context, url string, header http.Header, in any, out any) (http.Header, error) {
+	return c.do(ctx, http.MethodPost, url, header, in, out)
+}
+
+// do is post with a different method, for APIs that create resources with PUT.
+func (c *webhookClient) do(ctx context.Context, method, url string, header http.Header, in any, out any) (http.Header, error) {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshal request: %w", c.channel, err)
+	}
+
+	return c.send(ctx, method, url, header, "application/json", payload, out)
+}
+
+// send sends a body that is already encoded, e.g. the encrypted payload of a push message.
+func (c *webhookClient) send(ctx context.Context, method, url string, header http.Header, contentType string, body []byte, out any) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, &Error{Channel: c.channel, Message: err.Error()}
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Network level failures are always worth another attempt, unless the destination isn't allowed at all.
+		return nil, &Error{
+			Channel:   c.channel,
+			Message:   redact(err.Error(), url),
+			Retryable: !errors.Is(err, errPrivateAddress),
+		}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.Header, &Error{
+			Channel:    c.channel,
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(body)),
+			Retryable:  isRetryableStatus(resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if out != nil {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			// The notification went out, a response we can't read shouldn't cause a duplicate.
+			slog.Warn("decode response", slog.String("channel", c.channel), slog.Any("error", err))
+		}
+	}
+
+	return resp.Header, nil
+}
+
+// wait sleeps for d between two requests of a digest. If the activity wouldn't have the time for another request
+// afterwards, it returns a retryable *Error carrying d instead, so the activity retry does the waiting.
+func (c *webhookClient) wait(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d+c.httpClient.Timeout {
+		return &Error{
+			Channel:    c.channel,
+			Message:    fmt.Sprintf("rate limited, not enough time left to wait %s", d),
+			Retryable:  true,
+			RetryAfter: d,
+		}
+	}
+
+	return sleep(ctx, d)
+}
+
+// partialSend decides on the error of a digest that failed after sent of its total messages went out. A retry would post
+// the sent messages again, so once any message is out the digest counts as delivered and the rest is only logged.
+func (c *webhookClient) partialSend(sent, total int, err error) error {
+	if err == nil || sent == 0 {
+		return err
+	}
+
+	slog.Warn("digest partially sent", slog.String("channel", c.channel), slog.Int("sent", sent),
+		slog.Int("total", total), slog.Any("error", err))
+	return nil
+}
+
+// redact removes url from s. Webhook URLs are credentials, url.Error embeds them in its message.
+func redact(s, url string) string {
+	return strings.ReplaceAll(s, url, "[redacted]")
+}
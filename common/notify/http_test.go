@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicAddressOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "93.184.215.14:443"},
+		{address: "[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443"},
+		{address: "127.0.0.1:80", wantErr: true},
+		{address: "[::1]:80", wantErr: true},
+		{address: "10.0.0.1:80", wantErr: true},
+		{address: "172.16.5.4:80", wantErr: true},
+		{address: "192.168.1.1:80", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[fe80::1]:80", wantErr: true},
+		{address: "[fd00::1]:80", wantErr: true},
+		{address: "[::ffff:127.0.0.1]:80", wantErr: true},
+		{address: "0.0.0.0:80", wantErr: true},
+		{address: "224.0.0.1:80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := publicAddressOnly("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("publicAddressOnly() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicWebhookClientRefusesLoopback(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := newPublicWebhookClient(ChannelWebhook).post(context.Background(), srv.URL, nil, struct{}{}, nil)
+
+	target, ok := errors.AsType[*Error](err)
+	if !ok {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if target.Retryable {
+		t.Error("refused destination is retryable")
+	}
+	if called {
+		t.Error("request reached the server")
+	}
+}
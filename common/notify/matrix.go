@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	matrixMaxPosts = 25
+	// matrixMaxBodyBytes keeps body and formatted body together well below the 65536 bytes limit of an event.
+	matrixMaxBodyBytes = 24 << 10
+)
+
+// matrixPostTemplate renders a post like the email does, in the HTML subset Matrix clients display. Images would need
+// to be uploaded to the homeserver first, so there are no thumbnails. NSFW and spoiler titles are hidden behind a
+// spoiler instead.
+var matrixPostTemplate = template.Must(template.New("post").Parse(
+	`<li><a href="{{.Permalink}}">` +
+		`{{if or .NSFW .Spoiler}}<span data-mx-spoiler="{{if .NSFW}}NSFW{{else}}Spoiler{{end}}">{{.Title}}</span>` +
+		`{{else}}{{.Title}}{{end}}</a><br>` +
+		`r/{{.Subreddit}} • ⬆ {{.Ups}} • ⬇ {{.Downs}}{{if .Created}} • {{.Created}}{{end}}</li>`,
+))
+
+type (
+	matrixNotifier struct {
+		client *webhookClient
+	}
+
+	// matrixSettings are the settings of a Matrix recipient, the address is the homeserver URL.
+	matrixSettings struct {
+		AccessToken string `json:"accessToken"`
+		// RoomID is the internal ID of the room, e.g. !abcdefg:matrix.org, not an alias. The user of the access token
+		// has to be a member of the room.
+		RoomID string `json:"roomID"`
+	}
+
+	matrixMessage struct {
+		MsgType       string         `json:"msgtype"`
+		Body          string         `json:"body"`
+		Format        string         `json:"format"`
+		FormattedBody string         `json:"formatted_body"`
+		Mentions      map[string]any `json:"m.mentions"`
+	}
+
+	matrixResponse struct {
+		EventID string `json:"event_id"`
+	}
+
+	matrixError struct {
+		ErrCode      string `json:"errcode"`
+		Error        string `json:"error"`
+		RetryAfterMS int64  `json:"retry_after_ms"`
+	}
+)
+
+func newMatrixNotifier() *matrixNotifier {
+	return &matrixNotifier{
+		client: newPublicWebhookClient(ChannelMatrix),
+	}
+}
+
+func (m *matrixNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	messages, err := matrixMessages(digest)
+
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelMatrix,
+			Err:      err,
+		}
+		if err == nil {
+			result.MessageID, result.Err = m.send(ctx, digest.idempotencyKey(target), target, messages)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send sends the messages of a digest into the room and returns the event ID of the first one. The transaction ID of
+// every message is derived from the idempotency key, the homeserver answers a retried message with the event it
+// already created instead of sending it again.
+func (m *matrixNotifier) send(ctx context.Context, key string, target *Target, messages []*matrixMessage) (string, error) {
+	settings, err := parseMatrixSettings(target.Settings)
+	if err != nil {
+		return "", &Error{Channel: ChannelMatrix, Message: err.Error()}
+	}
+
+	base, err := matrixHomeserverURL(target.Address)
+	if err != nil {
+		return "", &Error{Channel: ChannelMatrix, Message: err.Error()}
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+settings.AccessToken)
+
+	var firstID string
+	for i, msg := range messages {
+		endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+			base,
+			url.PathEscape(settings.RoomID),
+			matrixTxnID(key, i),
+		)
+
+		var res matrixResponse
+		if _, err = m.client.do(ctx, http.MethodPut, endpoint, header, msg, &res); err != nil {
+			return "", matrixErrorFrom(err)
+		}
+
+		if firstID == "" {
+			firstID = res.EventID
+		}
+	}
+
+	return firstID, nil
+}
+
+// matrixTxnID is stable across attempts of the same digest and unique per message. The key contains slashes, the
+// hash makes it safe for the path.
+func matrixTxnID(key string, index int) string {
+	sum := sha256.Sum256([]byte(key + "/" + strconv.Itoa(index)))
+	return "rpn-" + hex.EncodeToString(sum[:16])
+}
+
+// matrixErrorFrom replaces the raw response in err with the Matrix error and takes the delay from retry_after_ms, not
+// every homeserver sends a Retry-After header.
+func matrixErrorFrom(err error) error {
+	target, ok := errors.AsType[*Error](err)
+	if !ok {
+		return err
+	}
+
+	var res matrixError
+	if json.Unmarshal([]byte(target.Message), &res) == nil && res.ErrCode != "" {
+		target.Message = res.ErrCode + ": " + res.Error
+		if res.RetryAfterMS > 0 {
+			target.RetryAfter = time.Duration(res.RetryAfterMS) * time.Millisecond
+		}
+	}
+
+	return target
+}
+
+// matrixMessages renders the digest as messages of at most 25 posts each, the first one starting with the summary.
+func matrixMessages(digest *Digest) ([]*matrixMessage, error) {
+	heading := fmt.Sprintf("%d new posts for %s", len(digest.Posts), digest.Keyword)
+	if len(digest.Posts) == 0 {
+		heading = fmt.Sprintf("No new posts for %s", digest.Keyword)
+	}
+
+	var (
+		messages []*matrixMessage
+		body     strings.Builder
+		html     strings.Builder
+		posts    int
+	)
+
+	body.WriteString(heading)
+	html.WriteString("<h4>" + template.HTMLEscapeString(heading) + "</h4>")
+
+	flush := func() {
+		if posts > 0 {
+			html.WriteString("</ul>")
+		}
+		messages = append(messages, &matrixMessage{
+			MsgType:       "m.text",
+			Body:          body.String(),
+			Format:        "org.matrix.custom.html",
+			FormattedBody: html.String(),
+			Mentions:      map[string]any{},
+		})
+		body.Reset()
+		html.Reset()
+		posts = 0
+	}
+
+	for _, post := range digest.Posts {
+		entry, err := matrixPost(&post)
+		if err != nil {
+			return nil, &Error{Channel: ChannelMatrix, Message: err.Error()}
+		}
+		text := fmt.Sprintf("• %s\n  %s\n  %s", post.Title, pushPostLine(&post), post.Permalink)
+
+		if posts == matrixMaxPosts || body.Len()+html.Len()+len(text)+len(entry) > matrixMaxBodyBytes {
+			flush()
+		}
+
+		if posts == 0 {
+			html.WriteString("<ul>")
+		}
+		if body.Len() > 0 {
+			body.WriteString("\n\n")
+		}
+		body.WriteString(text)
+		html.WriteString(entry)
+		posts++
+	}
+	flush()
+
+	return messages, nil
+}
+
+func matrixPost(post *persistence.Post) (string, error) {
+	var b strings.Builder
+	if err := matrixPostTemplate.Execute(&b, post); err != nil {
+		return "", fmt.Errorf("render post: %w", err)
+	}
+	return b.String(), nil
+}
+
+func matrixHomeserverURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid matrix homeserver url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", errors.New("invalid matrix homeserver url: expected an http or https url")
+	}
+
+	u.RawQuery, u.Fragment = "", ""
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+func parseMatrixSettings(raw json.RawMessage) (*matrixSettings, error) {
+	var settings matrixSettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid matrix settings: %w", err)
+	}
+
+	if settings.AccessToken == "" {
+		return nil, errors.New("invalid matrix settings: accessToken is required")
+	}
+	if !strings.HasPrefix(settings.RoomID, "!") {
+		return nil, errors.New("invalid matrix settings: roomID must be a room ID like !abcdefg:matrix.org")
+	}
+
+	return &settings, nil
+}
+
+func validateMatrixTarget(target *Target) error {
+	if _, err := matrixHomeserverURL(target.Address); err != nil {
+		return err
+	}
+
+	_, err := parseMatrixSettings(target.Settings)
+	return err
+}
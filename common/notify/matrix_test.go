@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatrixMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		posts int
+		items []int
+	}{
+		{name: "no posts", posts: 0, items: []int{0}},
+		{name: "single post", posts: 1, items: []int{1}},
+		{name: "full message", posts: matrixMaxPosts, items: []int{25}},
+		{name: "split", posts: matrixMaxPosts + 1, items: []int{25, 1}},
+		{name: "three messages", posts: 60, items: []int{25, 25, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := matrixMessages(&Digest{Keyword: "example", Posts: testPosts(tt.posts)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var items []int
+			for _, msg := range messages {
+				if len(msg.Body)+len(msg.FormattedBody) > matrixMaxBodyBytes {
+					t.Errorf("message has %d bytes", len(msg.Body)+len(msg.FormattedBody))
+				}
+				if msg.Mentions == nil {
+					t.Error("message without empty m.mentions could ping the room")
+				}
+				items = append(items, strings.Count(msg.FormattedBody, "<li>"))
+			}
+			if !slices.Equal(items, tt.items) {
+				t.Errorf("posts per message = %v, want %v", items, tt.items)
+			}
+			if !strings.HasPrefix(messages[0].FormattedBody, "<h4>") {
+				t.Error("first message doesn't start with the heading")
+			}
+		})
+	}
+}
+
+func TestMatrixPost(t *testing.T) {
+	posts := testPosts(3)
+	posts[0].Title = `<script>alert("x")</script>`
+	posts[1].NSFW = true
+	posts[2].Spoiler = true
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "escaped", want: `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`},
+		{name: "nsfw", want: `<span data-mx-spoiler="NSFW">Example post 1</span>`},
+		{name: "spoiler", want: `<span data-mx-spoiler="Spoiler">Example post 2</span>`},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := matrixPost(&posts[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(entry, tt.want) {
+				t.Errorf("entry %q doesn't contain %q", entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMatrixSettings(t *testing.T) {
+	tests := []struct {
+		settings string
+		wantErr  bool
+	}{
+		{settings: `{"accessToken":"syt_abc","roomID":"!abcdefg:matrix.org"}`},
+		{settings: `{"roomID":"!abcdefg:matrix.org"}`, wantErr: true},
+		{settings: `{"accessToken":"syt_abc","roomID":"#reddit:matrix.org"}`, wantErr: true},
+		{settings: `{"accessToken":"syt_abc"}`, wantErr: true},
+		{settings: ``, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.settings, func(t *testing.T) {
+			_, err := parseMatrixSettings(json.RawMessage(tt.settings))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMatrixSettings() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatrixHomeserverURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		base    string
+		wantErr bool
+	}{
+		{url: "https://matrix.example.org", base: "https://matrix.example.org"},
+		{url: "https://matrix.example.org/", base: "https://matrix.example.org"},
+		{url: "https://example.org/matrix/?x=1", base: "https://example.org/matrix"},
+		{url: "matrix.example.org", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			base, err := matrixHomeserverURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matrixHomeserverURL() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if base != tt.base {
+				t.Errorf("matrixHomeserverURL() = %q, want %q", base, tt.base)
+			}
+		})
+	}
+}
+
+func TestMatrixNotifierSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    bool
+		retryable  bool
+		retryAfter time.Duration
+		message    string
+	}{
+		{name: "sent", status: http.StatusOK, body: `{"event_id":"$YUwRidLecu:example.com"}`},
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			body:       `{"errcode":"M_LIMIT_EXCEEDED","error":"Too many requests","retry_after_ms":2000}`,
+			wantErr:    true,
+			retryable:  true,
+			retryAfter: 2 * time.Second,
+			message:    "M_LIMIT_EXCEEDED: Too many requests",
+		},
+		{
+			name:    "not in room",
+			status:  http.StatusForbidden,
+			body:    `{"errcode":"M_FORBIDDEN","error":"User @bot:example.com not in room"}`,
+			wantErr: true,
+			message: "M_FORBIDDEN: User @bot:example.com not in room",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Errorf("method = %s, want PUT", r.Method)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer syt_abc" {
+					t.Errorf("authorization = %q", got)
+				}
+				paths = append(paths, r.URL.EscapedPath())
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+
+			notifier := newMatrixNotifier()
+			// The public client refuses the loopback address of the test server.
+			notifier.client = newWebhookClient(ChannelMatrix)
+
+			target := &Target{
+				Channel:  ChannelMatrix,
+				Address:  srv.URL,
+				Settings: json.RawMessage(`{"accessToken":"syt_abc","roomID":"!abcdefg:example.com"}`),
+			}
+			messages, err := matrixMessages(&Digest{Keyword: "example", Posts: testPosts(1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id, err := notifier.send(context.Background(), "workflow/run/recipient", target, messages)
+			if tt.wantErr {
+				target, ok := errors.AsType[*Error](err)
+				if !ok {
+					t.Fatalf("expected *Error, got %v", err)
+				}
+				if target.Retryable != tt.retryable || target.RetryAfter != tt.retryAfter {
+					t.Errorf("retryable = %t after %s, want %t after %s", target.Retryable, target.RetryAfter,
+						tt.retryable, tt.retryAfter)
+				}
+				if target.Message != tt.message {
+					t.Errorf("message = %q, want %q", target.Message, tt.message)
+				}
+			} else if err != nil || id != "$YUwRidLecu:example.com" {
+				t.Fatalf("send() = %q, %v", id, err)
+			}
+
+			want := "/_matrix/client/v3/rooms/%21abcdefg:example.com/send/m.room.message/" +
+				matrixTxnID("workflow/run/recipient", 0)
+			if len(paths) != 1 || paths[0] != want {
+				t.Errorf("paths = %v, want %s", paths, want)
+			}
+		})
+	}
+}
+
+func TestMatrixTxnID(t *testing.T) {
+	if matrixTxnID("key", 0) != matrixTxnID("key", 0) {
+		t.Error("transaction ID isn't stable")
+	}
+	if matrixTxnID("key", 0) == matrixTxnID("key", 1) || matrixTxnID("key", 0) == matrixTxnID("other", 0) {
+		t.Error("transaction IDs collide")
+	}
+	if id := matrixTxnID("a/b/c", 0); strings.ContainsAny(id, "/?#") {
+		t.Errorf("transaction ID %q isn't safe for the path", id)
+	}
+}
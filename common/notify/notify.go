@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/google/uuid"
+	"net/mail"
+)
+
+// Channels a recipient can be notified on. Email is implemented by the digester on top of mail.Mailer, every other
+// channel lives in this package.
+const (
+	ChannelEmail    = "email"
+	ChannelDiscord  = "discord"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+	ChannelWebhook  = "webhook"
+	ChannelNtfy     = "ntfy"
+	ChannelGotify   = "gotify"
+	ChannelMatrix   = "matrix"
+	ChannelTeams    = "teams"
+	ChannelPushover = "pushover"
+	ChannelWebPush  = "webpush"
+)
+
+var ErrUnsupportedChannel = errors.New("unsupported channel")
+
+type (
+	// Notifier delivers a digest on one channel.
+	Notifier interface {
+		// Notify sends digest to every target. There's exactly one result per target, in the order of targets.
+		Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result
+	}
+
+	// Digest is the channel independent content of a notification.
+	Digest struct {
+		ScheduleID uuid.UUID
+		Keyword    string
+		Posts      []persistence.Post
+		// IdempotencyKey is the same for every attempt to send this digest. Channels that deduplicate requests derive
+		// their keys from it.
+		IdempotencyKey string
+	}
+
+	Target struct {
+		RecipientID uuid.UUID
+		Channel     string
+		// Address is the email address for email, the webhook URL for Discord, Slack and Teams, <bot token>/<chat id>
+		// for Telegram, the endpoint URL for webhooks, the topic URL for ntfy, the server URL for Gotify, the
+		// homeserver URL for Matrix, the user or group key for Pushover and the subscription endpoint for Web Push.
+		Address string
+		// Settings are the channel specific options of the target as JSON object, empty for most channels.
+		Settings json.RawMessage
+	}
+
+	Result struct {
+		Target    *Target
+		MessageID string
+		// Provider is the service that accepted the notification, e.g. the mail provider for email.
+		Provider string
+		Err      error
+	}
+)
+
+// New creates the notifiers of every channel except email. Web Push is only usable if its VAPID keys are configured.
+func New(conf *config.Notify) (map[string]Notifier, error) {
+	var vapid *VAPID
+	if conf.WebPushPrivateKey != "" {
+		var err error
+		if vapid, err = NewVAPID(conf.WebPushPublicKey, conf.WebPushPrivateKey, conf.WebPushSubject); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]Notifier{
+		ChannelDiscord:  newDiscordNotifier(),
+		ChannelSlack:    newSlackNotifier(),
+		ChannelTelegram: newTelegramNotifier(),
+		ChannelWebhook:  newWebhookNotifier(),
+		ChannelNtfy:     newNtfyNotifier(),
+		ChannelGotify:   newGotifyNotifier(),
+		ChannelMatrix:   newMatrixNotifier(),
+		ChannelTeams:    newTeamsNotifier(),
+		ChannelPushover: newPushoverNotifier(),
+		ChannelWebPush:  newWebPushNotifier(vapid),
+	}, nil
+}
+
+// ValidateTarget checks that target can be notified on its channel. An empty channel is email.
+func ValidateTarget(target *Target) error {
+	switch target.Channel {
+	case "", ChannelEmail:
+		if _, err := mail.ParseAddress(target.Address); err != nil {
+			return fmt.Errorf("invalid email address %q: %w", target.Address, err)
+		}
+		return nil
+	case ChannelDiscord:
+		return validateDiscordWebhookURL(target.Address)
+	case ChannelSlack:
+		return validateSlackWebhookURL(target.Address)
+	case ChannelTelegram:
+		return validateTelegramAddress(target.Address)
+	case ChannelWebhook:
+		return validateWebhookTarget(target)
+	case ChannelNtfy:
+		return validateNtfyTarget(target)
+	case ChannelGotify:
+		return validateGotifyTarget(target)
+	case ChannelMatrix:
+		return validateMatrixTarget(target)
+	case ChannelTeams:
+		return validateTeamsWebhookURL(target.Address)
+	case ChannelPushover:
+		return validatePushoverTarget(target)
+	case ChannelWebPush:
+		return validateWebPushTarget(target)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedChannel, target.Channel)
+	}
+}
+
+// idempotencyKey derives the key of a single target from the key of the digest.
+func (d *Digest) idempotencyKey(target *Target) string {
+	return d.IdempotencyKey + "/" + target.RecipientID.String()
+}
+
+// decodeSettings decodes the settings of a target into v. Unknown options are rejected, so a misspelled one doesn't go
+// unnoticed.
+func decodeSettings(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
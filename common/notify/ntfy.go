@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	ntfyNotifier struct {
+		client *webhookClient
+	}
+
+	// ntfySettings are the settings of a ntfy recipient. Token and username/password are mutually exclusive, topics
+	// that don't require authentication take neither.
+	ntfySettings struct {
+		Mode string `json:"mode"`
+		// Priority is 1 (min) to 5 (max), the server default of 3 if unset.
+		Priority int      `json:"priority"`
+		Tags     []string `json:"tags"`
+		Token    string   `json:"token"`
+		Username string   `json:"username"`
+		Password string   `json:"password"`
+	}
+
+	// ntfyMessage is published as JSON to the root URL of the server, which unlike the header based API takes
+	// non-ASCII titles, see https://docs.ntfy.sh/publish/#publish-as-json.
+	ntfyMessage struct {
+		Topic    string   `json:"topic"`
+		Title    string   `json:"title,omitempty"`
+		Message  string   `json:"message"`
+		Priority int      `json:"priority,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+		Click    string   `json:"click,omitempty"`
+		Attach   string   `json:"attach,omitempty"`
+	}
+
+	ntfyResponse struct {
+		ID string `json:"id"`
+	}
+)
+
+func newNtfyNotifier() *ntfyNotifier {
+	return &ntfyNotifier{
+		client: newPublicWebhookClient(ChannelNtfy),
+	}
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelNtfy,
+		}
+		result.MessageID, result.Err = n.send(ctx, digest, target)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send publishes the digest and returns the ID of the first message.
+func (n *ntfyNotifier) send(ctx context.Context, digest *Digest, target *Target) (string, error) {
+	settings, err := parseNtfySettings(target.Settings)
+	if err != nil {
+		return "", &Error{Channel: ChannelNtfy, Message: err.Error()}
+	}
+
+	server, topic, err := splitNtfyTopicURL(target.Address)
+	if err != nil {
+		return "", &Error{Channel: ChannelNtfy, Message: err.Error()}
+	}
+
+	header := http.Header{}
+	switch {
+	case settings.Token != "":
+		header.Set("Authorization", "Bearer "+settings.Token)
+	case settings.Username != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(settings.Username + ":" + settings.Password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+
+	var firstID string
+	for _, notification := range pushNotifications(digest, settings.Mode, false, pushMaxMessageBytes) {
+		var res ntfyResponse
+		if _, err = n.client.post(ctx, server, header, &ntfyMessage{
+			Topic:    topic,
+			Title:    notification.Title,
+			Message:  notification.Message,
+			Priority: settings.Priority,
+			Tags:     settings.Tags,
+			Click:    notification.Click,
+			Attach:   notification.Image,
+		}, &res); err != nil {
+			return "", err
+		}
+
+		if firstID == "" {
+			firstID = res.ID
+		}
+	}
+
+	return firstID, nil
+}
+
+// splitNtfyTopicURL splits a topic URL like https://ntfy.sh/reddit into the server URL and the topic. Servers hosted
+// under a path keep it.
+func splitNtfyTopicURL(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid ntfy topic url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", "", errors.New("invalid ntfy topic url: expected an http or https url")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 || path[i+1:] == "" {
+		return "", "", errors.New("invalid ntfy topic url: missing topic")
+	}
+
+	topic := path[i+1:]
+	u.Path = path[:i+1]
+	u.RawQuery, u.Fragment = "", ""
+
+	return u.String(), topic, nil
+}
+
+func parseNtfySettings(raw json.RawMessage) (*ntfySettings, error) {
+	var settings ntfySettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid ntfy settings: %w", err)
+	}
+
+	if err := validatePushMode(settings.Mode); err != nil {
+		return nil, fmt.Errorf("invalid ntfy settings: %w", err)
+	}
+	if settings.Priority < 0 || settings.Priority > 5 {
+		return nil, errors.New("invalid ntfy settings: priority must be between 1 and 5, or 0 for the default")
+	}
+	for _, tag := range settings.Tags {
+		if tag == "" || strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("invalid ntfy settings: invalid tag %q", tag)
+		}
+	}
+	if settings.Token != "" && settings.Username != "" {
+		return nil, errors.New("invalid ntfy settings: token and username are mutually exclusive")
+	}
+	if settings.Password != "" && settings.Username == "" {
+		return nil, errors.New("invalid ntfy settings: password requires a username")
+	}
+
+	return &settings, nil
+}
+
+func validateNtfyTarget(target *Target) error {
+	if _, _, err := splitNtfyTopicURL(target.Address); err != nil {
+		return err
+	}
+
+	_, err := parseNtfySettings(target.Settings)
+	return err
+}
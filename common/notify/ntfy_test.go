@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSplitNtfyTopicURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		server  string
+		topic   string
+		wantErr bool
+	}{
+		{url: "https://ntfy.sh/reddit", server: "https://ntfy.sh/", topic: "reddit"},
+		{url: "https://ntfy.sh/reddit/", server: "https://ntfy.sh/", topic: "reddit"},
+		{url: "https://example.com/ntfy/reddit?x=1#y", server: "https://example.com/ntfy/", topic: "reddit"},
+		{url: "http://localhost:8080/reddit", server: "http://localhost:8080/", topic: "reddit"},
+		{url: "https://ntfy.sh", wantErr: true},
+		{url: "https://ntfy.sh/", wantErr: true},
+		{url: "ftp://ntfy.sh/reddit", wantErr: true},
+		{url: "ntfy.sh/reddit", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			server, topic, err := splitNtfyTopicURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitNtfyTopicURL() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if server != tt.server || topic != tt.topic {
+				t.Errorf("splitNtfyTopicURL() = %q, %q, want %q, %q", server, topic, tt.server, tt.topic)
+			}
+		})
+	}
+}
+
+func TestParseNtfySettings(t *testing.T) {
+	tests := []struct {
+		settings string
+		wantErr  bool
+	}{
+		{settings: ``},
+		{settings: `{"mode":"post","priority":5,"tags":["reddit","rotating_light"],"token":"tk_abc"}`},
+		{settings: `{"username":"alice","password":"secret"}`},
+		{settings: `{"mode":"digest"}`, wantErr: true},
+		{settings: `{"priority":6}`, wantErr: true},
+		{settings: `{"priority":-1}`, wantErr: true},
+		{settings: `{"tags":["a,b"]}`, wantErr: true},
+		{settings: `{"tags":[""]}`, wantErr: true},
+		{settings: `{"token":"tk_abc","username":"alice"}`, wantErr: true},
+		{settings: `{"password":"secret"}`, wantErr: true},
+		{settings: `{"unknown":true}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.settings, func(t *testing.T) {
+			_, err := parseNtfySettings(json.RawMessage(tt.settings))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseNtfySettings() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNtfyNotifierSend(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      string
+		authorization string
+		messages      int
+	}{
+		{name: "anonymous", messages: 1},
+		{name: "token", settings: `{"token":"tk_abc"}`, authorization: "Bearer tk_abc", messages: 1},
+		{
+			name:          "basic auth",
+			settings:      `{"username":"alice","password":"secret"}`,
+			authorization: "Basic YWxpY2U6c2VjcmV0",
+			messages:      1,
+		},
+		{name: "post mode", settings: `{"mode":"post","priority":4}`, messages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []ntfyMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != tt.authorization {
+					t.Errorf("authorization = %q, want %q", got, tt.authorization)
+				}
+
+				var msg ntfyMessage
+				if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+					t.Error(err)
+				}
+				messages = append(messages, msg)
+
+				_, _ = w.Write([]byte(`{"id":"sPs71M8A2T","time":1760692800,"event":"message","topic":"reddit"}`))
+			}))
+			t.Cleanup(srv.Close)
+
+			notifier := newNtfyNotifier()
+			// The public client refuses the loopback address of the test server.
+			notifier.client = newWebhookClient(ChannelNtfy)
+
+			target := &Target{Channel: ChannelNtfy, Address: srv.URL + "/reddit"}
+			if tt.settings != "" {
+				target.Settings = json.RawMessage(tt.settings)
+			}
+
+			id, err := notifier.send(context.Background(), &Digest{Keyword: "example", Posts: testPosts(2)}, target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id != "sPs71M8A2T" {
+				t.Errorf("message id = %q", id)
+			}
+			if len(messages) != tt.messages {
+				t.Fatalf("messages = %d, want %d", len(messages), tt.messages)
+			}
+			for _, msg := range messages {
+				if msg.Topic != "reddit" || msg.Title == "" || msg.Message == "" {
+					t.Errorf("incomplete message %+v", msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"strings"
+)
+
+// Modes of the push channels, ntfy, Gotify, Pushover and Web Push. A summary is a single notification listing every
+// post of the digest, post mode sends one notification per post that opens the post when tapped.
+const (
+	pushModeSummary = "summary"
+	pushModePost    = "post"
+	// pushMaxPosts is the largest digest that is sent in post mode. Nobody wants their phone to buzz fifty times,
+	// larger digests are summarized.
+	pushMaxPosts = 10
+	// pushMaxMessageBytes keeps a summary below the 4096 bytes ntfy accepts as message before turning it into an
+	// attachment.
+	pushMaxMessageBytes = 4000
+)
+
+type pushNotification struct {
+	Title   string
+	Message string
+	// Click is the URL opened when the notification is tapped, only set in post mode.
+	Click string
+	// Image is the thumbnail of the post, only set in post mode and never for NSFW or spoiler posts.
+	Image string
+}
+
+func validatePushMode(mode string) error {
+	switch mode {
+	case "", pushModeSummary, pushModePost:
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q: expected %q or %q", mode, pushModeSummary, pushModePost)
+	}
+}
+
+// pushNotifications renders the digest in the given mode. markdown is set by channels whose clients render Markdown in
+// the message, a summary is cut off at maxBytes.
+func pushNotifications(digest *Digest, mode string, markdown bool, maxBytes int) []*pushNotification {
+	if mode == pushModePost && len(digest.Posts) > 0 && len(digest.Posts) <= pushMaxPosts {
+		notifications := make([]*pushNotification, 0, len(digest.Posts))
+		for _, post := range digest.Posts {
+			n := &pushNotification{
+				Title:   post.Title,
+				Message: pushPostLine(&post),
+				Click:   post.Permalink,
+			}
+			if !post.NSFW && !post.Spoiler && strings.HasPrefix(post.Thumbnail, "https://") {
+				n.Image = post.Thumbnail
+			}
+			notifications = append(notifications, n)
+		}
+		return notifications
+	}
+
+	if len(digest.Posts) == 0 {
+		return []*pushNotification{{
+			Title:   fmt.Sprintf("No new posts for %s", digest.Keyword),
+			Message: "Nothing new since the last digest.",
+		}}
+	}
+
+	var message strings.Builder
+	for i, post := range digest.Posts {
+		var entry string
+		if markdown {
+			entry = fmt.Sprintf("- [%s](%s)  \n  %s", escapeMarkdown(post.Title), post.Permalink, pushPostLine(&post))
+		} else {
+			entry = fmt.Sprintf("• %s\n  %s\n  %s", post.Title, pushPostLine(&post), post.Permalink)
+		}
+
+		more := fmt.Sprintf("\n\n… and %d more", len(digest.Posts)-i)
+		if message.Len() > 0 && message.Len()+2+len(entry)+len(more) > maxBytes {
+			message.WriteString(more)
+			break
+		}
+
+		if message.Len() > 0 {
+			message.WriteString("\n\n")
+		}
+		message.WriteString(entry)
+	}
+
+	return []*pushNotification{{
+		Title:   fmt.Sprintf("%d new posts for %s", len(digest.Posts), digest.Keyword),
+		Message: message.String(),
+	}}
+}
+
+func pushPostLine(post *persistence.Post) string {
+	line := fmt.Sprintf("r/%s • ⬆ %d • ⬇ %d", post.Subreddit, post.Ups, post.Downs)
+	if post.NSFW {
+		line += " • NSFW"
+	}
+	if post.Spoiler {
+		line += " • Spoiler"
+	}
+	return line
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPushNotifications(t *testing.T) {
+	tests := []struct {
+		name          string
+		posts         int
+		mode          string
+		notifications int
+		more          bool
+	}{
+		{name: "no posts", posts: 0, mode: pushModePost, notifications: 1},
+		{name: "summary", posts: 3, mode: pushModeSummary, notifications: 1},
+		{name: "default mode", posts: 3, notifications: 1},
+		{name: "post mode", posts: 3, mode: pushModePost, notifications: 3},
+		{name: "post mode at limit", posts: pushMaxPosts, mode: pushModePost, notifications: pushMaxPosts},
+		{name: "post mode summarized", posts: pushMaxPosts + 1, mode: pushModePost, notifications: 1},
+		{name: "summary cut off", posts: 100, mode: pushModeSummary, notifications: 1, more: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := testPosts(tt.posts)
+			for i := range posts {
+				posts[i].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+			}
+
+			notifications := pushNotifications(&Digest{Keyword: "example", Posts: posts}, tt.mode, false,
+				pushMaxMessageBytes)
+			if len(notifications) != tt.notifications {
+				t.Fatalf("notifications = %d, want %d", len(notifications), tt.notifications)
+			}
+
+			for _, n := range notifications {
+				if len(n.Message) > pushMaxMessageBytes {
+					t.Errorf("message has %d bytes, limit is %d", len(n.Message), pushMaxMessageBytes)
+				}
+			}
+			if more := strings.Contains(notifications[0].Message, "more"); more != tt.more {
+				t.Errorf("cut off = %t, want %t", more, tt.more)
+			}
+		})
+	}
+}
+
+func TestPushNotificationsPostMode(t *testing.T) {
+	posts := testPosts(3)
+	for i := range posts {
+		posts[i].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+	}
+	posts[1].NSFW = true
+	posts[2].Spoiler = true
+
+	notifications := pushNotifications(&Digest{Keyword: "example", Posts: posts}, pushModePost, false, 1000)
+
+	for i, n := range notifications {
+		if n.Title != posts[i].Title || n.Click != posts[i].Permalink {
+			t.Errorf("notification %d = %q %q, want the post", i, n.Title, n.Click)
+		}
+	}
+	if notifications[0].Image == "" {
+		t.Error("post without flags has no image")
+	}
+	if notifications[1].Image != "" || notifications[2].Image != "" {
+		t.Error("NSFW or spoiler post has an image")
+	}
+	if !strings.Contains(notifications[1].Message, "NSFW") || !strings.Contains(notifications[2].Message, "Spoiler") {
+		t.Error("flags are missing from the message")
+	}
+}
+
+func TestPushNotificationsMarkdown(t *testing.T) {
+	posts := testPosts(1)
+	posts[0].Title = "[Spoiler] *bold* claim"
+
+	notifications := pushNotifications(&Digest{Keyword: "example", Posts: posts}, pushModeSummary, true, 1000)
+
+	want := `- [\[Spoiler\] \*bold\* claim](` + posts[0].Permalink + ")"
+	if !strings.HasPrefix(notifications[0].Message, want) {
+		t.Errorf("message = %q, want prefix %q", notifications[0].Message, want)
+	}
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+	// pushoverMaxMessageBytes is the limit of 1024 characters, in bytes to be on the safe side.
+	pushoverMaxMessageBytes = 1024
+	pushoverMaxTitle        = 250
+)
+
+// Pushover user, group and application keys are 30 characters.
+var pushoverKeyRegex = regexp.MustCompile(`^[A-Za-z0-9]{30}$`)
+
+type (
+	pushoverNotifier struct {
+		client *webhookClient
+		// apiURL is the messages endpoint, it's only ever changed to point at a local server.
+		apiURL string
+	}
+
+	// pushoverSettings are the settings of a Pushover recipient, the address is the user or group key.
+	pushoverSettings struct {
+		Mode string `json:"mode"`
+		// Token is the API token of the application the digest is sent by.
+		Token string `json:"token"`
+		// Priority is -2 (no notification) to 1 (high), 0 if unset. Emergency priority isn't supported, it repeats
+		// the notification until it's acknowledged.
+		Priority int `json:"priority"`
+		// URL is attached to summaries, e.g. a link to the web UI. Notifications of single posts link the post.
+		URL string `json:"url"`
+		// Device limits the notification to one of the user's devices.
+		Device string `json:"device"`
+	}
+
+	// pushoverMessage is the message of https://pushover.net/api#messages.
+	pushoverMessage struct {
+		Token    string `json:"token"`
+		User     string `json:"user"`
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority,omitempty"`
+		URL      string `json:"url,omitempty"`
+		URLTitle string `json:"url_title,omitempty"`
+		Device   string `json:"device,omitempty"`
+	}
+
+	pushoverResponse struct {
+		Status  int      `json:"status"`
+		Request string   `json:"request"`
+		Errors  []string `json:"errors"`
+	}
+)
+
+func newPushoverNotifier() *pushoverNotifier {
+	return &pushoverNotifier{
+		client: newWebhookClient(ChannelPushover),
+		apiURL: pushoverAPIURL,
+	}
+}
+
+func (p *pushoverNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelPushover,
+		}
+		result.MessageID, result.Err = p.send(ctx, digest, target)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send sends a message per notification of the digest and returns the request ID of the first one, Pushover doesn't
+// expose message IDs.
+func (p *pushoverNotifier) send(ctx context.Context, digest *Digest, target *Target) (string, error) {
+	settings, err := parsePushoverSettings(target.Settings)
+	if err != nil {
+		return "", &Error{Channel: ChannelPushover, Message: err.Error()}
+	}
+
+	var firstID string
+	for _, notification := range pushNotifications(digest, settings.Mode, false, pushoverMaxMessageBytes) {
+		msg := &pushoverMessage{
+			Token:    settings.Token,
+			User:     target.Address,
+			Title:    truncate(notification.Title, pushoverMaxTitle),
+			Message:  notification.Message,
+			Priority: settings.Priority,
+			URL:      settings.URL,
+			Device:   settings.Device,
+		}
+		if notification.Click != "" {
+			msg.URL, msg.URLTitle = notification.Click, "Open on Reddit"
+		}
+
+		var res pushoverResponse
+		if _, err = p.client.post(ctx, p.apiURL, nil, msg, &res); err != nil {
+			return "", pushoverErrorFrom(err)
+		}
+
+		if firstID == "" {
+			firstID = res.Request
+		}
+	}
+
+	return firstID, nil
+}
+
+// pushoverErrorFrom replaces the raw response in err with the errors Pushover lists, they name the invalid parameter.
+func pushoverErrorFrom(err error) error {
+	target, ok := errors.AsType[*Error](err)
+	if !ok {
+		return err
+	}
+
+	var res pushoverResponse
+	if json.Unmarshal([]byte(target.Message), &res) == nil && len(res.Errors) > 0 {
+		target.Message = strings.Join(res.Errors, ", ")
+	}
+
+	return target
+}
+
+func parsePushoverSettings(raw json.RawMessage) (*pushoverSettings, error) {
+	var settings pushoverSettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid pushover settings: %w", err)
+	}
+
+	if err := validatePushMode(settings.Mode); err != nil {
+		return nil, fmt.Errorf("invalid pushover settings: %w", err)
+	}
+	if !pushoverKeyRegex.MatchString(settings.Token) {
+		return nil, errors.New("invalid pushover settings: token must be the 30 character API token of an application")
+	}
+	if settings.Priority < -2 || settings.Priority > 1 {
+		return nil, errors.New("invalid pushover settings: priority must be between -2 and 1")
+	}
+	if settings.URL != "" && !strings.HasPrefix(settings.URL, "https://") && !strings.HasPrefix(settings.URL, "http://") {
+		return nil, errors.New("invalid pushover settings: url must be an http or https url")
+	}
+
+	return &settings, nil
+}
+
+func validatePushoverTarget(target *Target) error {
+	if !pushoverKeyRegex.MatchString(target.Address) {
+		return errors.New("invalid pushover address: expected the 30 character user or group key")
+	}
+
+	_, err := parsePushoverSettings(target.Settings)
+	return err
+}
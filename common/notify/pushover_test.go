@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testPushoverToken = "azGDORePK8gMaC0QOYAMyEEuzJnyUi"
+	testPushoverUser  = "uQiRzpo4DXghDmr9QzzfQu27cmVRsG"
+)
+
+func TestPushoverNotifierSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		messageID string
+		message   string
+		retryable bool
+	}{
+		{
+			name:      "accepted",
+			status:    http.StatusOK,
+			body:      `{"status":1,"request":"647d2300-702c-4b38-8b2f-d56326ae460b"}`,
+			messageID: "647d2300-702c-4b38-8b2f-d56326ae460b",
+		},
+		{
+			name:    "invalid user",
+			status:  http.StatusBadRequest,
+			body:    `{"user":"invalid","errors":["user identifier is invalid"],"status":0,"request":"5042853c-402d-4a18-abcb-168734a801de"}`,
+			message: "user identifier is invalid",
+		},
+		{
+			name:      "over quota",
+			status:    http.StatusTooManyRequests,
+			body:      `{"errors":["application has exceeded its monthly message limit"],"status":0}`,
+			message:   "application has exceeded its monthly message limit",
+			retryable: true,
+		},
+		{
+			name:      "outage",
+			status:    http.StatusInternalServerError,
+			body:      `Internal Server Error`,
+			message:   "Internal Server Error",
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var msg pushoverMessage
+				if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				if msg.Token != testPushoverToken || msg.User != testPushoverUser {
+					t.Errorf("unexpected token %q and user %q", msg.Token, msg.User)
+				}
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			p := newPushoverNotifier()
+			p.apiURL = srv.URL
+
+			id, err := p.send(context.Background(), &Digest{Keyword: "example", Posts: testPosts(2)}, &Target{
+				Channel:  ChannelPushover,
+				Address:  testPushoverUser,
+				Settings: json.RawMessage(`{"token":"` + testPushoverToken + `"}`),
+			})
+			if tt.status == http.StatusOK {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id != tt.messageID {
+					t.Errorf("message id = %q, want %q", id, tt.messageID)
+				}
+				return
+			}
+
+			target, ok := errors.AsType[*Error](err)
+			if !ok {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if target.Message != tt.message {
+				t.Errorf("message = %q, want %q", target.Message, tt.message)
+			}
+			if target.Retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", target.Retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestValidatePushoverTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		settings string
+		wantErr  bool
+	}{
+		{name: "valid", address: testPushoverUser, settings: `{"token":"` + testPushoverToken + `","priority":-1,"url":"https://example.com"}`},
+		{name: "post mode", address: testPushoverUser, settings: `{"token":"` + testPushoverToken + `","mode":"post"}`},
+		{name: "invalid user", address: "user", settings: `{"token":"` + testPushoverToken + `"}`, wantErr: true},
+		{name: "missing token", address: testPushoverUser, settings: `{}`, wantErr: true},
+		{name: "emergency priority", address: testPushoverUser, settings: `{"token":"` + testPushoverToken + `","priority":2}`, wantErr: true},
+		{name: "invalid url", address: testPushoverUser, settings: `{"token":"` + testPushoverToken + `","url":"javascript:alert(1)"}`, wantErr: true},
+		{name: "invalid mode", address: testPushoverUser, settings: `{"token":"` + testPushoverToken + `","mode":"all"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePushoverTarget(&Target{
+				Channel:  ChannelPushover,
+				Address:  tt.address,
+				Settings: json.RawMessage(tt.settings),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
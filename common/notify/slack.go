@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// slackMaxBlocks is the block limit of a single message.
+	slackMaxBlocks = 50
+	// slackMaxTextChars is the limit of a section's text, titles are far below in practice.
+	slackMaxTextChars = 3000
+	// slackMessageInterval keeps a digest within the documented rate of one message per second and webhook.
+	slackMessageInterval   = time.Second
+	slackMaxRateLimitWait  = 5 * time.Second
+	slackMaxRateLimitTries = 3
+)
+
+type (
+	slackNotifier struct {
+		client *webhookClient
+	}
+
+	slackMessage struct {
+		// Text is the fallback shown in notifications, the blocks are what's rendered in the channel.
+		Text   string        `json:"text"`
+		Blocks []*slackBlock `json:"blocks"`
+	}
+
+	slackBlock struct {
+		Type      string        `json:"type"`
+		Text      *slackText    `json:"text,omitempty"`
+		Accessory *slackElement `json:"accessory,omitempty"`
+		Elements  []*slackText  `json:"elements,omitempty"`
+	}
+
+	slackText struct {
+		Type  string `json:"type"`
+		Text  string `json:"text"`
+		Emoji bool   `json:"emoji,omitempty"`
+	}
+
+	slackElement struct {
+		Type     string `json:"type"`
+		ImageURL string `json:"image_url"`
+		AltText  string `json:"alt_text"`
+	}
+)
+
+func newSlackNotifier() *slackNotifier {
+	return &slackNotifier{
+		client: newWebhookClient(ChannelSlack),
+	}
+}
+
+// Notify posts the digest to the incoming webhook of every target. The webhook URL is taken as is, so any server
+// speaking the webhook protocol, e.g. an httptest server, can stand in for hooks.slack.com.
+func (s *slackNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	messages := slackMessages(digest)
+
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, &Result{
+			Target:   target,
+			Provider: ChannelSlack,
+			Err:      s.send(ctx, target.Address, messages),
+		})
+	}
+
+	return results
+}
+
+// send posts all messages of a digest to one webhook. Incoming webhooks answer with a plain "ok" and don't return a
+// message ID. Once part of the digest went out, a failure of the rest doesn't fail the delivery.
+func (s *slackNotifier) send(ctx context.Context, webhookURL string, messages []*slackMessage) error {
+	for i, msg := range messages {
+		if i > 0 {
+			if err := s.client.wait(ctx, slackMessageInterval); err != nil {
+				return s.client.partialSend(i, len(messages), err)
+			}
+		}
+
+		for attempt := 1; ; attempt++ {
+			_, err := s.client.post(ctx, webhookURL, nil, msg, nil)
+			if err == nil {
+				break
+			}
+
+			target, ok := errors.AsType[*Error](err)
+			if !ok || target.StatusCode != http.StatusTooManyRequests || attempt >= slackMaxRateLimitTries ||
+				target.RetryAfter > slackMaxRateLimitWait {
+				return s.client.partialSend(i, len(messages), err)
+			}
+			if err = s.client.wait(ctx, max(target.RetryAfter, time.Second)); err != nil {
+				return s.client.partialSend(i, len(messages), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// slackMessages renders the digest as Block Kit messages. Every post is a section with a divider, messages are split
+// before they exceed the block limit.
+func slackMessages(digest *Digest) []*slackMessage {
+	summary := fmt.Sprintf("%d new posts for %s", len(digest.Posts), digest.Keyword)
+	if len(digest.Posts) == 0 {
+		summary = fmt.Sprintf("No new posts for %s", digest.Keyword)
+	}
+
+	header := &slackBlock{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncate(summary, 150), Emoji: true},
+	}
+
+	current := &slackMessage{Text: summary, Blocks: []*slackBlock{header}}
+	messages := []*slackMessage{current}
+
+	for _, post := range digest.Posts {
+		text := fmt.Sprintf("*<%s|%s>*\nr/%s • ⬆ %d • ⬇ %d",
+			post.Permalink,
+			escapeSlack(post.Title),
+			escapeSlack(post.Subreddit),
+			post.Ups,
+			post.Downs,
+		)
+
+		var flags []string
+		if post.NSFW {
+			flags = append(flags, "NSFW")
+		}
+		if post.Spoiler {
+			flags = append(flags, "Spoiler")
+		}
+
+		section := &slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn"},
+		}
+
+		// Slack doesn't blur images, flagged posts go without thumbnail like the blurred one in the email.
+		if len(flags) > 0 {
+			text += " • *" + strings.Join(flags, " & ") + "*"
+		} else if strings.HasPrefix(post.Thumbnail, "https://") {
+			section.Accessory = &slackElement{
+				Type:     "image",
+				ImageURL: post.Thumbnail,
+				AltText:  truncate(post.Title, 2000),
+			}
+		}
+		section.Text.Text = truncate(text, slackMaxTextChars)
+
+		if len(current.Blocks)+2 > slackMaxBlocks {
+			current = &slackMessage{Text: summary}
+			messages = append(messages, current)
+		}
+		current.Blocks = append(current.Blocks, section, &slackBlock{Type: "divider"})
+	}
+
+	return messages
+}
+
+// escapeSlack escapes the control characters of Slack's mrkdwn, which would otherwise break the link syntax.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func validateSlackWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid slack webhook url: %w", err)
+	}
+
+	if u.Scheme != "https" || u.Host != "hooks.slack.com" || !strings.HasPrefix(u.Path, "/services/") {
+		return errors.New("invalid slack webhook url")
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"slices"
+	"testing"
+)
+
+func TestSlackMessages(t *testing.T) {
+	tests := []struct {
+		name   string
+		posts  int
+		blocks []int
+	}{
+		{name: "no posts", posts: 0, blocks: []int{1}},
+		{name: "single post", posts: 1, blocks: []int{3}},
+		// The header takes one block, every post a section and a divider.
+		{name: "full first message", posts: 24, blocks: []int{49}},
+		{name: "split", posts: 25, blocks: []int{49, 2}},
+		{name: "full second message", posts: 49, blocks: []int{49, 50}},
+		{name: "three messages", posts: 50, blocks: []int{49, 50, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := slackMessages(&Digest{Keyword: "example", Posts: testPosts(tt.posts)})
+
+			var blocks []int
+			for _, msg := range messages {
+				if len(msg.Blocks) > slackMaxBlocks {
+					t.Errorf("message has %d blocks, limit is %d", len(msg.Blocks), slackMaxBlocks)
+				}
+				if msg.Text == "" {
+					t.Error("message has no fallback text")
+				}
+				blocks = append(blocks, len(msg.Blocks))
+			}
+			if !slices.Equal(blocks, tt.blocks) {
+				t.Errorf("blocks per message = %v, want %v", blocks, tt.blocks)
+			}
+
+			if messages[0].Blocks[0].Type != "header" {
+				t.Errorf("first block is %q, want header", messages[0].Blocks[0].Type)
+			}
+			for _, msg := range messages[1:] {
+				if msg.Blocks[0].Type != "section" {
+					t.Errorf("continuation starts with %q, want section", msg.Blocks[0].Type)
+				}
+			}
+		})
+	}
+}
+
+func TestSlackMessagesSection(t *testing.T) {
+	tests := []struct {
+		name      string
+		post      persistence.Post
+		text      string
+		thumbnail bool
+	}{
+		{
+			name: "thumbnail",
+			post: persistence.Post{
+				Title:     "Example post",
+				Subreddit: "example",
+				Ups:       12,
+				Downs:     1,
+				Thumbnail: "https://b.thumbs.redditmedia.com/example.jpg",
+				Permalink: "https://www.reddit.com/r/example/comments/1abc/example_post/",
+			},
+			text:      "*<https://www.reddit.com/r/example/comments/1abc/example_post/|Example post>*\nr/example • ⬆ 12 • ⬇ 1",
+			thumbnail: true,
+		},
+		{
+			name: "flagged",
+			post: persistence.Post{
+				Title:     "Example post",
+				Subreddit: "example",
+				NSFW:      true,
+				Spoiler:   true,
+				Thumbnail: "https://b.thumbs.redditmedia.com/example.jpg",
+				Permalink: "https://www.reddit.com/r/example/comments/1abc/example_post/",
+			},
+			text: "*<https://www.reddit.com/r/example/comments/1abc/example_post/|Example post>*\nr/example • ⬆ 0 • ⬇ 0 • *NSFW & Spoiler*",
+		},
+		{
+			name: "escaped",
+			post: persistence.Post{
+				Title:     "<b>Q&A</b> | ask anything",
+				Subreddit: "example",
+				Thumbnail: "self",
+				Permalink: "https://www.reddit.com/r/example/comments/1abc/qa/",
+			},
+			text: "*<https://www.reddit.com/r/example/comments/1abc/qa/|&lt;b&gt;Q&amp;A&lt;/b&gt; | ask anything>*\nr/example • ⬆ 0 • ⬇ 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := slackMessages(&Digest{Keyword: "example", Posts: []persistence.Post{tt.post}})
+
+			section := messages[0].Blocks[1]
+			if section.Text.Text != tt.text {
+				t.Errorf("text = %q, want %q", section.Text.Text, tt.text)
+			}
+			if (section.Accessory != nil) != tt.thumbnail {
+				t.Errorf("thumbnail = %t, want %t", section.Accessory != nil, tt.thumbnail)
+			}
+		})
+	}
+}
+
+// testPosts returns n distinct posts of a single subreddit.
+func testPosts(n int) []persistence.Post {
+	posts := make([]persistence.Post, 0, n)
+	for i := range n {
+		posts = append(posts, persistence.Post{
+			ID:        fmt.Sprintf("post%d", i),
+			Title:     fmt.Sprintf("Example post %d", i),
+			Subreddit: "example",
+			Ups:       i,
+			Created:   "17 Oct 26 08:00 UTC",
+			Permalink: fmt.Sprintf("https://www.reddit.com/r/example/comments/post%d/", i),
+		})
+	}
+	return posts
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"net/url"
+	"strings"
+)
+
+// teamsMaxCardBytes keeps the card below the 28 KB Teams accepts per message, with room for the envelope.
+const teamsMaxCardBytes = 24 << 10
+
+type (
+	teamsNotifier struct {
+		client *webhookClient
+	}
+
+	// teamsMessage is the envelope the "Post to a channel when a webhook request is received" workflow expects, see
+	// https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using.
+	teamsMessage struct {
+		Type        string             `json:"type"`
+		Attachments []*teamsAttachment `json:"attachments"`
+	}
+
+	teamsAttachment struct {
+		ContentType string     `json:"contentType"`
+		Content     *teamsCard `json:"content"`
+	}
+
+	teamsCard struct {
+		Schema  string          `json:"$schema"`
+		Type    string          `json:"type"`
+		Version string          `json:"version"`
+		Body    []*teamsElement `json:"body"`
+		MSTeams map[string]any  `json:"msteams,omitempty"`
+	}
+
+	teamsElement struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Size     string `json:"size,omitempty"`
+		Weight   string `json:"weight,omitempty"`
+		Spacing  string `json:"spacing,omitempty"`
+		IsSubtle bool   `json:"isSubtle,omitempty"`
+		Wrap     bool   `json:"wrap"`
+	}
+
+	teamsGroup struct {
+		subreddit string
+		posts     []*persistence.Post
+	}
+)
+
+func newTeamsNotifier() *teamsNotifier {
+	return &teamsNotifier{
+		client: newWebhookClient(ChannelTeams),
+	}
+}
+
+// Notify posts the card to the workflow webhook of every target. Teams doesn't return an ID for the message.
+func (t *teamsNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	msg := &teamsMessage{
+		Type: "message",
+		Attachments: []*teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     teamsDigestCard(digest),
+		}},
+	}
+
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		_, err := t.client.post(ctx, target.Address, nil, msg, nil)
+		results = append(results, &Result{
+			Target:   target,
+			Provider: ChannelTeams,
+			Err:      err,
+		})
+	}
+
+	return results
+}
+
+// teamsDigestCard summarizes the digest in a single card, grouped by subreddit in the order the subreddits first
+// appear. NSFW posts aren't listed, the card only tells how many were left out. Posts that don't fit the size limit
+// are counted at the end.
+func teamsDigestCard(digest *Digest) *teamsCard {
+	card := &teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		MSTeams: map[string]any{"width": "Full"},
+	}
+
+	heading := fmt.Sprintf("%d new posts for %s", len(digest.Posts), escapeMarkdown(digest.Keyword))
+	if len(digest.Posts) == 0 {
+		heading = fmt.Sprintf("No new posts for %s", escapeMarkdown(digest.Keyword))
+	}
+	card.Body = append(card.Body, &teamsElement{
+		Type:   "TextBlock",
+		Text:   heading,
+		Size:   "Large",
+		Weight: "Bolder",
+		Wrap:   true,
+	})
+
+	var (
+		groups []*teamsGroup
+		index  = make(map[string]*teamsGroup)
+		nsfw   int
+	)
+	for _, post := range digest.Posts {
+		if post.NSFW {
+			nsfw++
+			continue
+		}
+
+		group, ok := index[strings.ToLower(post.Subreddit)]
+		if !ok {
+			group = &teamsGroup{subreddit: post.Subreddit}
+			index[strings.ToLower(post.Subreddit)] = group
+			groups = append(groups, group)
+		}
+		group.posts = append(group.posts, &post)
+	}
+
+	size := teamsCardSize(card)
+	omitted := 0
+	for _, group := range groups {
+		elements := []*teamsElement{{
+			Type:    "TextBlock",
+			Text:    "r/" + escapeMarkdown(group.subreddit),
+			Weight:  "Bolder",
+			Spacing: "Medium",
+			Wrap:    true,
+		}}
+
+		for _, post := range group.posts {
+			text := fmt.Sprintf("[%s](%s) · ⬆ %d", escapeMarkdown(post.Title), post.Permalink, post.Ups)
+			if post.Spoiler {
+				text += " · Spoiler"
+			}
+			elements = append(elements, &teamsElement{
+				Type:    "TextBlock",
+				Text:    text,
+				Spacing: "Small",
+				Wrap:    true,
+			})
+		}
+
+		added := teamsElementsSize(elements)
+		if omitted > 0 || size+added > teamsMaxCardBytes {
+			omitted += len(group.posts)
+			continue
+		}
+
+		size += added
+		card.Body = append(card.Body, elements...)
+	}
+
+	var footer []string
+	if omitted > 0 {
+		footer = append(footer, fmt.Sprintf("%d more posts didn't fit into this message.", omitted))
+	}
+	if nsfw > 0 {
+		footer = append(footer, fmt.Sprintf("%d NSFW posts hidden.", nsfw))
+	}
+	if len(footer) > 0 {
+		card.Body = append(card.Body, &teamsElement{
+			Type:     "TextBlock",
+			Text:     strings.Join(footer, " "),
+			Spacing:  "Medium",
+			IsSubtle: true,
+			Wrap:     true,
+		})
+	}
+
+	return card
+}
+
+func teamsCardSize(card *teamsCard) int {
+	b, _ := json.Marshal(card)
+	return len(b)
+}
+
+func teamsElementsSize(elements []*teamsElement) int {
+	b, _ := json.Marshal(elements)
+	return len(b)
+}
+
+// validateTeamsWebhookURL accepts the URLs of Power Automate workflow triggers. The retired Office 365 connectors
+// aren't supported.
+func validateTeamsWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid teams webhook url: %w", err)
+	}
+
+	host := u.Hostname()
+	if u.Scheme != "https" ||
+		(!strings.HasSuffix(host, ".logic.azure.com") && !strings.HasSuffix(host, ".api.powerplatform.com")) {
+		return errors.New("invalid teams webhook url: expected the url of a workflow trigger")
+	}
+	if !strings.Contains(u.Path, "/workflows/") {
+		return errors.New("invalid teams webhook url: expected the url of a workflow trigger")
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTeamsDigestCard(t *testing.T) {
+	post := func(subreddit, title string, nsfw, spoiler bool) persistence.Post {
+		return persistence.Post{
+			Title:     title,
+			Subreddit: subreddit,
+			NSFW:      nsfw,
+			Spoiler:   spoiler,
+			Ups:       3,
+			Permalink: "https://www.reddit.com/r/" + subreddit + "/comments/1abc/",
+		}
+	}
+
+	tests := []struct {
+		name  string
+		posts []persistence.Post
+		body  []string
+	}{
+		{
+			name: "no posts",
+			body: []string{"No new posts for example"},
+		},
+		{
+			name: "grouped by subreddit",
+			posts: []persistence.Post{
+				post("golang", "First", false, false),
+				post("rust", "Second", false, true),
+				post("Golang", "Third", false, false),
+			},
+			body: []string{
+				"3 new posts for example",
+				"r/golang",
+				"[First](https://www.reddit.com/r/golang/comments/1abc/) · ⬆ 3",
+				"[Third](https://www.reddit.com/r/Golang/comments/1abc/) · ⬆ 3",
+				"r/rust",
+				"[Second](https://www.reddit.com/r/rust/comments/1abc/) · ⬆ 3 · Spoiler",
+			},
+		},
+		{
+			name: "nsfw hidden",
+			posts: []persistence.Post{
+				post("golang", "First", false, false),
+				post("golang", "Second", true, false),
+				post("rust", "Third", true, true),
+			},
+			body: []string{
+				"3 new posts for example",
+				"r/golang",
+				"[First](https://www.reddit.com/r/golang/comments/1abc/) · ⬆ 3",
+				"2 NSFW posts hidden.",
+			},
+		},
+		{
+			name: "only nsfw",
+			posts: []persistence.Post{
+				post("golang", "First", true, false),
+			},
+			body: []string{
+				"1 new posts for example",
+				"1 NSFW posts hidden.",
+			},
+		},
+		{
+			name: "escaped",
+			posts: []persistence.Post{
+				post("golang", "[Meta] *everything* about _generics_", false, false),
+			},
+			body: []string{
+				"1 new posts for example",
+				"r/golang",
+				`[\[Meta\] \*everything\* about \_generics\_](https://www.reddit.com/r/golang/comments/1abc/) · ⬆ 3`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := teamsDigestCard(&Digest{Keyword: "example", Posts: tt.posts})
+
+			body := make([]string, 0, len(card.Body))
+			for _, element := range card.Body {
+				body = append(body, element.Text)
+			}
+			if !slices.Equal(body, tt.body) {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestTeamsDigestCardSizeLimit(t *testing.T) {
+	posts := make([]persistence.Post, 0, 300)
+	for i := range 300 {
+		posts = append(posts, persistence.Post{
+			Title:     fmt.Sprintf("%03d %s", i, strings.Repeat("long title ", 20)),
+			Subreddit: fmt.Sprintf("sub%d", i/10),
+			NSFW:      i%100 == 0,
+			Permalink: fmt.Sprintf("https://www.reddit.com/r/sub%d/comments/%d/", i/10, i),
+		})
+	}
+
+	card := teamsDigestCard(&Digest{Keyword: "example", Posts: posts})
+
+	if size := teamsCardSize(card); size > teamsMaxCardBytes {
+		t.Errorf("card size = %d, limit is %d", size, teamsMaxCardBytes)
+	}
+
+	listed := 0
+	for _, element := range card.Body[1 : len(card.Body)-1] {
+		if !strings.HasPrefix(element.Text, "r/") {
+			listed++
+		}
+	}
+
+	// The posts that don't fit are counted in the footer, next to the NSFW posts that aren't listed at all.
+	omitted := 300 - 3 - listed
+	if listed == 0 || omitted == 0 {
+		t.Fatalf("expected the card to be cut off, %d posts listed", listed)
+	}
+	footer := card.Body[len(card.Body)-1].Text
+	if want := fmt.Sprintf("%d more posts didn't fit into this message. 3 NSFW posts hidden.", omitted); footer != want {
+		t.Errorf("footer = %q, want %q", footer, want)
+	}
+}
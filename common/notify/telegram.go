@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the Bot API, see https://core.telegram.org/bots/api#sendmessage and
+// https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this.
+const (
+	telegramAPIURL          = "https://api.telegram.org"
+	telegramMaxMessageChars = 4096
+	telegramMaxCaptionChars = 1024
+	// telegramMaxPhotos is the largest digest that is sent as one photo per post. Larger digests are sent as lists,
+	// a message per post would take longer than the activity is allowed to run.
+	telegramMaxPhotos = 5
+	// Telegram allows about one message per second in a private chat and twenty per minute in a group.
+	telegramChatInterval  = time.Second
+	telegramGroupInterval = 3 * time.Second
+)
+
+var (
+	telegramTokenRegex  = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]{30,}$`)
+	telegramChatIDRegex = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z][A-Za-z0-9_]{4,})$`)
+)
+
+type (
+	telegramNotifier struct {
+		client *webhookClient
+		// apiURL is the Bot API server, it's only ever changed to point at a local server.
+		apiURL string
+	}
+
+	// telegramRequest is a single Bot API call, either sendMessage or sendPhoto.
+	telegramRequest struct {
+		method  string
+		payload map[string]any
+	}
+
+	telegramResponse struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+)
+
+func newTelegramNotifier() *telegramNotifier {
+	return &telegramNotifier{
+		client: newWebhookClient(ChannelTelegram),
+		apiURL: telegramAPIURL,
+	}
+}
+
+func (t *telegramNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelTelegram,
+		}
+
+		token, chatID, err := parseTelegramAddress(target.Address)
+		if err != nil {
+			result.Err = &Error{Channel: ChannelTelegram, Message: err.Error()}
+		} else {
+			result.MessageID, result.Err = t.send(ctx, token, chatID, telegramRequests(digest, chatID))
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send calls the Bot API for every request of a digest and returns the ID of the first message. Rate limits aren't
+// waited out here, the returned error carries Telegram's retry_after for the activity retry. The same goes for the
+// pacing between messages once the activity runs out of time, unless part of the digest went out already.
+func (t *telegramNotifier) send(ctx context.Context, token, chatID string, requests []*telegramRequest) (string, error) {
+	interval := telegramChatInterval
+	if strings.HasPrefix(chatID, "-") || strings.HasPrefix(chatID, "@") {
+		interval = telegramGroupInterval
+	}
+
+	var firstID string
+	for i, req := range requests {
+		if i > 0 {
+			if err := t.client.wait(ctx, interval); err != nil {
+				return firstID, t.client.partialSend(i, len(requests), err)
+			}
+		}
+
+		id, err := t.call(ctx, token, req)
+
+		// Telegram fetches photos itself and rejects the call if it can't, the post is still worth sending as text.
+		if target, ok := errors.AsType[*Error](err); ok && req.method == "sendPhoto" &&
+			target.StatusCode == http.StatusBadRequest {
+			id, err = t.call(ctx, token, &telegramRequest{
+				method:  "sendMessage",
+				payload: telegramMessage(chatID, req.payload["caption"].(string)),
+			})
+		}
+		if err != nil {
+			return firstID, t.client.partialSend(i, len(requests), err)
+		}
+
+		if firstID == "" {
+			firstID = id
+		}
+	}
+
+	return firstID, nil
+}
+
+func (t *telegramNotifier) call(ctx context.Context, token string, req *telegramRequest) (string, error) {
+	var res telegramResponse
+
+	_, err := t.client.post(ctx, t.apiURL+"/bot"+token+"/"+req.method, nil, req.payload, &res)
+	if err != nil {
+		return "", telegramError(err, token)
+	}
+
+	return strconv.FormatInt(res.Result.MessageID, 10), nil
+}
+
+// telegramError replaces the raw response in err with Telegram's description and takes the delay from retry_after,
+// Telegram doesn't send a Retry-After header.
+func telegramError(err error, token string) error {
+	target, ok := errors.AsType[*Error](err)
+	if !ok {
+		return err
+	}
+
+	// The token is part of the URL, which ends up in network errors.
+	target.Message = redact(target.Message, token)
+
+	var res telegramResponse
+	if json.Unmarshal([]byte(target.Message), &res) == nil && res.Description != "" {
+		target.Message = res.Description
+		if res.Parameters.RetryAfter > 0 {
+			target.Retryable = true
+			target.RetryAfter = time.Duration(res.Parameters.RetryAfter) * time.Second
+		}
+	}
+
+	return target
+}
+
+// telegramRequests renders the digest. Small digests are sent as one photo per post with the post as caption, posts
+// without a photo and larger digests as HTML lists split at the message limit.
+func telegramRequests(digest *Digest, chatID string) []*telegramRequest {
+	header := fmt.Sprintf("<b>%d new posts for %s</b>", len(digest.Posts), html.EscapeString(digest.Keyword))
+	if len(digest.Posts) == 0 {
+		header = fmt.Sprintf("No new posts for <b>%s</b>.", html.EscapeString(digest.Keyword))
+	}
+
+	var (
+		requests []*telegramRequest
+		text     strings.Builder
+		photos   = len(digest.Posts) <= telegramMaxPhotos
+	)
+	text.WriteString(header)
+
+	flush := func() {
+		if text.Len() > 0 {
+			requests = append(requests, &telegramRequest{
+				method:  "sendMessage",
+				payload: telegramMessage(chatID, text.String()),
+			})
+			text.Reset()
+		}
+	}
+
+	for _, post := range digest.Posts {
+		entry := telegramPost(&post)
+
+		// Telegram blurs media only when asked to, flagged posts go without photo like the blurred one in the email.
+		if photos && !post.NSFW && !post.Spoiler && strings.HasPrefix(post.Thumbnail, "https://") &&
+			utf8.RuneCountInString(entry) <= telegramMaxCaptionChars {
+			flush()
+			requests = append(requests, &telegramRequest{
+				method: "sendPhoto",
+				payload: map[string]any{
+					"chat_id":    chatID,
+					"photo":      post.Thumbnail,
+					"caption":    entry,
+					"parse_mode": "HTML",
+				},
+			})
+			continue
+		}
+
+		if text.Len() > 0 && utf8.RuneCountInString(text.String())+2+utf8.RuneCountInString(entry) > telegramMaxMessageChars {
+			flush()
+		}
+		if text.Len() > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(entry)
+	}
+	flush()
+
+	return requests
+}
+
+func telegramMessage(chatID, text string) map[string]any {
+	return map[string]any{
+		"chat_id":              chatID,
+		"text":                 text,
+		"parse_mode":           "HTML",
+		"link_preview_options": map[string]any{"is_disabled": true},
+	}
+}
+
+// telegramPost renders a post in Telegram's HTML subset. Titles are cut well below the caption limit, so an entry
+// always fits a caption and a message.
+func telegramPost(post *persistence.Post) string {
+	entry := fmt.Sprintf(`<a href="%s">%s</a>`+"\nr/%s • ⬆ %d • ⬇ %d",
+		html.EscapeString(post.Permalink),
+		html.EscapeString(truncate(post.Title, 300)),
+		html.EscapeString(post.Subreddit),
+		post.Ups,
+		post.Downs,
+	)
+
+	var flags []string
+	if post.NSFW {
+		flags = append(flags, "NSFW")
+	}
+	if post.Spoiler {
+		flags = append(flags, "Spoiler")
+	}
+	if len(flags) > 0 {
+		entry += " • <b>" + strings.Join(flags, " &amp; ") + "</b>"
+	}
+
+	return entry
+}
+
+// parseTelegramAddress splits an address of the form <bot token>/<chat id>. The chat ID is numeric, negative for
+// groups, or the @username of a public channel.
+func parseTelegramAddress(address string) (string, string, error) {
+	token, chatID, ok := strings.Cut(address, "/")
+	if !ok || !telegramTokenRegex.MatchString(token) {
+		return "", "", errors.New("invalid telegram address: expected <bot token>/<chat id>")
+	}
+	if !telegramChatIDRegex.MatchString(chatID) {
+		return "", "", errors.New("invalid telegram address: invalid chat id")
+	}
+
+	return token, chatID, nil
+}
+
+func validateTelegramAddress(address string) error {
+	_, _, err := parseTelegramAddress(address)
+	return err
+}
@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+const testTelegramToken = "123456:ABCdefGHIjklMNOpqrSTUvwxYZ0123456789"
+
+func TestTelegramRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		posts   int
+		modify  func(i int, post *Digest)
+		methods []string
+	}{
+		{name: "no posts", posts: 0, methods: []string{"sendMessage"}},
+		{name: "posts without photo", posts: 2, methods: []string{"sendMessage"}},
+		{
+			name:  "photos",
+			posts: 2,
+			modify: func(i int, digest *Digest) {
+				digest.Posts[i].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+			},
+			methods: []string{"sendMessage", "sendPhoto", "sendPhoto"},
+		},
+		{
+			name:  "flagged post without photo",
+			posts: 3,
+			modify: func(i int, digest *Digest) {
+				digest.Posts[i].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+				digest.Posts[i].NSFW = i == 1
+			},
+			methods: []string{"sendMessage", "sendPhoto", "sendMessage", "sendPhoto"},
+		},
+		{
+			name:  "too many posts for photos",
+			posts: telegramMaxPhotos + 1,
+			modify: func(i int, digest *Digest) {
+				digest.Posts[i].Thumbnail = "https://b.thumbs.redditmedia.com/example.jpg"
+			},
+			methods: []string{"sendMessage"},
+		},
+		{
+			name:  "split at message limit",
+			posts: 20,
+			modify: func(i int, digest *Digest) {
+				digest.Posts[i].Title = strings.Repeat("a", 300)
+			},
+			methods: []string{"sendMessage", "sendMessage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := &Digest{Keyword: "example", Posts: testPosts(tt.posts)}
+			if tt.modify != nil {
+				for i := range digest.Posts {
+					tt.modify(i, digest)
+				}
+			}
+
+			requests := telegramRequests(digest, "42")
+
+			var methods []string
+			for _, req := range requests {
+				methods = append(methods, req.method)
+
+				switch req.method {
+				case "sendMessage":
+					if n := utf8.RuneCountInString(req.payload["text"].(string)); n > telegramMaxMessageChars {
+						t.Errorf("message has %d characters, limit is %d", n, telegramMaxMessageChars)
+					}
+				case "sendPhoto":
+					if n := utf8.RuneCountInString(req.payload["caption"].(string)); n > telegramMaxCaptionChars {
+						t.Errorf("caption has %d characters, limit is %d", n, telegramMaxCaptionChars)
+					}
+				}
+			}
+			if !slices.Equal(methods, tt.methods) {
+				t.Errorf("methods = %v, want %v", methods, tt.methods)
+			}
+		})
+	}
+}
+
+// telegramReply is the answer of the fake Bot API to one call.
+type telegramReply struct {
+	status int
+	body   string
+}
+
+var telegramOK = telegramReply{status: http.StatusOK, body: `{"ok":true,"result":{"message_id":%d}}`}
+
+func TestTelegramNotifierSend(t *testing.T) {
+	photo := &telegramRequest{
+		method: "sendPhoto",
+		payload: map[string]any{
+			"chat_id": "42",
+			"photo":   "https://b.thumbs.redditmedia.com/example.jpg",
+			"caption": "caption",
+		},
+	}
+	message := &telegramRequest{method: "sendMessage", payload: telegramMessage("42", "text")}
+
+	tests := []struct {
+		name       string
+		requests   []*telegramRequest
+		replies    []telegramReply
+		methods    []string
+		messageID  string
+		wantErr    bool
+		retryAfter time.Duration
+	}{
+		{
+			name:      "sent",
+			requests:  []*telegramRequest{message},
+			replies:   []telegramReply{telegramOK},
+			methods:   []string{"sendMessage"},
+			messageID: "1",
+		},
+		{
+			name:     "photo rejected",
+			requests: []*telegramRequest{photo},
+			replies: []telegramReply{
+				{status: http.StatusBadRequest, body: `{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier/HTTP URL specified"}`},
+				telegramOK,
+			},
+			methods:   []string{"sendPhoto", "sendMessage"},
+			messageID: "2",
+		},
+		{
+			name:     "rate limited",
+			requests: []*telegramRequest{message},
+			replies: []telegramReply{
+				{status: http.StatusTooManyRequests, body: `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 30","parameters":{"retry_after":30}}`},
+			},
+			methods:    []string{"sendMessage"},
+			wantErr:    true,
+			retryAfter: 30 * time.Second,
+		},
+		{
+			name:     "bot blocked",
+			requests: []*telegramRequest{message, message},
+			replies: []telegramReply{
+				{status: http.StatusForbidden, body: `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`},
+			},
+			methods: []string{"sendMessage"},
+			wantErr: true,
+		},
+		{
+			// A retry would send the first message again.
+			name:     "partially sent",
+			requests: []*telegramRequest{message, message},
+			replies: []telegramReply{
+				telegramOK,
+				{status: http.StatusTooManyRequests, body: `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5","parameters":{"retry_after":5}}`},
+			},
+			methods:   []string{"sendMessage", "sendMessage"},
+			messageID: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var methods []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testTelegramToken+"/")
+				if !ok {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				methods = append(methods, method)
+				if len(methods) > len(tt.replies) {
+					t.Errorf("unexpected call %d", len(methods))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				reply := tt.replies[len(methods)-1]
+				w.WriteHeader(reply.status)
+				if reply.status == http.StatusOK {
+					_, _ = fmt.Fprintf(w, reply.body, len(methods))
+				} else {
+					_, _ = w.Write([]byte(reply.body))
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			notifier := newTelegramNotifier()
+			notifier.apiURL = srv.URL
+
+			id, err := notifier.send(context.Background(), testTelegramToken, "42", tt.requests)
+			if tt.wantErr {
+				target, ok := errors.AsType[*Error](err)
+				if !ok {
+					t.Fatalf("expected *Error, got %v", err)
+				}
+				if target.RetryAfter != tt.retryAfter || target.Retryable != (tt.retryAfter > 0) {
+					t.Errorf("retryable = %t after %s, want after %s", target.Retryable, target.RetryAfter, tt.retryAfter)
+				}
+				if strings.Contains(target.Message, testTelegramToken) || strings.HasPrefix(target.Message, "{") {
+					t.Errorf("message %q isn't Telegram's description", target.Message)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if id != tt.messageID {
+				t.Errorf("message id = %q, want %q", id, tt.messageID)
+			}
+			if !slices.Equal(methods, tt.methods) {
+				t.Errorf("methods = %v, want %v", methods, tt.methods)
+			}
+		})
+	}
+}
+
+func TestParseTelegramAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		chatID  string
+		wantErr bool
+	}{
+		{address: testTelegramToken + "/42", chatID: "42"},
+		{address: testTelegramToken + "/-1001234567890", chatID: "-1001234567890"},
+		{address: testTelegramToken + "/@example_channel", chatID: "@example_channel"},
+		{address: testTelegramToken, wantErr: true},
+		{address: "123456:short/42", wantErr: true},
+		{address: testTelegramToken + "/", wantErr: true},
+		{address: testTelegramToken + "/@abc", wantErr: true},
+		{address: testTelegramToken + "/42/43", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			token, chatID, err := parseTelegramAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTelegramAddress() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && (token != testTelegramToken || chatID != tt.chatID) {
+				t.Errorf("parseTelegramAddress() = %q, %q", token, chatID)
+			}
+		})
+	}
+}
@@ -0,0 +1,547 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// redactedSecret replaces secrets in redacted targets.
+const redactedSecret = "****"
+
+var ErrUnsupportedScheme = errors.New("unsupported url scheme")
+
+// urlSchemes parses the notification URLs a recipient can be configured with instead of channel, address and settings.
+// The schemes follow those of Apprise (https://github.com/caronc/apprise/wiki), so existing URLs can be reused.
+var urlSchemes = map[string]func(raw string) (*Target, error){
+	"mailto":  parseMailtoURL,
+	"discord": parseDiscordURL,
+	"slack":   parseSlackURL,
+	"tgram":   parseTelegramURL,
+	"ntfy":    parseNtfyURL,
+	"ntfys":   parseNtfyURL,
+	"json":    parseJSONURL,
+	"jsons":   parseJSONURL,
+	"pover":   parsePushoverURL,
+}
+
+// secretSettings are the settings of any channel that hold a secret.
+var secretSettings = []string{"secret", "token", "password", "accessToken", "auth"}
+
+// ParseURL parses a notification URL like discord://<webhook id>/<webhook token> into a target. The target isn't
+// validated yet, a client may have sent back a redacted URL, see RestoreSecrets.
+func ParseURL(raw string) (*Target, error) {
+	scheme, _, ok := strings.Cut(raw, ":")
+	if !ok {
+		return nil, errors.New("invalid notification url: missing scheme")
+	}
+
+	parse, ok := urlSchemes[strings.ToLower(scheme)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, scheme)
+	}
+
+	target, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s url: %w", scheme, err)
+	}
+
+	return target, nil
+}
+
+// TargetURL formats target as notification URL, the inverse of ParseURL. Channels without a URL scheme and targets
+// that can't be expressed as URL result in an empty string.
+func TargetURL(target *Target) string {
+	switch target.Channel {
+	case "", ChannelEmail:
+		local, domain, ok := strings.Cut(target.Address, "@")
+		if !ok {
+			return ""
+		}
+		return (&url.URL{Scheme: "mailto", User: url.User(local), Host: domain}).String()
+	case ChannelDiscord:
+		u, err := url.Parse(target.Address)
+		if err != nil {
+			return ""
+		}
+		_, webhook, ok := strings.Cut(u.Path, "/webhooks/")
+		if !ok {
+			return ""
+		}
+		return "discord://" + webhook
+	case ChannelSlack:
+		webhook, ok := strings.CutPrefix(target.Address, "https://hooks.slack.com/services/")
+		if !ok {
+			return ""
+		}
+		return "slack://" + webhook
+	case ChannelTelegram:
+		return "tgram://" + target.Address
+	case ChannelNtfy:
+		return unescapeRedacted(ntfyURL(target))
+	case ChannelWebhook:
+		return unescapeRedacted(jsonURL(target))
+	case ChannelPushover:
+		return unescapeRedacted(pushoverURL(target))
+	default:
+		return ""
+	}
+}
+
+// unescapeRedacted keeps redacted secrets in queries and user info readable, url.URL escapes the asterisks.
+func unescapeRedacted(s string) string {
+	return strings.ReplaceAll(s, url.QueryEscape(redactedSecret), redactedSecret)
+}
+
+// RedactTarget returns a copy of target with its secrets replaced, for showing it to users. Webhook URLs keep their
+// ID, only the token part is replaced.
+func RedactTarget(target *Target) *Target {
+	redacted := *target
+
+	switch target.Channel {
+	case ChannelDiscord, ChannelSlack:
+		if i := strings.LastIndexByte(target.Address, '/'); i >= 0 && i < len(target.Address)-1 {
+			redacted.Address = target.Address[:i+1] + redactedSecret
+		}
+	case ChannelTelegram:
+		if botID, _, ok := strings.Cut(target.Address, ":"); ok {
+			_, chatID, _ := strings.Cut(target.Address, "/")
+			redacted.Address = botID + ":" + redactedSecret + "/" + chatID
+		}
+	case ChannelWebhook, ChannelNtfy, ChannelGotify, ChannelMatrix, ChannelTeams:
+		if u, err := url.Parse(target.Address); err == nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redactedSecret)
+			}
+			// Workflow URLs of Teams are authorized by the signature in the query.
+			if q := u.Query(); q.Has("sig") {
+				q.Set("sig", redactedSecret)
+				u.RawQuery = q.Encode()
+			}
+			redacted.Address = unescapeRedacted(u.String())
+		}
+	}
+
+	var settings map[string]any
+	if json.Unmarshal(target.Settings, &settings) == nil {
+		changed := false
+		for _, key := range secretSettings {
+			if v, ok := settings[key].(string); ok && v != "" {
+				settings[key] = redactedSecret
+				changed = true
+			}
+		}
+		if changed {
+			redacted.Settings, _ = json.Marshal(settings)
+		}
+	}
+
+	return &redacted
+}
+
+// RestoreSecrets takes the secrets target was redacted of from stored, the target it was redacted from. This lets
+// clients send a recipient back as they got it. Secrets that were changed are kept, and nothing is restored for a
+// target that points somewhere else now, the old credentials would be sent to the new server.
+func RestoreSecrets(target, stored *Target) *Target {
+	if target.Channel != stored.Channel || !sameDestination(target, stored) {
+		return target
+	}
+
+	restored := *target
+	if target.Address == RedactTarget(stored).Address {
+		restored.Address = stored.Address
+	}
+
+	var submitted, original map[string]any
+	if json.Unmarshal(target.Settings, &submitted) == nil && json.Unmarshal(stored.Settings, &original) == nil {
+		changed := false
+		for key, v := range submitted {
+			if v == redactedSecret && original[key] != nil {
+				submitted[key] = original[key]
+				changed = true
+			}
+		}
+		if changed {
+			restored.Settings, _ = json.Marshal(submitted)
+		}
+	}
+
+	return &restored
+}
+
+// sameDestination reports whether the submitted address still has the scheme, host and path of the stored one. Query
+// and user info may differ, they can be redacted. Addresses that aren't URLs have to match their redacted form.
+func sameDestination(target, stored *Target) bool {
+	if target.Address == stored.Address || target.Address == RedactTarget(stored).Address {
+		return true
+	}
+
+	u, err := url.Parse(target.Address)
+	if err != nil {
+		return false
+	}
+	s, err := url.Parse(stored.Address)
+	if err != nil {
+		return false
+	}
+
+	return u.Host != "" && strings.EqualFold(u.Scheme, s.Scheme) && strings.EqualFold(u.Host, s.Host) && u.Path == s.Path
+}
+
+// IsRedacted reports whether target still contains redacted secrets.
+func IsRedacted(target *Target) bool {
+	return strings.Contains(target.Address, redactedSecret) || bytes.Contains(target.Settings, []byte(redactedSecret))
+}
+
+// parseMailtoURL parses mailto://alice@example.com and mailto:alice@example.com. Email is sent with the configured mail
+// provider, credentials in the URL aren't supported.
+func parseMailtoURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Opaque != "" {
+		return &Target{Channel: ChannelEmail, Address: u.Opaque}, nil
+	}
+	if u.User == nil {
+		return nil, errors.New("expected mailto://<address>")
+	}
+	if _, ok := u.User.Password(); ok {
+		return nil, errors.New("credentials aren't supported")
+	}
+
+	return &Target{Channel: ChannelEmail, Address: u.User.Username() + "@" + u.Host}, nil
+}
+
+// parseDiscordURL parses discord://<webhook id>/<webhook token>.
+func parseDiscordURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	token := strings.Trim(u.Path, "/")
+	if u.Host == "" || token == "" || strings.Contains(token, "/") {
+		return nil, errors.New("expected discord://<webhook id>/<webhook token>")
+	}
+
+	return &Target{
+		Channel: ChannelDiscord,
+		Address: "https://discord.com/api/webhooks/" + u.Host + "/" + token,
+	}, nil
+}
+
+// parseSlackURL parses slack://<token a>/<token b>/<token c>, the path of the incoming webhook URL.
+func parseSlackURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+		return nil, errors.New("expected slack://<token a>/<token b>/<token c>")
+	}
+
+	return &Target{
+		Channel: ChannelSlack,
+		Address: "https://hooks.slack.com/services/" + u.Host + "/" + tokens[0] + "/" + tokens[1],
+	}, nil
+}
+
+// parseTelegramURL parses tgram://<bot token>/<chat id>. The token contains a colon, which url.Parse would take for
+// the start of a port.
+func parseTelegramURL(raw string) (*Target, error) {
+	_, rest, _ := strings.Cut(raw, "://")
+	rest, _, _ = strings.Cut(rest, "?")
+
+	token, chatID, ok := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	if !ok || token == "" || chatID == "" || strings.Contains(chatID, "/") {
+		return nil, errors.New("expected tgram://<bot token>/<chat id>")
+	}
+
+	return &Target{
+		Channel: ChannelTelegram,
+		Address: token + "/" + chatID,
+	}, nil
+}
+
+// parseNtfyURL parses ntfy://<topic> for topics on ntfy.sh and ntfy://[user:password@]<host>/<topic> for other
+// servers, ntfys:// for servers that use https. The query takes mode, priority, tags and token.
+func parseNtfyURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if u.Scheme == "ntfys" {
+		scheme = "https"
+	}
+
+	host, topic := u.Host, strings.Trim(u.Path, "/")
+	if topic == "" {
+		scheme, host, topic = "https", "ntfy.sh", host
+	}
+	if topic == "" {
+		return nil, errors.New("expected ntfy://<topic> or ntfy://<host>/<topic>")
+	}
+
+	settings := make(map[string]any)
+	if u.User != nil {
+		settings["username"] = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			settings["password"] = password
+		}
+	}
+
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "mode", "token":
+			settings[key] = value
+		case "priority":
+			priority, err := ntfyPriority(value)
+			if err != nil {
+				return nil, err
+			}
+			settings[key] = priority
+		case "tags":
+			settings[key] = strings.Split(value, ",")
+		default:
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+
+	return targetWithSettings(ChannelNtfy, scheme+"://"+host+"/"+topic, settings)
+}
+
+// ntfyPriority takes both the numbers and the names of priorities.
+func ntfyPriority(value string) (int, error) {
+	switch value {
+	case "min":
+		return 1, nil
+	case "low":
+		return 2, nil
+	case "default":
+		return 3, nil
+	case "high":
+		return 4, nil
+	case "max", "urgent":
+		return 5, nil
+	}
+
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid priority %q", value)
+	}
+	return priority, nil
+}
+
+func ntfyURL(target *Target) string {
+	server, topic, err := splitNtfyTopicURL(target.Address)
+	if err != nil {
+		return ""
+	}
+	settings, err := parseNtfySettings(target.Settings)
+	if err != nil {
+		return ""
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return ""
+	}
+
+	q := url.Values{}
+	if settings.Mode != "" {
+		q.Set("mode", settings.Mode)
+	}
+	if settings.Priority != 0 {
+		q.Set("priority", strconv.Itoa(settings.Priority))
+	}
+	if len(settings.Tags) > 0 {
+		q.Set("tags", strings.Join(settings.Tags, ","))
+	}
+	if settings.Token != "" {
+		q.Set("token", settings.Token)
+	}
+
+	if u.Scheme == "https" && u.Host == "ntfy.sh" && settings.Username == "" {
+		return (&url.URL{Scheme: "ntfy", Host: topic, RawQuery: q.Encode()}).String()
+	}
+
+	u.Scheme = "ntfy"
+	if strings.HasPrefix(server, "https") {
+		u.Scheme = "ntfys"
+	}
+	if settings.Username != "" {
+		u.User = url.UserPassword(settings.Username, settings.Password)
+	}
+	u.Path += topic
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// parseJSONURL parses json://<host>/<path> and jsons://<host>/<path> for webhooks. The secret, timeout and retries
+// parameters are taken as settings, the rest of the query is kept for the endpoint.
+func parseJSONURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("expected json://<host>/<path>")
+	}
+
+	settings := make(map[string]any)
+	q := u.Query()
+	if q.Has("secret") {
+		settings["secret"] = q.Get("secret")
+		q.Del("secret")
+	}
+	for param, key := range map[string]string{"timeout": "timeoutSeconds", "retries": "maxRetries"} {
+		if !q.Has(param) {
+			continue
+		}
+		n, err := strconv.Atoi(q.Get(param))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", param, q.Get(param))
+		}
+		settings[key] = n
+		q.Del(param)
+	}
+
+	endpoint := *u
+	endpoint.Scheme = "http"
+	if u.Scheme == "jsons" {
+		endpoint.Scheme = "https"
+	}
+	endpoint.RawQuery = q.Encode()
+
+	return targetWithSettings(ChannelWebhook, endpoint.String(), settings)
+}
+
+func jsonURL(target *Target) string {
+	u, err := url.Parse(target.Address)
+	if err != nil {
+		return ""
+	}
+
+	var settings webhookSettings
+	if err = decodeSettings(target.Settings, &settings); err != nil {
+		return ""
+	}
+
+	u.Scheme = "json"
+	if strings.HasPrefix(target.Address, "https") {
+		u.Scheme = "jsons"
+	}
+
+	q := u.Query()
+	if settings.Secret != "" {
+		q.Set("secret", settings.Secret)
+	}
+	if settings.TimeoutSeconds != 0 {
+		q.Set("timeout", strconv.Itoa(settings.TimeoutSeconds))
+	}
+	if settings.MaxRetries != 0 {
+		q.Set("retries", strconv.Itoa(settings.MaxRetries))
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// parseURL parses raw without repeating it in the error, it may contain secrets.
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if urlErr, ok := errors.AsType[*url.Error](err); ok {
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	return u, nil
+}
+
+// parsePushoverURL parses pover://<user key>@<application token>. The query takes mode, priority, url and device.
+func parsePushoverURL(raw string) (*Target, error) {
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, errors.New("expected pover://<user key>@<application token>")
+	}
+
+	settings := map[string]any{"token": u.Host}
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "mode", "url", "device":
+			settings[key] = value
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid priority %q", value)
+			}
+			settings[key] = priority
+		default:
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+
+	return targetWithSettings(ChannelPushover, u.User.Username(), settings)
+}
+
+func pushoverURL(target *Target) string {
+	var settings pushoverSettings
+	if err := decodeSettings(target.Settings, &settings); err != nil {
+		return ""
+	}
+
+	q := url.Values{}
+	if settings.Mode != "" {
+		q.Set("mode", settings.Mode)
+	}
+	if settings.Priority != 0 {
+		q.Set("priority", strconv.Itoa(settings.Priority))
+	}
+	if settings.URL != "" {
+		q.Set("url", settings.URL)
+	}
+	if settings.Device != "" {
+		q.Set("device", settings.Device)
+	}
+
+	return (&url.URL{
+		Scheme:   "pover",
+		User:     url.User(target.Address),
+		Host:     settings.Token,
+		RawQuery: q.Encode(),
+	}).String()
+}
+
+func targetWithSettings(channel, address string, settings map[string]any) (*Target, error) {
+	target := &Target{Channel: channel, Address: address}
+	if len(settings) > 0 {
+		raw, err := json.Marshal(settings)
+		if err != nil {
+			return nil, err
+		}
+		target.Settings = raw
+	}
+
+	return target, nil
+}
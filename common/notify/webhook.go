@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Headers of a webhook request. The signature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// secret of the recipient, the delivery ID is the same for every attempt to send a digest.
+const (
+	webhookSignatureHeader = "X-RPN-Signature"
+	webhookTimestampHeader = "X-RPN-Timestamp"
+	webhookDeliveryHeader  = "X-RPN-Delivery"
+)
+
+const (
+	webhookMinSecretLength  = 16
+	webhookDefaultTimeout   = 10
+	webhookMaxTimeout       = 30
+	webhookMaxRetries       = 3
+	webhookMaxRetryAfter    = 10 * time.Second
+	webhookInitialRetryWait = time.Second
+)
+
+type (
+	webhookNotifier struct {
+		client *webhookClient
+		now    func() time.Time
+	}
+
+	// webhookSettings are the settings of a webhook recipient.
+	webhookSettings struct {
+		Secret string `json:"secret"`
+		// TimeoutSeconds limits a single request, 10 seconds if unset.
+		TimeoutSeconds int `json:"timeoutSeconds"`
+		// MaxRetries is the number of immediate retries after a transient failure. Anything the endpoint can't take
+		// within them is left to the activity retry.
+		MaxRetries int `json:"maxRetries"`
+	}
+
+	webhookPayload struct {
+		// ID identifies the delivery, receivers deduplicate on it.
+		ID         string             `json:"id"`
+		ScheduleID uuid.UUID          `json:"scheduleID"`
+		Keyword    string             `json:"keyword"`
+		Posts      []persistence.Post `json:"posts"`
+		SentAt     time.Time          `json:"sentAt"`
+	}
+)
+
+func newWebhookNotifier() *webhookNotifier {
+	return &webhookNotifier{
+		client: newPublicWebhookClient(ChannelWebhook),
+		now:    time.Now,
+	}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelWebhook,
+		}
+
+		settings, err := parseWebhookSettings(target.Settings)
+		if err != nil {
+			result.Err = &Error{Channel: ChannelWebhook, Message: err.Error()}
+		} else {
+			result.MessageID, result.Err = w.send(ctx, digest, target, settings)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send posts the digest and retries transient failures, i.e. network errors, 5xx and 408/425/429, up to MaxRetries
+// times with exponential backoff or the delay the endpoint asked for. 4xx responses are permanent and returned at once.
+func (w *webhookNotifier) send(ctx context.Context, digest *Digest, target *Target, settings *webhookSettings) (string, error) {
+	posts := digest.Posts
+	if posts == nil {
+		posts = []persistence.Post{}
+	}
+
+	id := digest.idempotencyKey(target)
+	body, err := json.Marshal(&webhookPayload{
+		ID:         id,
+		ScheduleID: digest.ScheduleID,
+		Keyword:    digest.Keyword,
+		Posts:      posts,
+		SentAt:     w.now().UTC(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: marshal payload: %w", ChannelWebhook, err)
+	}
+
+	timeout := time.Duration(settings.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = webhookDefaultTimeout * time.Second
+	}
+
+	wait := webhookInitialRetryWait
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, target.Address, id, settings.Secret, body, timeout)
+		if err == nil {
+			return id, nil
+		}
+
+		failure, ok := errors.AsType[*Error](err)
+		if !ok || !failure.Retryable || attempt >= settings.MaxRetries || failure.RetryAfter > webhookMaxRetryAfter {
+			return "", err
+		}
+
+		delay := max(failure.RetryAfter, wait)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay+timeout {
+			return "", err
+		}
+		if err = sleep(ctx, delay); err != nil {
+			return "", err
+		}
+		wait *= 2
+	}
+}
+
+// post sends a single signed request. Every attempt is signed with a fresh timestamp, so receivers can reject stale
+// requests.
+func (w *webhookNotifier) post(ctx context.Context, endpoint, id, secret string, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	header := http.Header{}
+	header.Set(webhookDeliveryHeader, id)
+	header.Set(webhookTimestampHeader, timestamp)
+	header.Set(webhookSignatureHeader, "sha256="+signWebhook(secret, timestamp, body))
+
+	_, err := w.client.post(ctx, endpoint, header, json.RawMessage(body), nil)
+
+	// The endpoint is arbitrary, its response body has no place in the delivery log.
+	if failure, ok := errors.AsType[*Error](err); ok && failure.StatusCode != 0 {
+		failure.Message = http.StatusText(failure.StatusCode)
+	}
+	return err
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func parseWebhookSettings(raw json.RawMessage) (*webhookSettings, error) {
+	var settings webhookSettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid webhook settings: %w", err)
+	}
+
+	if len(settings.Secret) < webhookMinSecretLength {
+		return nil, fmt.Errorf("invalid webhook settings: secret must be at least %d characters", webhookMinSecretLength)
+	}
+	if settings.TimeoutSeconds < 0 || settings.TimeoutSeconds > webhookMaxTimeout {
+		return nil, fmt.Errorf("invalid webhook settings: timeoutSeconds must be between 1 and %d, or 0 for "+
+			"the default of %d", webhookMaxTimeout, webhookDefaultTimeout)
+	}
+	if settings.MaxRetries < 0 || settings.MaxRetries > webhookMaxRetries {
+		return nil, fmt.Errorf("invalid webhook settings: maxRetries must be between 0 and %d", webhookMaxRetries)
+	}
+
+	return &settings, nil
+}
+
+func validateWebhookTarget(target *Target) error {
+	u, err := url.Parse(target.Address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("invalid webhook url: expected an http or https url")
+	}
+
+	_, err = parseWebhookSettings(target.Settings)
+	return err
+}
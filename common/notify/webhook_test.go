@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWebhookSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{name: "secret only", settings: `{"secret":"0123456789abcdef"}`},
+		{name: "all settings", settings: `{"secret":"0123456789abcdef","timeoutSeconds":30,"maxRetries":3}`},
+		{name: "default timeout", settings: `{"secret":"0123456789abcdef","timeoutSeconds":0}`},
+		{name: "short secret", settings: `{"secret":"0123456789"}`, wantErr: true},
+		{name: "negative timeout", settings: `{"secret":"0123456789abcdef","timeoutSeconds":-1}`, wantErr: true},
+		{name: "long timeout", settings: `{"secret":"0123456789abcdef","timeoutSeconds":31}`, wantErr: true},
+		{name: "too many retries", settings: `{"secret":"0123456789abcdef","maxRetries":4}`, wantErr: true},
+		{name: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw json.RawMessage
+			if tt.settings != "" {
+				raw = json.RawMessage(tt.settings)
+			}
+
+			_, err := parseWebhookSettings(raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseWebhookSettings() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierSend(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		statuses []int
+		requests int
+		wantErr  bool
+	}{
+		{name: "delivered", statuses: []int{http.StatusNoContent}, requests: 1},
+		{name: "retried", statuses: []int{http.StatusBadGateway, http.StatusOK}, requests: 2},
+		{name: "rejected", statuses: []int{http.StatusUnauthorized}, requests: 1, wantErr: true},
+		{name: "retries exhausted", statuses: []int{http.StatusBadGateway, http.StatusBadGateway}, requests: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				body, _ := io.ReadAll(r.Body)
+				timestamp := r.Header.Get(webhookTimestampHeader)
+				if got := r.Header.Get(webhookSignatureHeader); got != "sha256="+signWebhook(secret, timestamp, body) {
+					t.Errorf("invalid signature %q", got)
+				}
+				if r.Header.Get(webhookDeliveryHeader) == "" {
+					t.Error("missing delivery ID")
+				}
+
+				w.WriteHeader(tt.statuses[requests-1])
+				_, _ = w.Write([]byte("internal details of the endpoint"))
+			}))
+			t.Cleanup(srv.Close)
+
+			notifier := newWebhookNotifier()
+			// The public client refuses the loopback address of the test server.
+			notifier.client = newWebhookClient(ChannelWebhook)
+			notifier.now = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+
+			id, err := notifier.send(context.Background(), &Digest{Keyword: "example", Posts: testPosts(1)},
+				&Target{Channel: ChannelWebhook, Address: srv.URL}, &webhookSettings{Secret: secret, MaxRetries: 1})
+			if tt.wantErr {
+				target, ok := errors.AsType[*Error](err)
+				if !ok {
+					t.Fatalf("expected *Error, got %v", err)
+				}
+				if strings.Contains(target.Message, "internal details") {
+					t.Errorf("error contains the response body: %q", target.Message)
+				}
+			} else if err != nil || id == "" {
+				t.Errorf("send() = %q, %v", id, err)
+			}
+
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
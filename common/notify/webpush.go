@@ -0,0 +1,414 @@
+package notify
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// webPushTTL is how long push services keep a message for a browser that is offline.
+	webPushTTL = 24 * time.Hour
+	// webPushMaxMessageBytes leaves room for the rest of the payload and the encryption overhead within the 4096
+	// bytes every push service accepts.
+	webPushMaxMessageBytes = 2048
+	// webPushRecordSize is the record size of the aes128gcm encoding, the payload always fits into one record.
+	webPushRecordSize = 4096
+	// vapidTokenLifetime is the validity of the JWT sent with every message, push services reject more than 24
+	// hours.
+	vapidTokenLifetime = 12 * time.Hour
+)
+
+var base64URL = base64.RawURLEncoding
+
+type (
+	webPushNotifier struct {
+		client *webhookClient
+		// vapid is nil while Web Push isn't configured.
+		vapid *VAPID
+	}
+
+	// VAPID identifies the application server to push services, see RFC 8292. Browsers only accept messages signed
+	// with the key they subscribed with.
+	VAPID struct {
+		key       *ecdsa.PrivateKey
+		publicKey string
+		subject   string
+	}
+
+	// webPushSettings are the keys of a browser's push subscription as returned by PushSubscription.toJSON(), the
+	// address is its endpoint.
+	webPushSettings struct {
+		Mode string `json:"mode"`
+		// P256DH is the public key of the browser, base64url encoded.
+		P256DH string `json:"p256dh"`
+		// Auth is the authentication secret of the subscription, base64url encoded.
+		Auth string `json:"auth"`
+	}
+
+	// webPushMessage is the payload the service worker receives, it shows it with showNotification.
+	webPushMessage struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		// URL is opened when the notification is clicked.
+		URL   string `json:"url,omitempty"`
+		Image string `json:"image,omitempty"`
+		// Tag replaces the previous summary of the same schedule instead of stacking them.
+		Tag       string `json:"tag"`
+		Timestamp int64  `json:"timestamp"`
+	}
+)
+
+func newWebPushNotifier(vapid *VAPID) *webPushNotifier {
+	return &webPushNotifier{
+		client: newWebhookClient(ChannelWebPush),
+		vapid:  vapid,
+	}
+}
+
+// GenerateVAPIDKeys generates a P-256 key pair for Web Push, base64url encoded as expected by NewVAPID and by the
+// applicationServerKey of PushManager.subscribe().
+func GenerateVAPIDKeys() (publicKey string, privateKey string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	private, err := key.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64URL.EncodeToString(public), base64URL.EncodeToString(private), nil
+}
+
+// NewVAPID parses a key pair generated by GenerateVAPIDKeys. The public key has to belong to the private key, a
+// mismatch would only show once browsers reject every message.
+func NewVAPID(publicKey, privateKey, subject string) (*VAPID, error) {
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	if encoded := base64URL.EncodeToString(public); encoded != strings.TrimRight(publicKey, "=") {
+		return nil, errors.New("invalid vapid public key: doesn't belong to the private key")
+	}
+
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https://") {
+		return nil, errors.New("invalid vapid subject: expected a mailto: or https: url")
+	}
+
+	return &VAPID{
+		key:       key,
+		publicKey: base64URL.EncodeToString(public),
+		subject:   subject,
+	}, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with.
+func (v *VAPID) PublicKey() string {
+	return v.publicKey
+}
+
+// authorization returns the Authorization header for a message to endpoint, RFC 8292 section 3.
+func (v *VAPID) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTokenLifetime).Unix(),
+		"sub": v.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64URL.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`)) + "." + base64URL.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	r, s, err := ecdsa.Sign(rand.Reader, v.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// JWS wants the signature as the fixed size concatenation of r and s, not ASN.1.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return "vapid t=" + unsigned + "." + base64URL.EncodeToString(signature) + ", k=" + v.publicKey, nil
+}
+
+func (w *webPushNotifier) Notify(ctx context.Context, digest *Digest, targets []*Target) []*Result {
+	results := make([]*Result, 0, len(targets))
+	for _, target := range targets {
+		result := &Result{
+			Target:   target,
+			Provider: ChannelWebPush,
+		}
+		result.MessageID, result.Err = w.send(ctx, digest, target)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// send pushes a message per notification of the digest and returns the location of the first one, which is all push
+// services tell about a message.
+func (w *webPushNotifier) send(ctx context.Context, digest *Digest, target *Target) (string, error) {
+	if w.vapid == nil {
+		return "", &Error{Channel: ChannelWebPush, Message: "web push is not configured"}
+	}
+
+	settings, err := parseWebPushSettings(target.Settings)
+	if err != nil {
+		return "", &Error{Channel: ChannelWebPush, Message: err.Error()}
+	}
+	if err = validateWebPushEndpoint(target.Address); err != nil {
+		return "", &Error{Channel: ChannelWebPush, Message: err.Error()}
+	}
+
+	// Both were validated above.
+	p256dh, _ := decodeBase64URL(settings.P256DH)
+	auth, _ := decodeBase64URL(settings.Auth)
+
+	authorization, err := w.vapid.authorization(target.Address, time.Now())
+	if err != nil {
+		return "", &Error{Channel: ChannelWebPush, Message: err.Error()}
+	}
+
+	notifications := pushNotifications(digest, settings.Mode, false, webPushMaxMessageBytes)
+
+	header := http.Header{}
+	header.Set("Authorization", authorization)
+	header.Set("Content-Encoding", "aes128gcm")
+	header.Set("TTL", strconv.Itoa(int(webPushTTL.Seconds())))
+	header.Set("Urgency", "normal")
+	if len(notifications) == 1 {
+		// A summary that wasn't delivered yet is replaced by the next one. The topic is at most 32 characters
+		// of the base64url alphabet, the schedule ID without dashes fits exactly.
+		header.Set("Topic", strings.ReplaceAll(digest.ScheduleID.String(), "-", ""))
+	}
+
+	var firstID string
+	for _, notification := range notifications {
+		msg := &webPushMessage{
+			Title:     notification.Title,
+			Body:      notification.Message,
+			URL:       notification.Click,
+			Image:     notification.Image,
+			Tag:       "rpn-" + digest.ScheduleID.String(),
+			Timestamp: time.Now().UnixMilli(),
+		}
+		if notification.Click != "" {
+			msg.Tag = notification.Click
+		}
+
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return "", &Error{Channel: ChannelWebPush, Message: err.Error()}
+		}
+
+		body, err := encryptWebPush(payload, p256dh, auth)
+		if err != nil {
+			return "", &Error{Channel: ChannelWebPush, Message: err.Error()}
+		}
+
+		res, err := w.client.send(ctx, http.MethodPost, target.Address, header, "application/octet-stream", body, nil)
+		if err != nil {
+			return "", webPushErrorFrom(err)
+		}
+
+		if firstID == "" {
+			firstID = res.Get("Location")
+		}
+	}
+
+	return firstID, nil
+}
+
+// webPushErrorFrom marks the subscription as gone if the push service doesn't know it anymore. Browsers drop
+// subscriptions when the user revokes the permission or the service worker is unregistered.
+func webPushErrorFrom(err error) error {
+	target, ok := errors.AsType[*Error](err)
+	if !ok {
+		return err
+	}
+
+	if target.StatusCode == http.StatusNotFound || target.StatusCode == http.StatusGone {
+		target.Gone, target.Retryable = true, false
+	}
+
+	return target
+}
+
+// encryptWebPush encrypts payload for the browser as a single aes128gcm record, RFC 8291 section 3 and RFC 8188.
+func encryptWebPush(payload, p256dh, auth []byte) ([]byte, error) {
+	if len(payload)+1+16 > webPushRecordSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds the record size", len(payload))
+	}
+
+	// Every message is encrypted with a new key pair and salt.
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return sealWebPush(payload, p256dh, auth, asPrivate, salt)
+}
+
+// sealWebPush is encryptWebPush with the key pair and salt of the sender given.
+func sealWebPush(payload, p256dh, auth []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	secret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(p256dh) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, secret, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header carries the salt, the record size and the public key of the sender as key ID. The 0x02 delimiter
+	// marks the last and only record, there's no padding.
+	out := make([]byte, 0, 16+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	out = append(out, salt...)
+	out = binary.BigEndian.AppendUint32(out, webPushRecordSize)
+	out = append(out, byte(len(asPublic)))
+	out = append(out, asPublic...)
+	out = gcm.Seal(out, nonce, append(payload, 0x02), nil)
+
+	return out, nil
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64URL.DecodeString(strings.TrimRight(s, "="))
+}
+
+func parseWebPushSettings(raw json.RawMessage) (*webPushSettings, error) {
+	var settings webPushSettings
+	if err := decodeSettings(raw, &settings); err != nil {
+		return nil, fmt.Errorf("invalid web push settings: %w", err)
+	}
+
+	if err := validatePushMode(settings.Mode); err != nil {
+		return nil, fmt.Errorf("invalid web push settings: %w", err)
+	}
+
+	p256dh, err := decodeBase64URL(settings.P256DH)
+	if err != nil {
+		return nil, errors.New("invalid web push settings: p256dh must be base64url encoded")
+	}
+	if _, err = ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return nil, errors.New("invalid web push settings: p256dh must be an uncompressed P-256 public key")
+	}
+
+	auth, err := decodeBase64URL(settings.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, errors.New("invalid web push settings: auth must be a base64url encoded 16 byte secret")
+	}
+
+	return &settings, nil
+}
+
+// validateWebPushEndpoint accepts the https endpoints of push services. Plain http is only accepted on the loopback
+// interface, for a local push service stub.
+func validateWebPushEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid web push endpoint: %w", err)
+	}
+	if u.Host == "" {
+		return errors.New("invalid web push endpoint: expected an https url")
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if u.Hostname() == "localhost" {
+			return nil
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+
+	return errors.New("invalid web push endpoint: expected an https url")
+}
+
+func validateWebPushTarget(target *Target) error {
+	if err := validateWebPushEndpoint(target.Address); err != nil {
+		return err
+	}
+
+	_, err := parseWebPushSettings(target.Settings)
+	return err
+}
@@ -0,0 +1,284 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example of RFC 8291 Appendix A.
+const (
+	rfc8291Plaintext = "V2hlbiBJIGdyb3cgdXAsIEkgd2FudCB0byBiZSBhIHdhdGVybWVsb24"
+	rfc8291ASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfc8291UAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfc8291UAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfc8291Salt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfc8291Auth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfc8291Message   = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func TestSealWebPushRFC8291(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecodeBase64URL(t, rfc8291ASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := sealWebPush(
+		mustDecodeBase64URL(t, rfc8291Plaintext),
+		mustDecodeBase64URL(t, rfc8291UAPublic),
+		mustDecodeBase64URL(t, rfc8291Auth),
+		asPrivate,
+		mustDecodeBase64URL(t, rfc8291Salt),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := base64URL.EncodeToString(out); got != rfc8291Message {
+		t.Errorf("message = %s, want %s", got, rfc8291Message)
+	}
+}
+
+func TestEncryptWebPush(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecodeBase64URL(t, rfc8291UAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := mustDecodeBase64URL(t, rfc8291Auth)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		wantErr bool
+	}{
+		{name: "empty", payload: []byte{}},
+		{name: "message", payload: []byte(`{"title":"3 new posts for example"}`)},
+		{name: "largest", payload: bytes.Repeat([]byte("a"), webPushRecordSize-17)},
+		{name: "too large", payload: bytes.Repeat([]byte("a"), webPushRecordSize-16), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := encryptWebPush(tt.payload, uaPrivate.PublicKey().Bytes(), auth)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plaintext, err := decryptWebPush(out, uaPrivate, auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plaintext, tt.payload) {
+				t.Errorf("decrypted %q, want %q", plaintext, tt.payload)
+			}
+		})
+	}
+}
+
+func TestVAPIDAuthorization(t *testing.T) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vapid, err := NewVAPID(publicKey, privateKey, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	authorization, err := vapid.authorization("https://fcm.googleapis.com/fcm/send/abc:def", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, key, ok := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	if !ok || !strings.HasPrefix(authorization, "vapid t=") {
+		t.Fatalf("unexpected authorization %q", authorization)
+	}
+	if key != publicKey {
+		t.Errorf("k = %q, want %q", key, publicKey)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT, got %q", token)
+	}
+
+	var header struct {
+		Typ string `json:"typ"`
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(mustDecodeBase64URL(t, parts[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Alg != "ES256" || header.Typ != "JWT" {
+		t.Errorf("header = %+v, want ES256 JWT", header)
+	}
+
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err = json.Unmarshal(mustDecodeBase64URL(t, parts[1]), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != "https://fcm.googleapis.com" {
+		t.Errorf("aud = %q, want the origin of the endpoint", claims.Aud)
+	}
+	if claims.Exp != now.Add(vapidTokenLifetime).Unix() {
+		t.Errorf("exp = %d, want %d", claims.Exp, now.Add(vapidTokenLifetime).Unix())
+	}
+	if claims.Sub != "mailto:admin@example.com" {
+		t.Errorf("sub = %q", claims.Sub)
+	}
+
+	// The signature has to verify with the public key browsers subscribed with.
+	public, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), mustDecodeBase64URL(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := mustDecodeBase64URL(t, parts[2])
+	if len(signature) != 64 {
+		t.Fatalf("signature has %d bytes, want 64", len(signature))
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(public, digest[:], r, s) {
+		t.Error("signature doesn't verify")
+	}
+}
+
+func TestNewVAPID(t *testing.T) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublicKey, _, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		publicKey  string
+		privateKey string
+		subject    string
+		wantErr    bool
+	}{
+		{name: "mailto", publicKey: publicKey, privateKey: privateKey, subject: "mailto:admin@example.com"},
+		{name: "https", publicKey: publicKey, privateKey: privateKey, subject: "https://example.com"},
+		{name: "padded", publicKey: publicKey + "=", privateKey: privateKey + "=", subject: "https://example.com"},
+		{name: "mismatched keys", publicKey: otherPublicKey, privateKey: privateKey, subject: "https://example.com", wantErr: true},
+		{name: "invalid private key", publicKey: publicKey, privateKey: "not a key", subject: "https://example.com", wantErr: true},
+		{name: "invalid subject", publicKey: publicKey, privateKey: privateKey, subject: "admin@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vapid, err := NewVAPID(tt.publicKey, tt.privateKey, tt.subject)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vapid.PublicKey() != publicKey {
+				t.Errorf("public key = %q, want %q", vapid.PublicKey(), publicKey)
+			}
+		})
+	}
+}
+
+// decryptWebPush is the browser's side of encryptWebPush, RFC 8291 section 3.4.
+func decryptWebPush(body []byte, uaPrivate *ecdh.PrivateKey, auth []byte) ([]byte, error) {
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	asPublic := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+	if len(ciphertext) > int(recordSize) {
+		return nil, errors.New("record exceeds the record size")
+	}
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := uaPrivate.ECDH(asKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPrivate.PublicKey().Bytes()) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, secret, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strip the padding, the last record ends with the 0x02 delimiter.
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("missing record delimiter")
+	}
+
+	return plaintext[:len(plaintext)-1], nil
+}
+
+func mustDecodeBase64URL(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := decodeBase64URL(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
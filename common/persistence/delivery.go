@@ -0,0 +1,165 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	reserveDeliveryInsertQ = `
+INSERT INTO deliveries (id, configuration_id, idempotency_key, recipient_id, channel, address, post_ids, status)
+VALUES (@id, @configuration_id, @idempotency_key, @recipient_id, @channel, @address, @post_ids, @status)
+ON CONFLICT (idempotency_key, recipient_id) WHERE idempotency_key <> '' DO NOTHING
+`
+	reserveDeliverySelectQ = `
+SELECT id, configuration_id, idempotency_key, recipient_id, channel, address, provider, message_id, post_ids, status, error, created_at
+FROM deliveries
+WHERE idempotency_key = @idempotency_key
+`
+)
+
+// ReserveDeliveries inserts the given deliveries unless a delivery for the same idempotency key and recipient exists
+// already, and returns all deliveries of the key. Callers use the returned status to skip recipients that were served
+// by an earlier attempt.
+func (h *Handle) ReserveDeliveries(ctx context.Context, in *ReserveDeliveriesInput) (*ReserveDeliveriesOutput, error) {
+	tx, err := h.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, d := range in.Deliveries {
+		if _, err = tx.Exec(ctx, reserveDeliveryInsertQ, pgx.NamedArgs{
+			"id":               d.ID,
+			"configuration_id": d.ConfigurationID,
+			"idempotency_key":  in.IdempotencyKey,
+			"recipient_id":     d.RecipientID,
+			"channel":          d.Channel,
+			"address":          d.Address,
+			"post_ids":         d.PostIDs,
+			"status":           d.Status,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(ctx, reserveDeliverySelectQ, pgx.NamedArgs{"idempotency_key": in.IdempotencyKey})
+	if err != nil {
+		return nil, err
+	}
+
+	dbModels, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Delivery])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(dbModels))
+	for _, m := range dbModels {
+		deliveries = append(deliveries, deliveryFromModel(&m))
+	}
+
+	return &ReserveDeliveriesOutput{
+		Deliveries: deliveries,
+	}, nil
+}
+
+// recordDeliveryInsertQ completes a reserved delivery, or inserts it if it was sent without reservation.
+const recordDeliveryInsertQ = `
+INSERT INTO deliveries (id, configuration_id, idempotency_key, recipient_id, channel, address, provider, message_id, post_ids, status, error)
+VALUES (@id, @configuration_id, @idempotency_key, @recipient_id, @channel, @address, @provider, @message_id, @post_ids, @status, @error)
+ON CONFLICT (idempotency_key, recipient_id) WHERE idempotency_key <> '' DO UPDATE SET
+    provider = EXCLUDED.provider,
+    message_id = EXCLUDED.message_id,
+    status = EXCLUDED.status,
+    error = EXCLUDED.error
+`
+
+func (h *Handle) RecordDeliveries(ctx context.Context, in *RecordDeliveriesInput) (*RecordDeliveriesOutput, error) {
+	batch := &pgx.Batch{}
+
+	for _, d := range in.Deliveries {
+		batch.Queue(recordDeliveryInsertQ, pgx.NamedArgs{
+			"id":               d.ID,
+			"configuration_id": d.ConfigurationID,
+			"idempotency_key":  d.IdempotencyKey,
+			"recipient_id":     d.RecipientID,
+			"channel":          d.Channel,
+			"address":          d.Address,
+			"provider":         d.Provider,
+			"message_id":       d.MessageID,
+			"post_ids":         d.PostIDs,
+			"status":           d.Status,
+			"error":            d.Error,
+		})
+	}
+
+	br := h.db.SendBatch(ctx, batch)
+	defer func() {
+		_ = br.Close()
+	}()
+
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			return nil, fmt.Errorf("error in record deliveries batch operation: %w", err)
+		}
+	}
+
+	return &RecordDeliveriesOutput{}, nil
+}
+
+const listDeliveriesSelectQ = `
+SELECT id, configuration_id, idempotency_key, recipient_id, channel, address, provider, message_id, post_ids, status, error, created_at
+FROM deliveries
+WHERE configuration_id = @configuration_id
+ORDER BY created_at DESC, id DESC
+LIMIT @limit
+`
+
+func (h *Handle) ListDeliveries(ctx context.Context, in *ListDeliveriesInput) (*ListDeliveriesOutput, error) {
+	rows, err := h.db.Query(ctx, listDeliveriesSelectQ, pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+		"limit":            in.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dbModels, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Delivery])
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(dbModels))
+	for _, m := range dbModels {
+		deliveries = append(deliveries, deliveryFromModel(&m))
+	}
+
+	return &ListDeliveriesOutput{
+		Deliveries: deliveries,
+	}, nil
+}
+
+func deliveryFromModel(m *models.Delivery) *Delivery {
+	return &Delivery{
+		ID:              m.ID,
+		ConfigurationID: m.ConfigurationID,
+		IdempotencyKey:  m.IdempotencyKey,
+		RecipientID:     m.RecipientID,
+		Channel:         m.Channel,
+		Address:         m.Address,
+		Provider:        m.Provider,
+		MessageID:       m.MessageID,
+		PostIDs:         m.PostIDs,
+		Status:          m.Status,
+		Error:           m.Error,
+		CreatedAt:       m.CreatedAt,
+	}
+}
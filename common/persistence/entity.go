@@ -1,14 +1,20 @@
 package persistence
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"time"
 )
 
 type (
 	Recipient struct {
-		ID      uuid.UUID `json:"id"`
-		Address string    `json:"address"`
+		ID uuid.UUID `json:"id"`
+		// Channel is one of the notify channels, recipients created before channels existed have email.
+		Channel string `json:"channel"`
+		Address string `json:"address"`
+		// Settings are the channel specific options of the recipient as JSON object.
+		Settings json.RawMessage `json:"settings,omitempty"`
 	}
 
 	Subreddit struct {
@@ -50,14 +56,17 @@ type (
 	}
 
 	CreateScheduleRecipient struct {
-		ID      uuid.UUID `json:"id"`
-		Address string    `json:"address"`
+		ID       uuid.UUID       `json:"id"`
+		Channel  string          `json:"channel"`
+		Address  string          `json:"address"`
+		Settings json.RawMessage `json:"settings"`
 	}
 
 	CreateScheduleInput struct {
 		ID         uuid.UUID                  `json:"id"`
 		Keyword    string                     `json:"keyword"`
 		Schedule   string                     `json:"schedule"`
+		FeedToken  string                     `json:"feed_token"`
 		Recipients []*CreateScheduleRecipient `json:"recipients"`
 		Subreddits []*CreateScheduleSubreddit `json:"subreddits"`
 	}
@@ -73,6 +82,7 @@ type (
 		ID         uuid.UUID    `json:"id"`
 		Keyword    string       `json:"keyword"`
 		Schedule   string       `json:"schedule"`
+		FeedToken  string       `json:"feed_token"`
 		Recipients []*Recipient `json:"recipients"`
 		Subreddits []*Subreddit `json:"subreddits,omitempty"`
 	}
@@ -143,9 +153,180 @@ type (
 		Items []QueueItem
 	}
 
+	ClaimPostsInput struct {
+		ConfigurationID uuid.UUID
+		IdempotencyKey  string
+	}
+
+	ClaimPostsOutput struct {
+		Items []QueueItem
+	}
+
 	PopPostsInput struct {
 		ConfigurationID uuid.UUID
+		// IdempotencyKey restricts popping to posts claimed with this key. If empty, all queued posts of the
+		// configuration are popped.
+		IdempotencyKey string
 	}
 
 	PopPostsOutput struct{}
+
+	Delivery struct {
+		ID              uuid.UUID `json:"id"`
+		ConfigurationID uuid.UUID `json:"configuration_id"`
+		IdempotencyKey  string    `json:"idempotency_key,omitempty"`
+		RecipientID     uuid.UUID `json:"recipient_id"`
+		Channel         string    `json:"channel"`
+		Address         string    `json:"address"`
+		Provider        string    `json:"provider"`
+		MessageID       string    `json:"message_id"`
+		PostIDs         []string  `json:"post_ids"`
+		Status          string    `json:"status"`
+		Error           string    `json:"error,omitempty"`
+		CreatedAt       time.Time `json:"created_at"`
+	}
+
+	RecordDeliveriesInput struct {
+		Deliveries []*Delivery
+	}
+
+	RecordDeliveriesOutput struct{}
+
+	ReserveDeliveriesInput struct {
+		IdempotencyKey string
+		Deliveries     []*Delivery
+	}
+
+	ReserveDeliveriesOutput struct {
+		// Deliveries contains every delivery recorded for the idempotency key, including the ones that were
+		// reserved or completed by an earlier attempt.
+		Deliveries []*Delivery
+	}
+
+	ListDeliveriesInput struct {
+		ConfigurationID uuid.UUID
+		Limit           int
+	}
+
+	ListDeliveriesOutput struct {
+		Deliveries []*Delivery
+	}
+
+	SuppressRecipientsInput struct {
+		// Addresses are matched case-insensitively against the email recipients of every schedule.
+		Addresses []string
+		// RecipientIDs are suppressed regardless of their address.
+		RecipientIDs []uuid.UUID
+		Reason       string
+	}
+
+	SuppressRecipientsOutput struct {
+		Suppressed int64
+	}
+
+	AddRecipientInput struct {
+		ID              uuid.UUID
+		ConfigurationID uuid.UUID
+		Channel         string
+		Address         string
+		Settings        json.RawMessage
+		// MaxRecipients is the number of recipients the configuration may have at most.
+		MaxRecipients int
+	}
+
+	AddRecipientOutput struct {
+		// ID is the ID of the existing recipient if the configuration had one with the same channel and address.
+		ID      uuid.UUID
+		Created bool
+	}
+
+	GetFeedInput struct {
+		ConfigurationID uuid.UUID
+		Limit           int
+	}
+
+	GetFeedOutput struct {
+		Keyword   string
+		FeedToken string
+		Posts     []*FeedPost
+	}
+
+	FeedPost struct {
+		Post Post
+		// CreatedTime is when the post was created on Reddit, zero for posts queued before it was stored.
+		CreatedTime time.Time
+		// SentAt is nil while the post is queued.
+		SentAt *time.Time
+	}
+
+	EmailTemplate struct {
+		ID              uuid.UUID
+		ConfigurationID uuid.UUID
+		Name            string
+		// Version counts the uploads of a name per configuration, starting at 1.
+		Version   int
+		Source    string
+		CreatedAt time.Time
+	}
+
+	CreateEmailTemplateInput struct {
+		ID              uuid.UUID
+		ConfigurationID uuid.UUID
+		Name            string
+		Source          string
+	}
+
+	CreateEmailTemplateOutput struct {
+		Version   int
+		CreatedAt time.Time
+	}
+
+	ListEmailTemplatesInput struct {
+		ConfigurationID uuid.UUID
+	}
+
+	ListEmailTemplatesOutput struct {
+		// Templates are ordered by name and version, their Source is empty.
+		Templates []*EmailTemplate
+		// ActiveID is uuid.Nil while the configuration uses the default templates.
+		ActiveID uuid.UUID
+	}
+
+	GetEmailTemplateInput struct {
+		ConfigurationID uuid.UUID
+		ID              uuid.UUID
+	}
+
+	GetEmailTemplateOutput struct {
+		Template *EmailTemplate
+	}
+
+	GetActiveEmailTemplateInput struct {
+		ConfigurationID uuid.UUID
+	}
+
+	GetActiveEmailTemplateOutput struct {
+		// Template is nil while the configuration uses the default templates.
+		Template *EmailTemplate
+	}
+
+	ActivateEmailTemplateInput struct {
+		ConfigurationID uuid.UUID
+		// ID is the version to activate, uuid.Nil restores the default templates.
+		ID uuid.UUID
+	}
+
+	ActivateEmailTemplateOutput struct{}
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrRecipientLimit is returned if a recipient would exceed AddRecipientInput.MaxRecipients.
+	ErrRecipientLimit = errors.New("recipient limit reached")
+)
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSent    = "sent"
+	DeliveryStatusFailed  = "failed"
 )
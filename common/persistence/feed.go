@@ -0,0 +1,80 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	getFeedSelectQ = `SELECT keyword, feed_token FROM configuration WHERE id = @configuration_id`
+
+	// getFeedPostsSelectQ returns the newest queued and sent posts. A post that was matched twice is listed once.
+	getFeedPostsSelectQ = `
+SELECT data, created_time, sent_at
+FROM (
+    SELECT DISTINCT ON (data->>'id') id, data, created_time, sent_at
+    FROM posts
+    WHERE configuration_id = @configuration_id
+    ORDER BY data->>'id', sent_at NULLS LAST
+) p
+ORDER BY created_time DESC NULLS LAST, id DESC
+LIMIT @limit
+`
+)
+
+// GetFeed returns the configuration's feed token and its newest posts, newest first. ErrNotFound is returned if the
+// configuration doesn't exist.
+func (h *Handle) GetFeed(ctx context.Context, in *GetFeedInput) (*GetFeedOutput, error) {
+	args := pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+		"limit":            in.Limit,
+	}
+
+	rows, err := h.db.Query(ctx, getFeedSelectQ, args)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Feed])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err = h.db.Query(ctx, getFeedPostsSelectQ, args)
+	if err != nil {
+		return nil, err
+	}
+
+	dbModels, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.FeedPost])
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]*FeedPost, 0, len(dbModels))
+	for _, m := range dbModels {
+		post := &FeedPost{
+			SentAt: m.SentAt,
+		}
+		if err = json.Unmarshal(m.Data, &post.Post); err != nil {
+			return nil, fmt.Errorf("unmarshal post: %w", err)
+		}
+		if m.CreatedTime != nil {
+			post.CreatedTime = *m.CreatedTime
+		}
+
+		posts = append(posts, post)
+	}
+
+	return &GetFeedOutput{
+		Keyword:   feed.Keyword,
+		FeedToken: feed.FeedToken,
+		Posts:     posts,
+	}, nil
+}
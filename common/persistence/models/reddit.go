@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"github.com/google/uuid"
+	"time"
 )
 
 type (
@@ -13,16 +14,19 @@ type (
 	}
 
 	GetSchedule struct {
-		ID                uuid.UUID `db:"id"`
-		SubredditID       uuid.UUID `db:"subreddit_id"`
-		Subreddit         string    `db:"subreddit"`
-		IncludeNSFW       bool      `db:"include_nsfw"`
-		Sort              string    `db:"sort"`
-		RestrictSubreddit bool      `db:"restrict_subreddit"`
-		Keyword           string    `db:"keyword"`
-		Schedule          string    `db:"schedule"`
-		RecipientID       uuid.UUID `db:"recipient_id"`
-		Address           string    `db:"address"`
+		ID                uuid.UUID       `db:"id"`
+		SubredditID       uuid.UUID       `db:"subreddit_id"`
+		Subreddit         string          `db:"subreddit"`
+		IncludeNSFW       bool            `db:"include_nsfw"`
+		Sort              string          `db:"sort"`
+		RestrictSubreddit bool            `db:"restrict_subreddit"`
+		Keyword           string          `db:"keyword"`
+		Schedule          string          `db:"schedule"`
+		FeedToken         string          `db:"feed_token"`
+		RecipientID       uuid.UUID       `db:"recipient_id"`
+		Channel           string          `db:"channel"`
+		Address           string          `db:"address"`
+		Settings          json.RawMessage `db:"settings"`
 	}
 
 	ListSchedulesModel struct {
@@ -32,4 +36,39 @@ type (
 		Subreddits json.RawMessage `db:"subreddits"`
 		Recipients json.RawMessage `db:"recipients"`
 	}
+
+	Feed struct {
+		Keyword   string `db:"keyword"`
+		FeedToken string `db:"feed_token"`
+	}
+
+	FeedPost struct {
+		Data        []byte     `db:"data"`
+		CreatedTime *time.Time `db:"created_time"`
+		SentAt      *time.Time `db:"sent_at"`
+	}
+
+	EmailTemplate struct {
+		ID              uuid.UUID `db:"id"`
+		ConfigurationID uuid.UUID `db:"configuration_id"`
+		Name            string    `db:"name"`
+		Version         int       `db:"version"`
+		Source          string    `db:"source"`
+		CreatedAt       time.Time `db:"created_at"`
+	}
+
+	Delivery struct {
+		ID              uuid.UUID `db:"id"`
+		ConfigurationID uuid.UUID `db:"configuration_id"`
+		IdempotencyKey  string    `db:"idempotency_key"`
+		RecipientID     uuid.UUID `db:"recipient_id"`
+		Channel         string    `db:"channel"`
+		Address         string    `db:"address"`
+		Provider        string    `db:"provider"`
+		MessageID       string    `db:"message_id"`
+		PostIDs         []string  `db:"post_ids"`
+		Status          string    `db:"status"`
+		Error           string    `db:"error"`
+		CreatedAt       time.Time `db:"created_at"`
+	}
 )
@@ -16,5 +16,17 @@ type Persistence interface {
 	UpdateSchedule(ctx context.Context, in *UpdateScheduleInput) (*UpdateScheduleOutput, error)
 	QueuePosts(ctx context.Context, in *QueuePostsInput) (*QueuePostsOutput, error)
 	GetPosts(ctx context.Context, in *GetPostsInput) (*GetPostsOutput, error)
+	ClaimPosts(ctx context.Context, in *ClaimPostsInput) (*ClaimPostsOutput, error)
 	PopPosts(ctx context.Context, in *PopPostsInput) (*PopPostsOutput, error)
+	ReserveDeliveries(ctx context.Context, in *ReserveDeliveriesInput) (*ReserveDeliveriesOutput, error)
+	RecordDeliveries(ctx context.Context, in *RecordDeliveriesInput) (*RecordDeliveriesOutput, error)
+	ListDeliveries(ctx context.Context, in *ListDeliveriesInput) (*ListDeliveriesOutput, error)
+	SuppressRecipients(ctx context.Context, in *SuppressRecipientsInput) (*SuppressRecipientsOutput, error)
+	GetFeed(ctx context.Context, in *GetFeedInput) (*GetFeedOutput, error)
+	AddRecipient(ctx context.Context, in *AddRecipientInput) (*AddRecipientOutput, error)
+	CreateEmailTemplate(ctx context.Context, in *CreateEmailTemplateInput) (*CreateEmailTemplateOutput, error)
+	ListEmailTemplates(ctx context.Context, in *ListEmailTemplatesInput) (*ListEmailTemplatesOutput, error)
+	GetEmailTemplate(ctx context.Context, in *GetEmailTemplateInput) (*GetEmailTemplateOutput, error)
+	GetActiveEmailTemplate(ctx context.Context, in *GetActiveEmailTemplateInput) (*GetActiveEmailTemplateOutput, error)
+	ActivateEmailTemplate(ctx context.Context, in *ActivateEmailTemplateInput) (*ActivateEmailTemplateOutput, error)
 }
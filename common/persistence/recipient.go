@@ -0,0 +1,110 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"strings"
+)
+
+const suppressRecipientsUpdateQ = `
+UPDATE recipients
+SET suppressed_at = CURRENT_TIMESTAMP, suppression_reason = @reason
+WHERE ((channel = 'email' AND lower(address) = ANY(@addresses)) OR id = ANY(@recipient_ids))
+AND suppressed_at IS NULL
+`
+
+const (
+	// addRecipientCountQ locks the configuration, so concurrent additions can't exceed the limit together.
+	addRecipientCountQ = `
+SELECT (SELECT count(*) FROM recipients WHERE configuration_id = c.id)
+FROM configuration c
+WHERE c.id = @configuration_id
+FOR UPDATE
+`
+
+	addRecipientUpdateQ = `
+UPDATE recipients
+SET settings = @settings, suppressed_at = NULL, suppression_reason = ''
+WHERE configuration_id = @configuration_id AND channel = @channel AND address = @address
+RETURNING id
+`
+
+	addRecipientInsertQ = `
+INSERT INTO recipients (id, configuration_id, channel, address, settings)
+VALUES (@id, @configuration_id, @channel, @address, @settings)
+`
+)
+
+// SuppressRecipients marks every email recipient with one of the given addresses and every recipient with one of the
+// given IDs as suppressed. Recipients that are suppressed already keep their original reason.
+func (h *Handle) SuppressRecipients(ctx context.Context, in *SuppressRecipientsInput) (*SuppressRecipientsOutput, error) {
+	addresses := make([]string, 0, len(in.Addresses))
+	for _, address := range in.Addresses {
+		addresses = append(addresses, strings.ToLower(address))
+	}
+
+	tag, err := h.db.Exec(ctx, suppressRecipientsUpdateQ, pgx.NamedArgs{
+		"addresses":     addresses,
+		"recipient_ids": in.RecipientIDs,
+		"reason":        in.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SuppressRecipientsOutput{
+		Suppressed: tag.RowsAffected(),
+	}, nil
+}
+
+// AddRecipient adds a recipient to a configuration. A recipient with the same channel and address is updated instead,
+// its settings are replaced and a suppression is lifted. ErrNotFound is returned if the configuration doesn't exist.
+func (h *Handle) AddRecipient(ctx context.Context, in *AddRecipientInput) (*AddRecipientOutput, error) {
+	tx, err := h.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	args := pgx.NamedArgs{
+		"id":               in.ID,
+		"configuration_id": in.ConfigurationID,
+		"channel":          in.Channel,
+		"address":          in.Address,
+		"settings":         in.Settings,
+	}
+
+	var count int
+	if err = tx.QueryRow(ctx, addRecipientCountQ, args).Scan(&count); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	out := &AddRecipientOutput{}
+
+	err = tx.QueryRow(ctx, addRecipientUpdateQ, args).Scan(&out.ID)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		if count >= in.MaxRecipients {
+			return nil, ErrRecipientLimit
+		}
+		if _, err = tx.Exec(ctx, addRecipientInsertQ, args); err != nil {
+			return nil, err
+		}
+		out.ID, out.Created = in.ID, true
+	default:
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
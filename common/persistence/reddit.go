@@ -31,9 +31,10 @@ SELECT
     (
         SELECT COALESCE(jsonb_agg(r), '[]')
         FROM (
-            SELECT r.id, r.address
+            SELECT r.id, r.channel, r.address, r.settings
             FROM recipients r
             WHERE r.configuration_id = c.id
+            AND r.suppressed_at IS NULL
         ) r
     ) AS recipients
 FROM
@@ -110,8 +111,8 @@ func (h *Handle) UpdateState(ctx context.Context, in *UpdateStateInput) (*Update
 const (
 	createScheduleConfigurationQuery = `
 INSERT INTO 
-    configuration (id, keyword, schedule) 
-VALUES (@id, @keyword, @schedule)
+    configuration (id, keyword, schedule, feed_token) 
+VALUES (@id, @keyword, @schedule, @feed_token)
 `
 	createScheduleSubredditConfigurationQuery = `
 INSERT INTO 
@@ -120,8 +121,8 @@ VALUES (@id, @configuration_id, @subreddit, @include_nsfw, @sort, @restrict_subr
 `
 	createScheduleRecipientsQuery = `
 INSERT INTO 
-    recipients (id, configuration_id, address)
-VALUES (@id, @configuration_id, @address)`
+    recipients (id, configuration_id, channel, address, settings)
+VALUES (@id, @configuration_id, @channel, @address, @settings)`
 )
 
 func (h *Handle) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (*CreateScheduleOutput, error) {
@@ -134,9 +135,10 @@ func (h *Handle) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (*
 	}()
 
 	if _, err = tx.Exec(ctx, createScheduleConfigurationQuery, pgx.NamedArgs{
-		"id":       in.ID,
-		"keyword":  in.Keyword,
-		"schedule": in.Schedule,
+		"id":         in.ID,
+		"keyword":    in.Keyword,
+		"schedule":   in.Schedule,
+		"feed_token": in.FeedToken,
 	}); err != nil {
 		return nil, err
 	}
@@ -159,7 +161,9 @@ func (h *Handle) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (*
 		args := pgx.NamedArgs{
 			"id":               recipient.ID,
 			"configuration_id": in.ID,
+			"channel":          recipient.Channel,
 			"address":          recipient.Address,
+			"settings":         recipient.Settings,
 		}
 		if _, err = tx.Exec(ctx, createScheduleRecipientsQuery, args); err != nil {
 			return nil, err
@@ -178,13 +182,16 @@ SELECT
     c.id AS id,
 	c.keyword AS keyword,
 	c.schedule AS schedule,
+	c.feed_token AS feed_token,
 	sc.id AS subreddit_id,
 	sc.subreddit AS subreddit,
 	sc.include_nsfw AS include_nsfw,
 	sc.sort AS sort,
 	sc.restrict_subreddit AS restrict_subreddit,
 	r.id AS recipient_id,
-	r.address AS address
+	r.channel AS channel,
+	r.address AS address,
+	r.settings AS settings
 FROM
     configuration c
 JOIN
@@ -232,8 +239,10 @@ func (h *Handle) GetSchedule(ctx context.Context, in *GetScheduleInput) (*GetSch
 
 		if _, ok := recipientMap[m.RecipientID]; !ok {
 			recipientMap[m.RecipientID] = &Recipient{
-				ID:      m.RecipientID,
-				Address: m.Address,
+				ID:       m.RecipientID,
+				Channel:  m.Channel,
+				Address:  m.Address,
+				Settings: m.Settings,
 			}
 		}
 	}
@@ -252,6 +261,7 @@ func (h *Handle) GetSchedule(ctx context.Context, in *GetScheduleInput) (*GetSch
 		ID:         dbModels[0].ID,
 		Keyword:    keyword,
 		Schedule:   schedule,
+		FeedToken:  dbModels[0].FeedToken,
 		Recipients: recipients,
 		Subreddits: subreddits,
 	}, nil
@@ -292,7 +302,7 @@ SELECT
     (
         SELECT COALESCE(jsonb_agg(r), '[]')
         FROM (
-            SELECT r.id, r.address
+            SELECT r.id, r.channel, r.address, r.settings
             FROM recipients r
             WHERE r.configuration_id = c.id
         ) r
@@ -378,10 +388,17 @@ delete_recipients AS (
     WHERE configuration_id = (SELECT cfg_id FROM input_data)
     AND id NOT IN (SELECT (jsonb_array_elements(recipients)->>'id')::uuid FROM input_data)
 )
-INSERT INTO recipients (id, configuration_id, address)
-SELECT (e->>'id')::uuid, (SELECT cfg_id FROM input_data), e->>'address'
+INSERT INTO recipients (id, configuration_id, channel, address, settings)
+SELECT
+    (e->>'id')::uuid, (SELECT cfg_id FROM input_data), COALESCE(NULLIF(e->>'channel', ''), 'email'), e->>'address',
+    COALESCE(e->'settings', '{}')
 FROM input_data, jsonb_array_elements(recipients) AS e
-ON CONFLICT (id) DO UPDATE SET address = EXCLUDED.address;
+ON CONFLICT (id) DO UPDATE SET
+    channel = EXCLUDED.channel,
+    address = EXCLUDED.address,
+    settings = EXCLUDED.settings,
+    suppressed_at = CASE WHEN recipients.address = EXCLUDED.address THEN recipients.suppressed_at END,
+    suppression_reason = CASE WHEN recipients.address = EXCLUDED.address THEN recipients.suppression_reason ELSE '' END;
 `
 
 func (h *Handle) UpdateSchedule(ctx context.Context, in *UpdateScheduleInput) (*UpdateScheduleOutput, error) {
@@ -410,7 +427,9 @@ func (h *Handle) UpdateSchedule(ctx context.Context, in *UpdateScheduleInput) (*
 	return &UpdateScheduleOutput{}, nil
 }
 
-const queuePostsInsertQ = `INSERT INTO posts (id, configuration_id, data) VALUES (@id, @configuration_id, @data)`
+const queuePostsInsertQ = `
+INSERT INTO posts (id, configuration_id, data, created_time) VALUES (@id, @configuration_id, @data, @created_time)
+`
 
 func (h *Handle) QueuePosts(ctx context.Context, in *QueuePostsInput) (*QueuePostsOutput, error) {
 	batch := &pgx.Batch{}
@@ -422,6 +441,7 @@ func (h *Handle) QueuePosts(ctx context.Context, in *QueuePostsInput) (*QueuePos
 				"id":               post.ID,
 				"configuration_id": post.ConfigurationID,
 				"data":             post.Post,
+				"created_time":     post.CreatedTime,
 			},
 		)
 	}
@@ -440,7 +460,9 @@ func (h *Handle) QueuePosts(ctx context.Context, in *QueuePostsInput) (*QueuePos
 	return &QueuePostsOutput{}, nil
 }
 
-const getPostsSelectQ = `SELECT id, configuration_id, data FROM posts WHERE configuration_id = @configuration_id`
+const getPostsSelectQ = `
+SELECT id, configuration_id, data FROM posts WHERE configuration_id = @configuration_id AND sent_at IS NULL
+`
 
 func (h *Handle) GetPosts(ctx context.Context, in *GetPostsInput) (*GetPostsOutput, error) {
 	rows, err := h.db.Query(ctx, getPostsSelectQ, pgx.NamedArgs{"configuration_id": in.ConfigurationID})
@@ -473,13 +495,104 @@ func (h *Handle) GetPosts(ctx context.Context, in *GetPostsInput) (*GetPostsOutp
 	}, nil
 }
 
-const popPostsDeleteQ = `DELETE FROM posts WHERE configuration_id = @configuration_id`
+// claimPostsQ claims every queued post of the configuration for the given key. That includes posts claimed by an
+// earlier digest run that never completed, digest runs of one schedule don't overlap so such a claim is always stale.
+const claimPostsQ = `
+UPDATE posts SET claimed_by = @idempotency_key
+WHERE configuration_id = @configuration_id AND sent_at IS NULL
+RETURNING id, configuration_id, data
+`
+
+func (h *Handle) ClaimPosts(ctx context.Context, in *ClaimPostsInput) (*ClaimPostsOutput, error) {
+	rows, err := h.db.Query(ctx, claimPostsQ, pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+		"idempotency_key":  in.IdempotencyKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type postModel struct {
+		ID              uuid.UUID `db:"id"`
+		ConfigurationID uuid.UUID `db:"configuration_id"`
+		Data            []byte    `db:"data"`
+	}
+
+	posts, err := pgx.CollectRows(rows, pgx.RowToStructByName[postModel])
+	if err != nil {
+		return nil, err
+	}
+
+	var items = make([]QueueItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, QueueItem{
+			ID:              post.ID,
+			ConfigurationID: post.ConfigurationID,
+			Post:            post.Data,
+		})
+	}
+
+	return &ClaimPostsOutput{
+		Items: items,
+	}, nil
+}
+
+// sentPostsRetained is the number of sent posts kept per configuration for its feeds, older ones are deleted when
+// posts are popped.
+const sentPostsRetained = 100
 
+const (
+	popPostsUpdateQ = `
+UPDATE posts SET sent_at = CURRENT_TIMESTAMP
+WHERE configuration_id = @configuration_id AND sent_at IS NULL
+`
+	popClaimedPostsUpdateQ = `
+UPDATE posts SET sent_at = CURRENT_TIMESTAMP
+WHERE configuration_id = @configuration_id AND sent_at IS NULL AND claimed_by = @idempotency_key
+`
+	pruneSentPostsDeleteQ = `
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM posts
+    WHERE configuration_id = @configuration_id AND sent_at IS NOT NULL
+    ORDER BY created_time DESC NULLS LAST, sent_at DESC
+    OFFSET @retained
+)
+`
+)
+
+// PopPosts removes posts from the queue. They are marked as sent rather than deleted, the feeds are built from them.
 func (h *Handle) PopPosts(ctx context.Context, in *PopPostsInput) (*PopPostsOutput, error) {
-	_, err := h.db.Exec(ctx, popPostsDeleteQ, pgx.NamedArgs{"configuration_id": in.ConfigurationID})
+	query := popPostsUpdateQ
+	if in.IdempotencyKey != "" {
+		query = popClaimedPostsUpdateQ
+	}
+
+	tx, err := h.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err = tx.Exec(ctx, query, pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+		"idempotency_key":  in.IdempotencyKey,
+	}); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, pruneSentPostsDeleteQ, pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+		"retained":         sentPostsRetained,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
 	return &PopPostsOutput{}, nil
 }
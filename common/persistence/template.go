@@ -0,0 +1,209 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// createEmailTemplateLockQ locks the configuration, so concurrent uploads of a name get consecutive versions.
+	createEmailTemplateLockQ = `SELECT id FROM configuration WHERE id = @configuration_id FOR UPDATE`
+
+	createEmailTemplateInsertQ = `
+INSERT INTO email_templates (id, configuration_id, name, version, source)
+SELECT @id, @configuration_id, @name, COALESCE(MAX(version), 0) + 1, @source
+FROM email_templates
+WHERE configuration_id = @configuration_id AND name = @name
+RETURNING version, created_at
+`
+)
+
+// CreateEmailTemplate stores a new version of the named template. ErrNotFound is returned if the configuration doesn't
+// exist.
+func (h *Handle) CreateEmailTemplate(ctx context.Context, in *CreateEmailTemplateInput) (*CreateEmailTemplateOutput, error) {
+	tx, err := h.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	args := pgx.NamedArgs{
+		"id":               in.ID,
+		"configuration_id": in.ConfigurationID,
+		"name":             in.Name,
+		"source":           in.Source,
+	}
+
+	var id uuid.UUID
+	if err = tx.QueryRow(ctx, createEmailTemplateLockQ, args).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	out := &CreateEmailTemplateOutput{}
+	if err = tx.QueryRow(ctx, createEmailTemplateInsertQ, args).Scan(&out.Version, &out.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+const (
+	listEmailTemplatesActiveQ = `SELECT email_template_id FROM configuration WHERE id = @configuration_id`
+
+	// listEmailTemplatesSelectQ leaves out the sources, they're only needed to render a single version.
+	listEmailTemplatesSelectQ = `
+SELECT id, configuration_id, name, version, '' AS source, created_at
+FROM email_templates
+WHERE configuration_id = @configuration_id
+ORDER BY name, version
+`
+)
+
+// ListEmailTemplates returns every version of the configuration's templates and the active one. ErrNotFound is
+// returned if the configuration doesn't exist.
+func (h *Handle) ListEmailTemplates(ctx context.Context, in *ListEmailTemplatesInput) (*ListEmailTemplatesOutput, error) {
+	args := pgx.NamedArgs{"configuration_id": in.ConfigurationID}
+
+	var active *uuid.UUID
+	if err := h.db.QueryRow(ctx, listEmailTemplatesActiveQ, args).Scan(&active); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := h.db.Query(ctx, listEmailTemplatesSelectQ, args)
+	if err != nil {
+		return nil, err
+	}
+
+	dbModels, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.EmailTemplate])
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ListEmailTemplatesOutput{
+		Templates: make([]*EmailTemplate, 0, len(dbModels)),
+	}
+	if active != nil {
+		out.ActiveID = *active
+	}
+	for _, m := range dbModels {
+		out.Templates = append(out.Templates, emailTemplateFromModel(&m))
+	}
+
+	return out, nil
+}
+
+const getEmailTemplateSelectQ = `
+SELECT id, configuration_id, name, version, source, created_at
+FROM email_templates
+WHERE id = @id AND configuration_id = @configuration_id
+`
+
+// GetEmailTemplate returns a version of the configuration's templates, ErrNotFound if it has no such version.
+func (h *Handle) GetEmailTemplate(ctx context.Context, in *GetEmailTemplateInput) (*GetEmailTemplateOutput, error) {
+	rows, err := h.db.Query(ctx, getEmailTemplateSelectQ, pgx.NamedArgs{
+		"id":               in.ID,
+		"configuration_id": in.ConfigurationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.EmailTemplate])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &GetEmailTemplateOutput{
+		Template: emailTemplateFromModel(&m),
+	}, nil
+}
+
+const getActiveEmailTemplateSelectQ = `
+SELECT t.id, t.configuration_id, t.name, t.version, t.source, t.created_at
+FROM configuration c
+JOIN email_templates t ON t.id = c.email_template_id
+WHERE c.id = @configuration_id
+`
+
+// GetActiveEmailTemplate returns the active version of the configuration's templates. The template is nil if the
+// configuration uses the default templates or doesn't exist.
+func (h *Handle) GetActiveEmailTemplate(ctx context.Context, in *GetActiveEmailTemplateInput) (*GetActiveEmailTemplateOutput, error) {
+	rows, err := h.db.Query(ctx, getActiveEmailTemplateSelectQ, pgx.NamedArgs{
+		"configuration_id": in.ConfigurationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.EmailTemplate])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &GetActiveEmailTemplateOutput{}, nil
+		}
+		return nil, err
+	}
+
+	return &GetActiveEmailTemplateOutput{
+		Template: emailTemplateFromModel(&m),
+	}, nil
+}
+
+const activateEmailTemplateUpdateQ = `
+UPDATE configuration c
+SET email_template_id = @id
+WHERE c.id = @configuration_id
+AND (@id::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM email_templates t WHERE t.id = @id AND t.configuration_id = c.id
+))
+`
+
+// ActivateEmailTemplate makes a version the template of the configuration's digests. ErrNotFound is returned if the
+// configuration or the version doesn't exist.
+func (h *Handle) ActivateEmailTemplate(ctx context.Context, in *ActivateEmailTemplateInput) (*ActivateEmailTemplateOutput, error) {
+	var id *uuid.UUID
+	if in.ID != uuid.Nil {
+		id = &in.ID
+	}
+
+	tag, err := h.db.Exec(ctx, activateEmailTemplateUpdateQ, pgx.NamedArgs{
+		"id":               id,
+		"configuration_id": in.ConfigurationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &ActivateEmailTemplateOutput{}, nil
+}
+
+func emailTemplateFromModel(m *models.EmailTemplate) *EmailTemplate {
+	return &EmailTemplate{
+		ID:              m.ID,
+		ConfigurationID: m.ConfigurationID,
+		Name:            m.Name,
+		Version:         m.Version,
+		Source:          m.Source,
+		CreatedAt:       m.CreatedAt,
+	}
+}
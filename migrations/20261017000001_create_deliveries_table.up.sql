@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS deliveries
+(
+    id               UUID PRIMARY KEY,
+    configuration_id UUID        NOT NULL REFERENCES configuration (id) ON DELETE CASCADE,
+    -- No foreign key, the history of a recipient outlives the recipient. The address is kept as snapshot.
+    recipient_id     UUID        NOT NULL,
+    address          TEXT        NOT NULL,
+    provider         TEXT        NOT NULL DEFAULT '',
+    message_id       TEXT        NOT NULL DEFAULT '',
+    post_ids         TEXT[]      NOT NULL DEFAULT '{}',
+    status           TEXT        NOT NULL,
+    error            TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS deliveries_configuration_id_created_at_idx ON deliveries (configuration_id, created_at DESC);
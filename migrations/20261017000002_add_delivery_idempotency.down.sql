@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS deliveries_idempotency_key_recipient_id_idx;
+ALTER TABLE deliveries DROP COLUMN IF EXISTS idempotency_key;
+
+DROP INDEX IF EXISTS posts_configuration_id_claimed_by_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS claimed_by;
@@ -0,0 +1,13 @@
+-- Posts are claimed by the digest run that is about to send them, so a retried activity sends the same set of posts
+-- and only deletes what it actually sent.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS claimed_by TEXT;
+
+CREATE INDEX IF NOT EXISTS posts_configuration_id_claimed_by_idx ON posts (configuration_id, claimed_by);
+
+-- Deliveries are reserved per idempotency key and recipient before sending. Rows written before this migration keep an
+-- empty key and are exempt from the unique constraint.
+ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS idempotency_key TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS deliveries_idempotency_key_recipient_id_idx
+    ON deliveries (idempotency_key, recipient_id)
+    WHERE idempotency_key <> '';
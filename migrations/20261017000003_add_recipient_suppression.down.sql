@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS recipients_lower_address_idx;
+
+ALTER TABLE recipients DROP COLUMN IF EXISTS suppression_reason;
+ALTER TABLE recipients DROP COLUMN IF EXISTS suppressed_at;
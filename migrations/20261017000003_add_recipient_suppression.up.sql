@@ -0,0 +1,6 @@
+-- Recipients are suppressed after a hard bounce or a spam complaint and skipped by every following digest. Changing the
+-- address of a recipient lifts the suppression.
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS suppressed_at TIMESTAMPTZ;
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS suppression_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS recipients_lower_address_idx ON recipients (lower(address));
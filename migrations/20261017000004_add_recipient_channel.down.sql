@@ -0,0 +1,5 @@
+ALTER TABLE deliveries DROP COLUMN IF EXISTS channel;
+
+DELETE FROM recipients WHERE channel <> 'email';
+
+ALTER TABLE recipients DROP COLUMN IF EXISTS channel;
@@ -0,0 +1,7 @@
+-- Recipients can be notified on other channels than email. For those the address column holds the channel specific
+-- target, e.g. the webhook URL for Discord.
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT 'email';
+
+-- Deliveries keep the channel of their recipient. Their address snapshot is only kept for email, the address of the
+-- other channels is a credential.
+ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT 'email';
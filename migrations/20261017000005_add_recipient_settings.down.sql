@@ -0,0 +1 @@
+ALTER TABLE recipients DROP COLUMN IF EXISTS settings;
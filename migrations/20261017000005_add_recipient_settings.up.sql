@@ -0,0 +1,2 @@
+-- Channel specific options of a recipient that don't fit the address, e.g. the signing secret of a webhook.
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,7 @@
+ALTER TABLE configuration DROP COLUMN IF EXISTS feed_token;
+
+DELETE FROM posts WHERE sent_at IS NOT NULL;
+
+DROP INDEX IF EXISTS posts_configuration_id_created_time_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS created_time;
+ALTER TABLE posts DROP COLUMN IF EXISTS sent_at;
@@ -0,0 +1,12 @@
+-- Sent posts are kept for the feeds of a schedule instead of being deleted. The queue only holds posts without
+-- sent_at, every schedule keeps its newest sent posts.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS sent_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS created_time TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS posts_configuration_id_created_time_idx ON posts (configuration_id, created_time DESC);
+
+-- Feed readers can't authenticate, the feeds of a schedule are only served with its token. Existing schedules get a
+-- random one.
+ALTER TABLE configuration
+    ADD COLUMN IF NOT EXISTS feed_token TEXT NOT NULL
+        DEFAULT replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '');
@@ -0,0 +1,3 @@
+ALTER TABLE configuration DROP COLUMN IF EXISTS email_template_id;
+
+DROP TABLE IF EXISTS email_templates;
@@ -0,0 +1,15 @@
+-- Every upload of a named template is stored as a new version, versions are never changed.
+CREATE TABLE IF NOT EXISTS email_templates
+(
+    id               UUID PRIMARY KEY,
+    configuration_id UUID        NOT NULL REFERENCES configuration (id) ON DELETE CASCADE,
+    name             TEXT        NOT NULL,
+    version          INT         NOT NULL,
+    source           TEXT        NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (configuration_id, name, version)
+);
+
+-- NULL renders the digests of the schedule with the default templates.
+ALTER TABLE configuration
+    ADD COLUMN IF NOT EXISTS email_template_id UUID REFERENCES email_templates (id) ON DELETE SET NULL;
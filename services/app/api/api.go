@@ -28,6 +28,8 @@ func NewRouter(app *app.App) http.Handler {
 	)
 
 	r.Route("/v1", func(r chi.Router) {
+		webPushHandler := v1.NewWebPushHandler(app.ScheduleService(), app.WebPushPublicKey())
+
 		r.Route("/schedule", func(r chi.Router) {
 			scheduleHandler := v1.NewScheduleHandler(app.ScheduleService(), app.Validator())
 
@@ -38,8 +40,32 @@ func NewRouter(app *app.App) http.Handler {
 				r.Get("/", scheduleHandler.GetScheduleGet())
 				r.Put("/", scheduleHandler.UpdateSchedulePut())
 				r.Delete("/", scheduleHandler.DeleteScheduleDelete())
+				r.Get("/deliveries", scheduleHandler.ListDeliveriesGet())
+				r.Get("/feed.atom", scheduleHandler.FeedAtomGet())
+				r.Get("/feed.rss", scheduleHandler.FeedRSSGet())
+				r.Post("/webpush", webPushHandler.SubscriptionPost())
+
+				r.Route("/templates", func(r chi.Router) {
+					r.Get("/", scheduleHandler.EmailTemplatesGet())
+					r.Post("/", scheduleHandler.EmailTemplatesPost())
+					r.Delete("/active", scheduleHandler.EmailTemplateActiveDelete())
+					r.Post("/{templateID}/activate", scheduleHandler.EmailTemplateActivatePost())
+					r.Get("/{templateID}/preview", scheduleHandler.EmailTemplatePreviewGet())
+				})
 			})
 		})
+
+		r.Route("/webpush", func(r chi.Router) {
+			r.Get("/key", webPushHandler.PublicKeyGet())
+		})
+
+		r.Route("/webhook", func(r chi.Router) {
+			webhookHandler := v1.NewWebhookHandler(app.WebhookService())
+
+			r.Post("/ses", webhookHandler.SESPost())
+			r.Post("/resend", webhookHandler.ResendPost())
+			r.Post("/mailersend", webhookHandler.MailerSendPost())
+		})
 	})
 
 	return r
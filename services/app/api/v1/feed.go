@@ -0,0 +1,300 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// feedContentTemplate renders the content of an entry. Thumbnails of NSFW and spoiler posts are left out, feed readers
+// don't blur them.
+var feedContentTemplate = template.Must(template.New("content").Parse(
+	`<p><a href="{{.Permalink}}">{{.Title}}</a> in r/{{.Subreddit}}</p>` +
+		`{{if and .Thumbnail (not .NSFW) (not .Spoiler)}}<p><img src="{{.Thumbnail}}" alt=""></p>{{end}}` +
+		`<p>⬆ {{.Ups}} • ⬇ {{.Downs}}{{if .NSFW}} • NSFW{{end}}{{if .Spoiler}} • Spoiler{{end}}</p>` +
+		`{{if ne .URL .Permalink}}<p><a href="{{.URL}}">{{.URL}}</a></p>{{end}}`,
+))
+
+type (
+	atomFeed struct {
+		XMLName   xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+		ID        string       `xml:"id"`
+		Title     string       `xml:"title"`
+		Updated   string       `xml:"updated"`
+		Links     []*atomLink  `xml:"link"`
+		Author    atomAuthor   `xml:"author"`
+		Generator string       `xml:"generator"`
+		Entries   []*atomEntry `xml:"entry"`
+	}
+
+	atomEntry struct {
+		ID         string          `xml:"id"`
+		Title      string          `xml:"title"`
+		Links      []*atomLink     `xml:"link"`
+		Updated    string          `xml:"updated"`
+		Published  string          `xml:"published"`
+		Categories []*atomCategory `xml:"category"`
+		Content    atomContent     `xml:"content"`
+	}
+
+	atomLink struct {
+		Rel  string `xml:"rel,attr,omitempty"`
+		Type string `xml:"type,attr,omitempty"`
+		Href string `xml:"href,attr"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomCategory struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr,omitempty"`
+	}
+
+	atomContent struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+
+	rssFeed struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Atom    string     `xml:"xmlns:atom,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		Title         string      `xml:"title"`
+		Link          string      `xml:"link"`
+		Description   string      `xml:"description"`
+		LastBuildDate string      `xml:"lastBuildDate"`
+		Generator     string      `xml:"generator"`
+		Self          rssAtomLink `xml:"atom:link"`
+		Items         []*rssItem  `xml:"item"`
+	}
+
+	rssAtomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	}
+
+	rssItem struct {
+		Title       string        `xml:"title"`
+		Link        string        `xml:"link"`
+		GUID        rssGUID       `xml:"guid"`
+		PubDate     string        `xml:"pubDate"`
+		Category    string        `xml:"category"`
+		Description string        `xml:"description"`
+		Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+	}
+
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		ID          string `xml:",chardata"`
+	}
+
+	rssEnclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int    `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	}
+)
+
+const feedGenerator = "reddit-post-notifier"
+
+// FeedAtomGet serves the Atom feed of a schedule.
+func (h *ScheduleHandler) FeedAtomGet() http.HandlerFunc {
+	return h.feed("application/atom+xml; charset=utf-8", func(r *http.Request, feed *reddit.GetFeedOutput) (any, error) {
+		out := &atomFeed{
+			ID:      "urn:uuid:" + feed.ScheduleID.String(),
+			Title:   fmt.Sprintf("Reddit posts for %s", feed.Keyword),
+			Updated: feed.Updated.Format(time.RFC3339),
+			Links: []*atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: requestURL(r)},
+				{Rel: "alternate", Type: "text/html", Href: searchURL(feed.Keyword)},
+			},
+			Author:    atomAuthor{Name: feedGenerator},
+			Generator: feedGenerator,
+			Entries:   make([]*atomEntry, 0, len(feed.Posts)),
+		}
+
+		for _, post := range feed.Posts {
+			content, err := feedContent(post)
+			if err != nil {
+				return nil, err
+			}
+
+			entry := &atomEntry{
+				ID:        fullname(post),
+				Title:     post.Title,
+				Links:     []*atomLink{{Rel: "alternate", Type: "text/html", Href: post.Permalink}},
+				Updated:   post.Created.Format(time.RFC3339),
+				Published: post.Created.Format(time.RFC3339),
+				Categories: []*atomCategory{
+					{Term: post.Subreddit, Label: "r/" + post.Subreddit},
+				},
+				Content: atomContent{Type: "html", Body: content},
+			}
+			if enclosure := feedEnclosure(post); enclosure != "" {
+				entry.Links = append(entry.Links, &atomLink{
+					Rel:  "enclosure",
+					Type: enclosureType(enclosure),
+					Href: enclosure,
+				})
+			}
+
+			out.Entries = append(out.Entries, entry)
+		}
+
+		return out, nil
+	})
+}
+
+// FeedRSSGet serves the RSS 2.0 feed of a schedule.
+func (h *ScheduleHandler) FeedRSSGet() http.HandlerFunc {
+	return h.feed("application/rss+xml; charset=utf-8", func(r *http.Request, feed *reddit.GetFeedOutput) (any, error) {
+		out := &rssFeed{
+			Version: "2.0",
+			Atom:    "http://www.w3.org/2005/Atom",
+			Channel: rssChannel{
+				Title:         fmt.Sprintf("Reddit posts for %s", feed.Keyword),
+				Link:          searchURL(feed.Keyword),
+				Description:   fmt.Sprintf("New Reddit posts matching %q", feed.Keyword),
+				LastBuildDate: feed.Updated.Format(time.RFC1123Z),
+				Generator:     feedGenerator,
+				Self: rssAtomLink{
+					Href: requestURL(r),
+					Rel:  "self",
+					Type: "application/rss+xml",
+				},
+				Items: make([]*rssItem, 0, len(feed.Posts)),
+			},
+		}
+
+		for _, post := range feed.Posts {
+			content, err := feedContent(post)
+			if err != nil {
+				return nil, err
+			}
+
+			item := &rssItem{
+				Title:       post.Title,
+				Link:        post.Permalink,
+				GUID:        rssGUID{ID: fullname(post)},
+				PubDate:     post.Created.Format(time.RFC1123Z),
+				Category:    "r/" + post.Subreddit,
+				Description: content,
+			}
+			if enclosure := feedEnclosure(post); enclosure != "" {
+				// The size isn't known without downloading the image, readers accept 0.
+				item.Enclosure = &rssEnclosure{URL: enclosure, Type: enclosureType(enclosure)}
+			}
+
+			out.Channel.Items = append(out.Channel.Items, item)
+		}
+
+		return out, nil
+	})
+}
+
+// feed serves a feed of a schedule, authorized by the token in the query. Unknown schedules and wrong tokens are both
+// answered with 404. Last-Modified is the time of the newest post, so readers polling with If-Modified-Since get a
+// 304 until there's something new.
+func (h *ScheduleHandler) feed(contentType string, render func(*http.Request, *reddit.GetFeedOutput) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		feed, err := h.scheduleService.GetFeed(ctx, &reddit.GetFeedInput{
+			ScheduleID: id,
+			Token:      r.URL.Query().Get("token"),
+		})
+		if err != nil {
+			if errors.Is(err, reddit.ErrFeedNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		out, err := render(r, feed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		if err = xml.NewEncoder(&buf).Encode(out); err != nil {
+			slog.Error("encode feed", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		http.ServeContent(w, r, "", feed.Updated, bytes.NewReader(buf.Bytes()))
+	}
+}
+
+func feedContent(post *reddit.FeedPost) (string, error) {
+	var b strings.Builder
+	if err := feedContentTemplate.Execute(&b, post); err != nil {
+		return "", fmt.Errorf("render feed content: %w", err)
+	}
+	return b.String(), nil
+}
+
+// fullname is the Reddit fullname of a post, stable across every feed and every update of the post.
+func fullname(post *reddit.FeedPost) string {
+	return "t3_" + post.ID
+}
+
+func feedEnclosure(post *reddit.FeedPost) string {
+	if post.NSFW || post.Spoiler || !strings.HasPrefix(post.Thumbnail, "https://") {
+		return ""
+	}
+	return post.Thumbnail
+}
+
+func enclosureType(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		if t := mime.TypeByExtension(path.Ext(u.Path)); strings.HasPrefix(t, "image/") {
+			return t
+		}
+	}
+	return "image/jpeg"
+}
+
+func searchURL(keyword string) string {
+	return "https://www.reddit.com/search/?q=" + url.QueryEscape(keyword)
+}
+
+// requestURL reconstructs the URL the feed was requested with, token included, for the self link.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
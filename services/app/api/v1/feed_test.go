@@ -0,0 +1,214 @@
+package v1
+
+import (
+	"context"
+	"encoding/xml"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed(scheduleID uuid.UUID) *reddit.GetFeedOutput {
+	created := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	return &reddit.GetFeedOutput{
+		ScheduleID: scheduleID,
+		Keyword:    "rust & go",
+		Updated:    created,
+		Posts: []*reddit.FeedPost{
+			{
+				ID:        "1abcde",
+				Title:     "Example post with <b>markup</b>",
+				URL:       "https://example.com/article",
+				Subreddit: "example",
+				Ups:       42,
+				Thumbnail: "https://b.thumbs.redditmedia.com/abcdef.png",
+				Permalink: "https://www.reddit.com/r/example/comments/1abcde/example_post/",
+				Created:   created,
+			},
+			{
+				ID:        "1fghij",
+				Title:     "Example NSFW post",
+				URL:       "https://www.reddit.com/r/example/comments/1fghij/example_nsfw_post/",
+				Subreddit: "example",
+				NSFW:      true,
+				Thumbnail: "https://b.thumbs.redditmedia.com/ghijkl.jpg",
+				Permalink: "https://www.reddit.com/r/example/comments/1fghij/example_nsfw_post/",
+				Created:   created.Add(-time.Hour),
+			},
+		},
+	}
+}
+
+func TestFeedGet(t *testing.T) {
+	scheduleID := uuid.MustParse("0199f2a0-0000-7000-8000-000000000001")
+
+	service := &fakeService{
+		getFeed: func(_ context.Context, in *reddit.GetFeedInput) (*reddit.GetFeedOutput, error) {
+			if in.ScheduleID != scheduleID || in.Token != "secret" {
+				return nil, reddit.ErrFeedNotFound
+			}
+			return testFeed(scheduleID), nil
+		},
+	}
+	h := newTestScheduleHandler(service)
+
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		path            string
+		ifModifiedSince string
+		status          int
+		contentType     string
+	}{
+		{
+			name:        "atom",
+			handler:     h.FeedAtomGet(),
+			path:        "/v1/schedule/" + scheduleID.String() + "/feed.atom?token=secret",
+			status:      http.StatusOK,
+			contentType: "application/atom+xml; charset=utf-8",
+		},
+		{
+			name:        "rss",
+			handler:     h.FeedRSSGet(),
+			path:        "/v1/schedule/" + scheduleID.String() + "/feed.rss?token=secret",
+			status:      http.StatusOK,
+			contentType: "application/rss+xml; charset=utf-8",
+		},
+		{
+			name:            "not modified",
+			handler:         h.FeedAtomGet(),
+			path:            "/v1/schedule/" + scheduleID.String() + "/feed.atom?token=secret",
+			ifModifiedSince: "Sat, 17 Oct 2026 12:00:00 GMT",
+			status:          http.StatusNotModified,
+		},
+		{
+			name:            "modified",
+			handler:         h.FeedAtomGet(),
+			path:            "/v1/schedule/" + scheduleID.String() + "/feed.atom?token=secret",
+			ifModifiedSince: "Sat, 17 Oct 2026 11:00:00 GMT",
+			status:          http.StatusOK,
+			contentType:     "application/atom+xml; charset=utf-8",
+		},
+		{
+			name:    "wrong token",
+			handler: h.FeedAtomGet(),
+			path:    "/v1/schedule/" + scheduleID.String() + "/feed.atom?token=guess",
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "missing token",
+			handler: h.FeedRSSGet(),
+			path:    "/v1/schedule/" + scheduleID.String() + "/feed.rss",
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "invalid id",
+			handler: h.FeedAtomGet(),
+			path:    "/v1/schedule/nope/feed.atom?token=secret",
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := "/v1/schedule/{id}/feed.atom"
+			if strings.Contains(tt.path, "feed.rss") {
+				pattern = "/v1/schedule/{id}/feed.rss"
+			}
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.ifModifiedSince != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+
+			rec := serveHandler(http.MethodGet, pattern, tt.handler, r)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.contentType != "" {
+				if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+					t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+				}
+				if got := rec.Header().Get("Last-Modified"); got != "Sat, 17 Oct 2026 12:00:00 GMT" {
+					t.Errorf("Last-Modified = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestFeedAtomGetEntries(t *testing.T) {
+	scheduleID := uuid.MustParse("0199f2a0-0000-7000-8000-000000000001")
+	h := newTestScheduleHandler(&fakeService{
+		getFeed: func(context.Context, *reddit.GetFeedInput) (*reddit.GetFeedOutput, error) {
+			return testFeed(scheduleID), nil
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/schedule/"+scheduleID.String()+"/feed.atom?token=secret", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	rec := serveHandler(http.MethodGet, "/v1/schedule/{id}/feed.atom", h.FeedAtomGet(), r)
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+
+	if feed.ID != "urn:uuid:"+scheduleID.String() || feed.Updated != "2026-10-17T12:00:00Z" {
+		t.Errorf("feed = %s updated %s", feed.ID, feed.Updated)
+	}
+	if want := "https://example.com/v1/schedule/" + scheduleID.String() + "/feed.atom?token=secret"; feed.Links[0].Href != want {
+		t.Errorf("self link = %q, want %q", feed.Links[0].Href, want)
+	}
+	if want := "https://www.reddit.com/search/?q=rust+%26+go"; feed.Links[1].Href != want {
+		t.Errorf("alternate link = %q, want %q", feed.Links[1].Href, want)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(feed.Entries))
+	}
+
+	post, nsfw := feed.Entries[0], feed.Entries[1]
+	if post.ID != "t3_1abcde" || post.Title != "Example post with <b>markup</b>" {
+		t.Errorf("entry = %s %q", post.ID, post.Title)
+	}
+	if len(post.Links) != 2 || post.Links[1].Rel != "enclosure" || post.Links[1].Type != "image/png" {
+		t.Errorf("entry links = %+v, want an image/png enclosure", post.Links)
+	}
+	if !strings.Contains(post.Content.Body, "&lt;b&gt;markup&lt;/b&gt;") {
+		t.Errorf("title isn't escaped in the content: %s", post.Content.Body)
+	}
+	if !strings.Contains(post.Content.Body, `<a href="https://example.com/article">`) {
+		t.Errorf("content doesn't link the post URL: %s", post.Content.Body)
+	}
+
+	// Readers don't blur thumbnails.
+	if len(nsfw.Links) != 1 || strings.Contains(nsfw.Content.Body, "<img") {
+		t.Errorf("NSFW entry has a thumbnail: %+v %s", nsfw.Links, nsfw.Content.Body)
+	}
+}
+
+func TestEnclosureType(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://b.thumbs.redditmedia.com/abcdef.png", want: "image/png"},
+		{url: "https://b.thumbs.redditmedia.com/abcdef.jpg", want: "image/jpeg"},
+		{url: "https://b.thumbs.redditmedia.com/abcdef.gif?width=140", want: "image/gif"},
+		{url: "https://b.thumbs.redditmedia.com/abcdef", want: "image/jpeg"},
+		{url: "https://example.com/page.html", want: "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := enclosureType(tt.url); got != tt.want {
+				t.Errorf("enclosureType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,15 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
 	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -35,7 +38,10 @@ func (h *ScheduleHandler) CreateSchedulePost() http.HandlerFunc {
 			RestrictSubreddit bool   `json:"restrict_subreddit"`
 		}
 		recipient struct {
-			Address string `json:"address" validate:"required,email"`
+			Channel  string          `json:"channel"`
+			Address  string          `json:"address" validate:"required_without=URL"`
+			Settings json.RawMessage `json:"settings,omitempty"`
+			URL      string          `json:"url"`
 		}
 
 		request struct {
@@ -78,8 +84,19 @@ func (h *ScheduleHandler) CreateSchedulePost() http.HandlerFunc {
 		}
 
 		for _, rec := range req.Recipients {
+			target, err := recipientTarget(rec.Channel, rec.Address, rec.Settings, rec.URL)
+			if err == nil {
+				err = notify.ValidateTarget(target)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			recipients = append(recipients, &reddit.Recipient{
-				Address: rec.Address,
+				Channel:  target.Channel,
+				Address:  target.Address,
+				Settings: target.Settings,
 			})
 		}
 
@@ -115,8 +132,11 @@ func (h *ScheduleHandler) GetScheduleGet() http.HandlerFunc {
 		}
 
 		recipient struct {
-			ID      uuid.UUID `json:"id"`
-			Address string    `json:"address" validate:"required,email"`
+			ID       uuid.UUID       `json:"id"`
+			Channel  string          `json:"channel"`
+			Address  string          `json:"address" validate:"required"`
+			Settings json.RawMessage `json:"settings,omitempty"`
+			URL      string          `json:"url,omitempty"`
 		}
 
 		response struct {
@@ -128,6 +148,7 @@ func (h *ScheduleHandler) GetScheduleGet() http.HandlerFunc {
 			NextActionTimes     []time.Time  `json:"nextActionTimes"`
 			Paused              bool         `json:"paused"`
 			LastExecutionStatus string       `json:"lastExecutionStatus"`
+			FeedToken           string       `json:"feedToken"`
 		}
 	)
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -164,9 +185,17 @@ func (h *ScheduleHandler) GetScheduleGet() http.HandlerFunc {
 		}
 
 		for _, rec := range schedule.Recipients {
+			target := notify.RedactTarget(&notify.Target{
+				Channel:  rec.Channel,
+				Address:  rec.Address,
+				Settings: rec.Settings,
+			})
 			recipients = append(recipients, &recipient{
-				ID:      rec.ID,
-				Address: rec.Address,
+				ID:       rec.ID,
+				Channel:  rec.Channel,
+				Address:  target.Address,
+				Settings: target.Settings,
+				URL:      notify.TargetURL(target),
 			})
 		}
 
@@ -183,6 +212,7 @@ func (h *ScheduleHandler) GetScheduleGet() http.HandlerFunc {
 			NextActionTimes:     nextActionTimes,
 			Paused:              schedule.Paused,
 			LastExecutionStatus: schedule.LastExecutionStatus,
+			FeedToken:           schedule.FeedToken,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -225,8 +255,11 @@ func (h *ScheduleHandler) UpdateSchedulePut() http.HandlerFunc {
 			RestrictSubreddit bool      `json:"restrictSubreddit"`
 		}
 		recipient struct {
-			ID      uuid.UUID `json:"id"`
-			Address string    `json:"address" validate:"required,email"`
+			ID       uuid.UUID       `json:"id"`
+			Channel  string          `json:"channel"`
+			Address  string          `json:"address" validate:"required_without=URL"`
+			Settings json.RawMessage `json:"settings,omitempty"`
+			URL      string          `json:"url"`
 		}
 
 		request struct {
@@ -257,6 +290,24 @@ func (h *ScheduleHandler) UpdateSchedulePut() http.HandlerFunc {
 			return
 		}
 
+		// Recipients are returned with redacted secrets, those sent back unchanged keep the stored ones.
+		current, err := h.scheduleService.GetSchedule(ctx, &reddit.GetScheduleInput{
+			ScheduleID: id,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		stored := make(map[uuid.UUID]*notify.Target, len(current.Recipients))
+		for _, rec := range current.Recipients {
+			stored[rec.ID] = &notify.Target{
+				Channel:  rec.Channel,
+				Address:  rec.Address,
+				Settings: rec.Settings,
+			}
+		}
+
 		var (
 			subreddits = make([]*reddit.Subreddit, 0, len(req.Subreddits))
 			recipients = make([]*reddit.Recipient, 0, len(req.Recipients))
@@ -273,9 +324,27 @@ func (h *ScheduleHandler) UpdateSchedulePut() http.HandlerFunc {
 		}
 
 		for _, rec := range req.Recipients {
+			target, err := recipientTarget(rec.Channel, rec.Address, rec.Settings, rec.URL)
+			if err == nil {
+				if original, ok := stored[rec.ID]; ok {
+					target = notify.RestoreSecrets(target, original)
+				}
+				if notify.IsRedacted(target) {
+					err = errors.New("recipient contains redacted secrets, they have to be sent in full")
+				} else {
+					err = notify.ValidateTarget(target)
+				}
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			recipients = append(recipients, &reddit.Recipient{
-				ID:      rec.ID,
-				Address: rec.Address,
+				ID:       rec.ID,
+				Channel:  target.Channel,
+				Address:  target.Address,
+				Settings: target.Settings,
 			})
 		}
 
@@ -307,8 +376,11 @@ func (h *ScheduleHandler) ListSchedulesGet() http.HandlerFunc {
 		}
 
 		recipient struct {
-			ID      uuid.UUID `json:"id"`
-			Address string    `json:"address" validate:"required,email"`
+			ID       uuid.UUID       `json:"id"`
+			Channel  string          `json:"channel"`
+			Address  string          `json:"address" validate:"required"`
+			Settings json.RawMessage `json:"settings,omitempty"`
+			URL      string          `json:"url,omitempty"`
 		}
 
 		scheduleForList struct {
@@ -350,9 +422,17 @@ func (h *ScheduleHandler) ListSchedulesGet() http.HandlerFunc {
 			}
 
 			for _, rec := range sched.Recipients {
+				target := notify.RedactTarget(&notify.Target{
+					Channel:  rec.Channel,
+					Address:  rec.Address,
+					Settings: rec.Settings,
+				})
 				recipients = append(recipients, &recipient{
-					ID:      rec.ID,
-					Address: rec.Address,
+					ID:       rec.ID,
+					Channel:  rec.Channel,
+					Address:  target.Address,
+					Settings: target.Settings,
+					URL:      notify.TargetURL(target),
 				})
 			}
 
@@ -374,3 +454,96 @@ func (h *ScheduleHandler) ListSchedulesGet() http.HandlerFunc {
 		}
 	}
 }
+
+func (h *ScheduleHandler) ListDeliveriesGet() http.HandlerFunc {
+	const defaultLimit = 50
+
+	type (
+		delivery struct {
+			ID          uuid.UUID `json:"id"`
+			RecipientID uuid.UUID `json:"recipientID"`
+			Channel     string    `json:"channel"`
+			Address     string    `json:"address"`
+			Provider    string    `json:"provider"`
+			MessageID   string    `json:"messageID"`
+			PostIDs     []string  `json:"postIDs"`
+			Status      string    `json:"status"`
+			Error       string    `json:"error,omitempty"`
+			CreatedAt   time.Time `json:"createdAt"`
+		}
+
+		response struct {
+			Deliveries []*delivery `json:"deliveries"`
+		}
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		in := &reddit.ListDeliveriesInput{
+			ScheduleID: id,
+			Limit:      limit,
+		}
+
+		if err = h.validator.Struct(in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		list, err := h.scheduleService.ListDeliveries(ctx, in)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var deliveries = make([]*delivery, 0, len(list.Deliveries))
+		for _, d := range list.Deliveries {
+			// Addresses are stored redacted already, redacting them again is a second line of defence.
+			target := notify.RedactTarget(&notify.Target{Channel: d.Channel, Address: d.Address})
+			deliveries = append(deliveries, &delivery{
+				ID:          d.ID,
+				RecipientID: d.RecipientID,
+				Channel:     d.Channel,
+				Address:     target.Address,
+				Provider:    d.Provider,
+				MessageID:   d.MessageID,
+				PostIDs:     d.PostIDs,
+				Status:      d.Status,
+				Error:       d.Error,
+				CreatedAt:   d.CreatedAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err = json.NewEncoder(w).Encode(response{Deliveries: deliveries}); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
+
+// recipientTarget returns the target of a recipient, configured either by a notification URL or by channel, address
+// and settings.
+func recipientTarget(channel, address string, settings json.RawMessage, rawURL string) (*notify.Target, error) {
+	if rawURL == "" {
+		return &notify.Target{Channel: channel, Address: address, Settings: settings}, nil
+	}
+	if channel != "" || address != "" || len(settings) > 0 {
+		return nil, errors.New("url is mutually exclusive with channel, address and settings")
+	}
+
+	return notify.ParseURL(rawURL)
+}
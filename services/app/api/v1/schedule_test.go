@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeService implements the methods of reddit.Servicer a test sets, calling any other one panics.
+type fakeService struct {
+	reddit.Servicer
+	listDeliveries func(ctx context.Context, in *reddit.ListDeliveriesInput) (*reddit.ListDeliveriesOutput, error)
+	getFeed        func(ctx context.Context, in *reddit.GetFeedInput) (*reddit.GetFeedOutput, error)
+}
+
+func (s *fakeService) ListDeliveries(ctx context.Context, in *reddit.ListDeliveriesInput) (*reddit.ListDeliveriesOutput, error) {
+	return s.listDeliveries(ctx, in)
+}
+
+func (s *fakeService) GetFeed(ctx context.Context, in *reddit.GetFeedInput) (*reddit.GetFeedOutput, error) {
+	return s.getFeed(ctx, in)
+}
+
+// serveHandler routes a single request to handler, registered under pattern so URL parameters resolve.
+func serveHandler(method, pattern string, handler http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Method(method, pattern, handler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+
+	return rec
+}
+
+func newTestScheduleHandler(service reddit.Servicer) *ScheduleHandler {
+	return NewScheduleHandler(service, validator.New(validator.WithRequiredStructEnabled()))
+}
+
+func TestListDeliveriesGet(t *testing.T) {
+	scheduleID := uuid.MustParse("0199f2a0-0000-7000-8000-000000000001")
+
+	tests := []struct {
+		name      string
+		path      string
+		err       error
+		status    int
+		wantLimit int
+	}{
+		{
+			name:      "default limit",
+			path:      "/v1/schedule/" + scheduleID.String() + "/deliveries",
+			status:    http.StatusOK,
+			wantLimit: 50,
+		},
+		{
+			name:      "limit",
+			path:      "/v1/schedule/" + scheduleID.String() + "/deliveries?limit=500",
+			status:    http.StatusOK,
+			wantLimit: 500,
+		},
+		{
+			name:   "invalid id",
+			path:   "/v1/schedule/nope/deliveries",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "limit not a number",
+			path:   "/v1/schedule/" + scheduleID.String() + "/deliveries?limit=ten",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "limit too small",
+			path:   "/v1/schedule/" + scheduleID.String() + "/deliveries?limit=0",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "limit too large",
+			path:   "/v1/schedule/" + scheduleID.String() + "/deliveries?limit=501",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:      "service error",
+			path:      "/v1/schedule/" + scheduleID.String() + "/deliveries",
+			err:       errors.New("database unavailable"),
+			status:    http.StatusInternalServerError,
+			wantLimit: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLimit int
+			service := &fakeService{
+				listDeliveries: func(_ context.Context, in *reddit.ListDeliveriesInput) (*reddit.ListDeliveriesOutput, error) {
+					if in.ScheduleID != scheduleID {
+						t.Errorf("schedule id = %s, want %s", in.ScheduleID, scheduleID)
+					}
+					gotLimit = in.Limit
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &reddit.ListDeliveriesOutput{}, nil
+				},
+			}
+
+			rec := serveHandler(http.MethodGet, "/v1/schedule/{id}/deliveries", newTestScheduleHandler(service).ListDeliveriesGet(),
+				httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestListDeliveriesGetResponse(t *testing.T) {
+	service := &fakeService{
+		listDeliveries: func(context.Context, *reddit.ListDeliveriesInput) (*reddit.ListDeliveriesOutput, error) {
+			return &reddit.ListDeliveriesOutput{
+				Deliveries: []*reddit.Delivery{
+					{
+						Channel:   "email",
+						Address:   "alice@test.mail",
+						Provider:  "smtp",
+						MessageID: "<1@example.com>",
+						PostIDs:   []string{"1o2abcd"},
+						Status:    "sent",
+					},
+					{
+						Channel: "discord",
+						Address: "https://discord.com/api/webhooks/123/token",
+						Status:  "failed",
+						Error:   "discord: status 404",
+					},
+				},
+			}, nil
+		},
+	}
+
+	rec := serveHandler(http.MethodGet, "/v1/schedule/{id}/deliveries", newTestScheduleHandler(service).ListDeliveriesGet(),
+		httptest.NewRequest(http.MethodGet, "/v1/schedule/"+uuid.NewString()+"/deliveries", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var res struct {
+		Deliveries []struct {
+			Channel   string   `json:"channel"`
+			Address   string   `json:"address"`
+			MessageID string   `json:"messageID"`
+			PostIDs   []string `json:"postIDs"`
+			Status    string   `json:"status"`
+			Error     string   `json:"error"`
+		} `json:"deliveries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(res.Deliveries))
+	}
+
+	sent := res.Deliveries[0]
+	if sent.Channel != "email" || sent.Address != "alice@test.mail" || sent.MessageID != "<1@example.com>" ||
+		len(sent.PostIDs) != 1 || sent.Status != "sent" {
+		t.Errorf("unexpected delivery %+v", sent)
+	}
+
+	failed := res.Deliveries[1]
+	if failed.Address != "https://discord.com/api/webhooks/123/****" {
+		t.Errorf("address = %q, want the webhook token redacted", failed.Address)
+	}
+	if failed.Status != "failed" || failed.Error != "discord: status 404" {
+		t.Errorf("unexpected delivery %+v", failed)
+	}
+}
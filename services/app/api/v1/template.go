@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxTemplateBodySize leaves room for the JSON escaping of a source at its limit of 64 Ki characters.
+const maxTemplateBodySize = 1 << 20
+
+type emailTemplate struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// EmailTemplatesPost uploads a new version of a named email template. It's validated by rendering it with sample
+// posts, but only used for the digests once it's activated.
+func (h *ScheduleHandler) EmailTemplatesPost() http.HandlerFunc {
+	type request struct {
+		Name   string `json:"name" validate:"required,max=64"`
+		Source string `json:"source" validate:"required,max=65536"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req request
+
+		if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTemplateBodySize)).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err = h.validator.Struct(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := h.scheduleService.CreateEmailTemplate(ctx, &reddit.CreateEmailTemplateInput{
+			ScheduleID: id,
+			Name:       req.Name,
+			Source:     req.Source,
+		})
+		if err != nil {
+			writeEmailTemplateError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err = json.NewEncoder(w).Encode(&emailTemplate{
+			ID:        res.Template.ID,
+			Name:      res.Template.Name,
+			Version:   res.Template.Version,
+			Active:    res.Template.Active,
+			CreatedAt: res.Template.CreatedAt,
+		}); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
+
+func (h *ScheduleHandler) EmailTemplatesGet() http.HandlerFunc {
+	type response struct {
+		Templates []*emailTemplate `json:"templates"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		list, err := h.scheduleService.ListEmailTemplates(ctx, &reddit.ListEmailTemplatesInput{
+			ScheduleID: id,
+		})
+		if err != nil {
+			writeEmailTemplateError(w, err)
+			return
+		}
+
+		tmpls := make([]*emailTemplate, 0, len(list.Templates))
+		for _, t := range list.Templates {
+			tmpls = append(tmpls, &emailTemplate{
+				ID:        t.ID,
+				Name:      t.Name,
+				Version:   t.Version,
+				Active:    t.Active,
+				CreatedAt: t.CreatedAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response{Templates: tmpls}); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
+
+// EmailTemplateActivatePost switches the digests of the schedule to a version.
+func (h *ScheduleHandler) EmailTemplateActivatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		templateID, err := uuid.Parse(chi.URLParam(r, "templateID"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err = h.scheduleService.ActivateEmailTemplate(ctx, &reddit.ActivateEmailTemplateInput{
+			ScheduleID: id,
+			TemplateID: templateID,
+		}); err != nil {
+			writeEmailTemplateError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// EmailTemplateActiveDelete switches the digests of the schedule back to the default templates.
+func (h *ScheduleHandler) EmailTemplateActiveDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err = h.scheduleService.ActivateEmailTemplate(ctx, &reddit.ActivateEmailTemplateInput{
+			ScheduleID: id,
+		}); err != nil {
+			writeEmailTemplateError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// EmailTemplatePreviewGet renders a version with sample posts. The HTML is the schedule owner's, so it's sandboxed
+// rather than rendered as a page of the API.
+func (h *ScheduleHandler) EmailTemplatePreviewGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		templateID, err := uuid.Parse(chi.URLParam(r, "templateID"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := h.scheduleService.PreviewEmailTemplate(ctx, &reddit.PreviewEmailTemplateInput{
+			ScheduleID: id,
+			TemplateID: templateID,
+		})
+		if err != nil {
+			writeEmailTemplateError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err = w.Write([]byte(res.HTML)); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
+
+func writeEmailTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, reddit.ErrInvalidTemplate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, reddit.ErrScheduleNotFound), errors.Is(err, reddit.ErrTemplateNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
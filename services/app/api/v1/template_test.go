@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// templateStore holds the stored versions for the email template tests, keyed by ID.
+type templateStore struct {
+	persistence.Persistence
+	templates map[uuid.UUID]*persistence.EmailTemplate
+}
+
+func (s *templateStore) GetEmailTemplate(_ context.Context, in *persistence.GetEmailTemplateInput) (*persistence.GetEmailTemplateOutput, error) {
+	tmpl, ok := s.templates[in.ID]
+	if !ok || tmpl.ConfigurationID != in.ConfigurationID {
+		return nil, persistence.ErrNotFound
+	}
+	return &persistence.GetEmailTemplateOutput{Template: tmpl}, nil
+}
+
+func TestEmailTemplatePreviewGet(t *testing.T) {
+	var (
+		scheduleID = uuid.MustParse("0199f2a0-0000-7000-8000-000000000001")
+		validID    = uuid.MustParse("019a0c1e-0000-7000-8000-000000000001")
+		brokenID   = uuid.MustParse("019a0c1e-0000-7000-8000-000000000002")
+		largeID    = uuid.MustParse("019a0c1e-0000-7000-8000-000000000003")
+	)
+
+	db := &templateStore{templates: map[uuid.UUID]*persistence.EmailTemplate{
+		validID: {
+			ID:              validID,
+			ConfigurationID: scheduleID,
+			Source:          `{{define "post"}}<p class="custom">{{.Title}}</p>{{end}}`,
+		},
+		// Stored before the field was checked, it fails to render now.
+		brokenID: {
+			ID:              brokenID,
+			ConfigurationID: scheduleID,
+			Source:          `{{define "post"}}{{.Author}}{{end}}`,
+		},
+		largeID: {
+			ID:              largeID,
+			ConfigurationID: scheduleID,
+			Source:          `{{define "email"}}{{range 100000000}}<p>too much</p>{{end}}{{end}}`,
+		},
+	}}
+
+	service, err := reddit.NewService(db, nil, validator.New(validator.WithRequiredStructEnabled()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newTestScheduleHandler(service).EmailTemplatePreviewGet()
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{
+			name:   "preview",
+			path:   "/v1/schedule/" + scheduleID.String() + "/templates/" + validID.String() + "/preview",
+			status: http.StatusOK,
+			body:   `<p class="custom">Example post with a thumbnail</p>`,
+		},
+		{
+			name:   "render error",
+			path:   "/v1/schedule/" + scheduleID.String() + "/templates/" + brokenID.String() + "/preview",
+			status: http.StatusBadRequest,
+			body:   "invalid email template",
+		},
+		{
+			name:   "output too large",
+			path:   "/v1/schedule/" + scheduleID.String() + "/templates/" + largeID.String() + "/preview",
+			status: http.StatusBadRequest,
+			body:   "template output is too large",
+		},
+		{
+			name:   "unknown template",
+			path:   "/v1/schedule/" + scheduleID.String() + "/templates/" + uuid.Nil.String() + "/preview",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "template of another schedule",
+			path:   "/v1/schedule/" + uuid.Nil.String() + "/templates/" + validID.String() + "/preview",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "invalid template id",
+			path:   "/v1/schedule/" + scheduleID.String() + "/templates/nope/preview",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveHandler(http.MethodGet, "/v1/schedule/{id}/templates/{templateID}/preview", handler,
+				httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("body doesn't contain %q: %s", tt.body, rec.Body.String())
+			}
+
+			if tt.status == http.StatusOK {
+				if got := rec.Header().Get("Content-Security-Policy"); got != "sandbox" {
+					t.Errorf("Content-Security-Policy = %q, want sandbox", got)
+				}
+				if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+					t.Errorf("Content-Type = %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/webhook"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxWebhookBodySize is far above any bounce or complaint notification the providers send.
+const maxWebhookBodySize = 1 << 20
+
+type WebhookHandler struct {
+	webhookService webhook.Servicer
+}
+
+func NewWebhookHandler(
+	webhookService webhook.Servicer,
+) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) SESPost() http.HandlerFunc {
+	return h.ingest(h.webhookService.IngestSES)
+}
+
+func (h *WebhookHandler) ResendPost() http.HandlerFunc {
+	return h.ingest(h.webhookService.IngestResend)
+}
+
+func (h *WebhookHandler) MailerSendPost() http.HandlerFunc {
+	return h.ingest(h.webhookService.IngestMailerSend)
+}
+
+// ingest passes the raw request to the service, the signatures are computed over the exact bytes that were sent.
+func (h *WebhookHandler) ingest(
+	fn func(ctx context.Context, in *webhook.IngestInput) (*webhook.IngestOutput, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		res, err := fn(ctx, &webhook.IngestInput{
+			Header: r.Header,
+			Body:   body,
+		})
+		switch {
+		case errors.Is(err, webhook.ErrDisabled):
+			http.NotFound(w, r)
+			return
+		case errors.Is(err, webhook.ErrInvalidSignature):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case errors.Is(err, webhook.ErrInvalidPayload):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if res.Suppressed > 0 {
+			slog.Info("suppressed recipients", slog.Int("events", res.Events), slog.Int64("suppressed", res.Suppressed))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(res); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
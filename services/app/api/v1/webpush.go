@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+)
+
+type WebPushHandler struct {
+	scheduleService reddit.Servicer
+	// publicKey is empty while Web Push isn't configured.
+	publicKey string
+}
+
+func NewWebPushHandler(
+	scheduleService reddit.Servicer,
+	publicKey string,
+) *WebPushHandler {
+	return &WebPushHandler{
+		scheduleService: scheduleService,
+		publicKey:       publicKey,
+	}
+}
+
+// PublicKeyGet returns the applicationServerKey browsers pass to PushManager.subscribe().
+func (h *WebPushHandler) PublicKeyGet() http.HandlerFunc {
+	type response struct {
+		PublicKey string `json:"publicKey"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.publicKey == "" {
+			http.Error(w, "web push is not configured", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response{PublicKey: h.publicKey}); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
+
+// SubscriptionPost adds the push subscription of a browser as recipient of a schedule. The request is the
+// subscription as serialized by PushSubscription.toJSON(), with an optional mode.
+func (h *WebPushHandler) SubscriptionPost() http.HandlerFunc {
+	type (
+		keys struct {
+			P256DH string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		}
+
+		request struct {
+			Endpoint string `json:"endpoint"`
+			Keys     keys   `json:"keys"`
+			Mode     string `json:"mode"`
+		}
+
+		response struct {
+			RecipientID uuid.UUID `json:"recipientID"`
+		}
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if h.publicKey == "" {
+			http.Error(w, "web push is not configured", http.StatusNotFound)
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req request
+
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := h.scheduleService.SubscribeWebPush(ctx, &reddit.SubscribeWebPushInput{
+			ScheduleID: id,
+			Endpoint:   req.Endpoint,
+			P256DH:     req.Keys.P256DH,
+			Auth:       req.Keys.Auth,
+			Mode:       req.Mode,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, reddit.ErrInvalidSubscription):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, reddit.ErrScheduleNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, reddit.ErrRecipientLimit):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if res.Created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		if err = json.NewEncoder(w).Encode(response{RecipientID: res.RecipientID}); err != nil {
+			slog.Error("write response", slog.Any("error", err))
+		}
+	}
+}
@@ -3,8 +3,10 @@ package app
 import (
 	"context"
 	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
 	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
 	"github.com/forbiddencoding/reddit-post-notifier/services/app/reddit"
+	"github.com/forbiddencoding/reddit-post-notifier/services/app/webhook"
 	"github.com/go-playground/validator/v10"
 	"go.temporal.io/sdk/client"
 )
@@ -15,7 +17,10 @@ type App struct {
 	persistence persistence.Persistence
 	validator   *validator.Validate
 	// ---
-	redditService reddit.Servicer
+	redditService  reddit.Servicer
+	webhookService webhook.Servicer
+	// vapid is nil while Web Push isn't configured.
+	vapid *notify.VAPID
 }
 
 func New(
@@ -29,12 +34,27 @@ func New(
 		return nil, err
 	}
 
+	webhookService, err := webhook.NewService(persistence, &config.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var vapid *notify.VAPID
+	if config.Notify.WebPushPrivateKey != "" {
+		vapid, err = notify.NewVAPID(config.Notify.WebPushPublicKey, config.Notify.WebPushPrivateKey, config.Notify.WebPushSubject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &App{
-		config:        config,
-		temporal:      temporalClient,
-		persistence:   persistence,
-		validator:     validator,
-		redditService: redditService,
+		config:         config,
+		temporal:       temporalClient,
+		persistence:    persistence,
+		validator:      validator,
+		redditService:  redditService,
+		webhookService: webhookService,
+		vapid:          vapid,
 	}, nil
 }
 
@@ -50,6 +70,18 @@ func (a *App) ScheduleService() reddit.Servicer {
 	return a.redditService
 }
 
+func (a *App) WebhookService() webhook.Servicer {
+	return a.webhookService
+}
+
+// WebPushPublicKey is the key browsers subscribe with, empty while Web Push isn't configured.
+func (a *App) WebPushPublicKey() string {
+	if a.vapid == nil {
+		return ""
+	}
+	return a.vapid.PublicKey()
+}
+
 func (a *App) Validator() *validator.Validate {
 	return a.validator
 }
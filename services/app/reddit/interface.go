@@ -1,14 +1,20 @@
 package reddit
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"time"
 )
 
 type (
 	Recipient struct {
-		ID      uuid.UUID `json:"id"`
-		Address string    `json:"address" validate:"required,email"`
+		ID uuid.UUID `json:"id"`
+		// Channel defaults to email.
+		Channel string `json:"channel"`
+		Address string `json:"address" validate:"required"`
+		// Settings are the channel specific options, e.g. the secret of a webhook.
+		Settings json.RawMessage `json:"settings,omitempty"`
 	}
 
 	Subreddit struct {
@@ -41,6 +47,8 @@ type (
 		Subreddits []*Subreddit `json:"subreddits"`
 		Schedule   string       `json:"schedule"` // Cron string
 		Recipients []*Recipient `json:"recipients"`
+		// FeedToken authorizes requests for the schedule's feeds.
+		FeedToken string `json:"feedToken"`
 		// --- Data from temporal
 		NextActionTimes     []time.Time `json:"nextActionTimes"`
 		Paused              bool        `json:"paused"`
@@ -79,4 +87,128 @@ type (
 	ListSchedulesOutput struct {
 		Schedules []*Schedule `json:"schedules"`
 	}
+
+	Delivery struct {
+		ID          uuid.UUID `json:"id"`
+		RecipientID uuid.UUID `json:"recipientID"`
+		Channel     string    `json:"channel"`
+		Address     string    `json:"address"`
+		Provider    string    `json:"provider"`
+		MessageID   string    `json:"messageID"`
+		PostIDs     []string  `json:"postIDs"`
+		Status      string    `json:"status"`
+		Error       string    `json:"error,omitempty"`
+		CreatedAt   time.Time `json:"createdAt"`
+	}
+
+	ListDeliveriesInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		Limit      int       `json:"limit" validate:"min=1,max=500"`
+	}
+
+	ListDeliveriesOutput struct {
+		Deliveries []*Delivery `json:"deliveries"`
+	}
+
+	GetFeedInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		Token      string    `json:"token"`
+	}
+
+	GetFeedOutput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		Keyword    string    `json:"keyword"`
+		// Updated is the creation time of the newest post, or the creation time of the schedule if there's none.
+		Updated time.Time   `json:"updated"`
+		Posts   []*FeedPost `json:"posts"`
+	}
+
+	FeedPost struct {
+		ID        string    `json:"id"`
+		Title     string    `json:"title"`
+		URL       string    `json:"url"`
+		Subreddit string    `json:"subreddit"`
+		NSFW      bool      `json:"nsfw"`
+		Spoiler   bool      `json:"spoiler"`
+		Ups       int       `json:"ups"`
+		Downs     int       `json:"downs"`
+		Thumbnail string    `json:"thumbnail"`
+		Permalink string    `json:"permalink"`
+		Created   time.Time `json:"created"`
+	}
+
+	// SubscribeWebPushInput is the push subscription of a browser, the keys as returned by PushSubscription.toJSON().
+	SubscribeWebPushInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		Endpoint   string    `json:"endpoint"`
+		P256DH     string    `json:"p256dh"`
+		Auth       string    `json:"auth"`
+		Mode       string    `json:"mode,omitempty"`
+	}
+
+	SubscribeWebPushOutput struct {
+		RecipientID uuid.UUID `json:"recipientID"`
+		// Created is false if the browser was subscribed already.
+		Created bool `json:"created"`
+	}
+
+	EmailTemplate struct {
+		ID        uuid.UUID `json:"id"`
+		Name      string    `json:"name"`
+		Version   int       `json:"version"`
+		Active    bool      `json:"active"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// CreateEmailTemplateInput is a new version of a named template. The source defines "email", "post" or both,
+	// templates it doesn't define are taken from the defaults.
+	CreateEmailTemplateInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		Name       string    `json:"name" validate:"required,max=64"`
+		Source     string    `json:"source" validate:"required,max=65536"`
+	}
+
+	CreateEmailTemplateOutput struct {
+		Template *EmailTemplate `json:"template"`
+	}
+
+	ListEmailTemplatesInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+	}
+
+	ListEmailTemplatesOutput struct {
+		Templates []*EmailTemplate `json:"templates"`
+	}
+
+	ActivateEmailTemplateInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		// TemplateID is the version to activate, uuid.Nil restores the default templates.
+		TemplateID uuid.UUID `json:"templateID"`
+	}
+
+	ActivateEmailTemplateOutput struct {
+	}
+
+	PreviewEmailTemplateInput struct {
+		ScheduleID uuid.UUID `json:"scheduleID"`
+		TemplateID uuid.UUID `json:"templateID"`
+	}
+
+	PreviewEmailTemplateOutput struct {
+		// HTML is the digest of the sample posts rendered with the version.
+		HTML string `json:"html"`
+	}
+)
+
+var (
+	// ErrFeedNotFound is returned for unknown schedules as well as wrong tokens, so the feeds can't be enumerated.
+	ErrFeedNotFound     = errors.New("feed not found")
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrInvalidSubscription wraps the reason a push subscription was rejected.
+	ErrInvalidSubscription = errors.New("invalid push subscription")
+	// ErrRecipientLimit is returned if a recipient would exceed the recipients a schedule can have.
+	ErrRecipientLimit = errors.New("schedule has the maximum number of recipients")
+	// ErrInvalidTemplate wraps the reason an email template was rejected.
+	ErrInvalidTemplate  = errors.New("invalid email template")
+	ErrTemplateNotFound = errors.New("email template not found")
 )
@@ -2,15 +2,31 @@ package reddit
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
 	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
 	"github.com/forbiddencoding/reddit-post-notifier/services/digester"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/client"
+	"html/template"
 	"sort"
 	"strings"
+	"time"
+)
+
+const (
+	// feedMaxEntries is the number of posts in a feed.
+	feedMaxEntries = 50
+	// maxRecipients matches the limit of the schedule API.
+	maxRecipients = 10
 )
 
 type (
@@ -20,12 +36,22 @@ type (
 		UpdateSchedule(ctx context.Context, in *UpdateScheduleInput) (*UpdateScheduleOutput, error)
 		DeleteSchedule(ctx context.Context, in *DeleteScheduleInput) (*DeleteScheduleOutput, error)
 		ListSchedules(ctx context.Context, in *ListSchedulesInput) (*ListSchedulesOutput, error)
+		ListDeliveries(ctx context.Context, in *ListDeliveriesInput) (*ListDeliveriesOutput, error)
+		GetFeed(ctx context.Context, in *GetFeedInput) (*GetFeedOutput, error)
+		SubscribeWebPush(ctx context.Context, in *SubscribeWebPushInput) (*SubscribeWebPushOutput, error)
+		CreateEmailTemplate(ctx context.Context, in *CreateEmailTemplateInput) (*CreateEmailTemplateOutput, error)
+		ListEmailTemplates(ctx context.Context, in *ListEmailTemplatesInput) (*ListEmailTemplatesOutput, error)
+		ActivateEmailTemplate(ctx context.Context, in *ActivateEmailTemplateInput) (*ActivateEmailTemplateOutput, error)
+		PreviewEmailTemplate(ctx context.Context, in *PreviewEmailTemplateInput) (*PreviewEmailTemplateOutput, error)
 	}
 
 	Service struct {
 		db             persistence.Persistence
 		temporalClient client.Client
 		validator      *validator.Validate
+		// emailTemplates are the default templates custom ones are validated on top of. They're never executed, only
+		// cloned.
+		emailTemplates *template.Template
 	}
 )
 
@@ -36,10 +62,16 @@ func NewService(
 	temporalClient client.Client,
 	validator *validator.Validate,
 ) (Servicer, error) {
+	emailTemplates, err := templates.Default()
+	if err != nil {
+		return nil, fmt.Errorf("parse email templates: %w", err)
+	}
+
 	return &Service{
 		db:             db,
 		temporalClient: temporalClient,
 		validator:      validator,
+		emailTemplates: emailTemplates,
 	}, nil
 }
 
@@ -53,6 +85,11 @@ func (s *Service) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (
 		return nil, fmt.Errorf("generate ID: %w", err)
 	}
 
+	feedToken, err := newFeedToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate feed token: %w", err)
+	}
+
 	var (
 		recipients = make([]*persistence.CreateScheduleRecipient, 0, len(in.Recipients))
 		subreddits = make([]*persistence.CreateScheduleSubreddit, 0, len(in.Subreddits))
@@ -65,8 +102,10 @@ func (s *Service) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (
 		}
 
 		recipients = append(recipients, &persistence.CreateScheduleRecipient{
-			ID:      recipientID,
-			Address: recipient.Address,
+			ID:       recipientID,
+			Channel:  recipientChannel(recipient),
+			Address:  recipient.Address,
+			Settings: recipientSettings(recipient),
 		})
 	}
 
@@ -91,6 +130,7 @@ func (s *Service) CreateSchedule(ctx context.Context, in *CreateScheduleInput) (
 		ID:         id,
 		Keyword:    in.Keyword,
 		Schedule:   in.Schedule,
+		FeedToken:  feedToken,
 		Recipients: recipients,
 		Subreddits: subreddits,
 	})
@@ -145,8 +185,10 @@ func (s *Service) GetSchedule(ctx context.Context, in *GetScheduleInput) (*GetSc
 
 	for _, recipient := range schedule.Recipients {
 		recipients = append(recipients, &Recipient{
-			ID:      recipient.ID,
-			Address: recipient.Address,
+			ID:       recipient.ID,
+			Channel:  recipient.Channel,
+			Address:  recipient.Address,
+			Settings: recipient.Settings,
 		})
 	}
 
@@ -180,6 +222,7 @@ func (s *Service) GetSchedule(ctx context.Context, in *GetScheduleInput) (*GetSc
 		Subreddits:          subreddits,
 		Schedule:            schedule.Schedule,
 		Recipients:          recipients,
+		FeedToken:           schedule.FeedToken,
 		NextActionTimes:     desc.Info.NextActionTimes,
 		Paused:              desc.Schedule.State.Paused,
 		LastExecutionStatus: status,
@@ -209,8 +252,10 @@ func (s *Service) UpdateSchedule(ctx context.Context, in *UpdateScheduleInput) (
 		}
 
 		recipients = append(recipients, &persistence.Recipient{
-			ID:      id,
-			Address: recipient.Address,
+			ID:       id,
+			Channel:  recipientChannel(recipient),
+			Address:  recipient.Address,
+			Settings: recipientSettings(recipient),
 		})
 	}
 
@@ -309,8 +354,10 @@ func (s *Service) ListSchedules(ctx context.Context, in *ListSchedulesInput) (*L
 		recipients := make([]*Recipient, 0, len(schedule.Recipients))
 		for _, recipient := range schedule.Recipients {
 			recipients = append(recipients, &Recipient{
-				ID:      recipient.ID,
-				Address: recipient.Address,
+				ID:       recipient.ID,
+				Channel:  recipient.Channel,
+				Address:  recipient.Address,
+				Settings: recipient.Settings,
 			})
 		}
 
@@ -331,3 +378,172 @@ func (s *Service) ListSchedules(ctx context.Context, in *ListSchedulesInput) (*L
 		Schedules: schedules,
 	}, nil
 }
+
+func (s *Service) ListDeliveries(ctx context.Context, in *ListDeliveriesInput) (*ListDeliveriesOutput, error) {
+	if err := s.validator.Struct(in); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.ListDeliveries(ctx, &persistence.ListDeliveriesInput{
+		ConfigurationID: in.ScheduleID,
+		Limit:           in.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(res.Deliveries))
+	for _, d := range res.Deliveries {
+		deliveries = append(deliveries, &Delivery{
+			ID:          d.ID,
+			RecipientID: d.RecipientID,
+			Channel:     d.Channel,
+			Address:     d.Address,
+			Provider:    d.Provider,
+			MessageID:   d.MessageID,
+			PostIDs:     d.PostIDs,
+			Status:      d.Status,
+			Error:       d.Error,
+			CreatedAt:   d.CreatedAt,
+		})
+	}
+
+	return &ListDeliveriesOutput{
+		Deliveries: deliveries,
+	}, nil
+}
+
+// GetFeed returns the newest posts of a schedule for its feeds. Unknown schedules and wrong tokens both result in
+// ErrFeedNotFound.
+func (s *Service) GetFeed(ctx context.Context, in *GetFeedInput) (*GetFeedOutput, error) {
+	res, err := s.db.GetFeed(ctx, &persistence.GetFeedInput{
+		ConfigurationID: in.ScheduleID,
+		Limit:           feedMaxEntries,
+	})
+	if err != nil {
+		if errors.Is(err, persistence.ErrNotFound) {
+			return nil, ErrFeedNotFound
+		}
+		return nil, err
+	}
+
+	if in.Token == "" || subtle.ConstantTimeCompare([]byte(in.Token), []byte(res.FeedToken)) != 1 {
+		return nil, ErrFeedNotFound
+	}
+
+	out := &GetFeedOutput{
+		ScheduleID: in.ScheduleID,
+		Keyword:    res.Keyword,
+		Updated:    time.Unix(in.ScheduleID.Time().UnixTime()).UTC(),
+		Posts:      make([]*FeedPost, 0, len(res.Posts)),
+	}
+
+	for _, p := range res.Posts {
+		created := p.CreatedTime
+		if created.IsZero() {
+			// Posts queued before the creation time was stored only have it formatted in their data.
+			if t, err := time.Parse(time.RFC822, p.Post.Created); err == nil {
+				created = t
+			} else if p.SentAt != nil {
+				created = *p.SentAt
+			}
+		}
+
+		if created.After(out.Updated) {
+			out.Updated = created.UTC()
+		}
+
+		out.Posts = append(out.Posts, &FeedPost{
+			ID:        p.Post.ID,
+			Title:     p.Post.Title,
+			URL:       p.Post.URL,
+			Subreddit: p.Post.Subreddit,
+			NSFW:      p.Post.NSFW,
+			Spoiler:   p.Post.Spoiler,
+			Ups:       p.Post.Ups,
+			Downs:     p.Post.Downs,
+			Thumbnail: p.Post.Thumbnail,
+			Permalink: p.Post.Permalink,
+			Created:   created.UTC(),
+		})
+	}
+
+	return out, nil
+}
+
+// SubscribeWebPush adds the push subscription of a browser as recipient of a schedule. Browsers subscribe again with
+// the same endpoint after their keys changed, that updates the existing recipient.
+func (s *Service) SubscribeWebPush(ctx context.Context, in *SubscribeWebPushInput) (*SubscribeWebPushOutput, error) {
+	keys := map[string]string{
+		"p256dh": in.P256DH,
+		"auth":   in.Auth,
+	}
+	if in.Mode != "" {
+		keys["mode"] = in.Mode
+	}
+
+	settings, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = notify.ValidateTarget(&notify.Target{
+		Channel:  notify.ChannelWebPush,
+		Address:  in.Endpoint,
+		Settings: settings,
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSubscription, err)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("generate recipient ID: %w", err)
+	}
+
+	res, err := s.db.AddRecipient(ctx, &persistence.AddRecipientInput{
+		ID:              id,
+		ConfigurationID: in.ScheduleID,
+		Channel:         notify.ChannelWebPush,
+		Address:         in.Endpoint,
+		Settings:        settings,
+		MaxRecipients:   maxRecipients,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, persistence.ErrNotFound):
+			return nil, ErrScheduleNotFound
+		case errors.Is(err, persistence.ErrRecipientLimit):
+			return nil, ErrRecipientLimit
+		}
+		return nil, fmt.Errorf("add recipient: %w", err)
+	}
+
+	return &SubscribeWebPushOutput{
+		RecipientID: res.ID,
+		Created:     res.Created,
+	}, nil
+}
+
+// newFeedToken returns 256 random bits, URL safe since the token is passed in the query of the feed URL.
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func recipientChannel(recipient *Recipient) string {
+	if recipient.Channel == "" {
+		return notify.ChannelEmail
+	}
+	return recipient.Channel
+}
+
+// recipientSettings defaults to an empty object, the column doesn't take null.
+func recipientSettings(recipient *Recipient) json.RawMessage {
+	if len(recipient.Settings) == 0 || string(recipient.Settings) == "null" {
+		return json.RawMessage("{}")
+	}
+	return recipient.Settings
+}
@@ -0,0 +1,139 @@
+package reddit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"github.com/google/uuid"
+	"html/template"
+)
+
+// CreateEmailTemplate stores a new version of a named template of the schedule. The version is only used once it's
+// activated, so a template can be previewed before the digests switch to it.
+func (s *Service) CreateEmailTemplate(ctx context.Context, in *CreateEmailTemplateInput) (*CreateEmailTemplateOutput, error) {
+	if err := s.validator.Struct(in); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	if _, err := s.parseEmailTemplate(ctx, in.Source); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("generate template ID: %w", err)
+	}
+
+	res, err := s.db.CreateEmailTemplate(ctx, &persistence.CreateEmailTemplateInput{
+		ID:              id,
+		ConfigurationID: in.ScheduleID,
+		Name:            in.Name,
+		Source:          in.Source,
+	})
+	if err != nil {
+		if errors.Is(err, persistence.ErrNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("create email template: %w", err)
+	}
+
+	return &CreateEmailTemplateOutput{
+		Template: &EmailTemplate{
+			ID:        id,
+			Name:      in.Name,
+			Version:   res.Version,
+			CreatedAt: res.CreatedAt,
+		},
+	}, nil
+}
+
+func (s *Service) ListEmailTemplates(ctx context.Context, in *ListEmailTemplatesInput) (*ListEmailTemplatesOutput, error) {
+	res, err := s.db.ListEmailTemplates(ctx, &persistence.ListEmailTemplatesInput{
+		ConfigurationID: in.ScheduleID,
+	})
+	if err != nil {
+		if errors.Is(err, persistence.ErrNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+
+	tmpls := make([]*EmailTemplate, 0, len(res.Templates))
+	for _, t := range res.Templates {
+		tmpls = append(tmpls, &EmailTemplate{
+			ID:        t.ID,
+			Name:      t.Name,
+			Version:   t.Version,
+			Active:    t.ID == res.ActiveID,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
+	return &ListEmailTemplatesOutput{
+		Templates: tmpls,
+	}, nil
+}
+
+// ActivateEmailTemplate switches the digests of the schedule to a version, or back to the default templates.
+func (s *Service) ActivateEmailTemplate(ctx context.Context, in *ActivateEmailTemplateInput) (*ActivateEmailTemplateOutput, error) {
+	if _, err := s.db.ActivateEmailTemplate(ctx, &persistence.ActivateEmailTemplateInput{
+		ConfigurationID: in.ScheduleID,
+		ID:              in.TemplateID,
+	}); err != nil {
+		if errors.Is(err, persistence.ErrNotFound) {
+			if in.TemplateID == uuid.Nil {
+				return nil, ErrScheduleNotFound
+			}
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	return &ActivateEmailTemplateOutput{}, nil
+}
+
+// PreviewEmailTemplate renders a version with the sample posts it was validated with.
+func (s *Service) PreviewEmailTemplate(ctx context.Context, in *PreviewEmailTemplateInput) (*PreviewEmailTemplateOutput, error) {
+	res, err := s.db.GetEmailTemplate(ctx, &persistence.GetEmailTemplateInput{
+		ConfigurationID: in.ScheduleID,
+		ID:              in.TemplateID,
+	})
+	if err != nil {
+		if errors.Is(err, persistence.ErrNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	tmpl, err := s.parseEmailTemplate(ctx, res.Template.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err = templates.Render(ctx, &html, tmpl, templates.Sample()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	return &PreviewEmailTemplateOutput{
+		HTML: html.String(),
+	}, nil
+}
+
+// parseEmailTemplate parses source on top of the default templates and renders it with sample posts, most mistakes
+// only show once the template is executed.
+func (s *Service) parseEmailTemplate(ctx context.Context, source string) (*template.Template, error) {
+	tmpl, err := templates.Extend(s.emailTemplates, source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	if err = templates.Validate(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	return tmpl, nil
+}
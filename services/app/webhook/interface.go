@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrDisabled is returned for providers whose webhook isn't configured.
+	ErrDisabled = errors.New("webhook is not configured")
+	// ErrInvalidSignature is returned if the request can't be attributed to the provider.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrInvalidPayload is returned for requests with a valid signature whose body can't be parsed.
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
+
+// Reasons stored with a suppressed recipient.
+const (
+	reasonBounce    = "bounce"
+	reasonComplaint = "complaint"
+)
+
+type (
+	IngestInput struct {
+		Header http.Header
+		Body   []byte
+	}
+
+	IngestOutput struct {
+		// Events is the number of bounce and complaint events in the request.
+		Events int `json:"events"`
+		// Suppressed is the number of recipients that were suppressed because of them.
+		Suppressed int64 `json:"suppressed"`
+	}
+
+	// event is a bounce or complaint that results in the suppression of address.
+	event struct {
+		address string
+		reason  string
+	}
+)
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type mailerSendEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Email struct {
+			Recipient struct {
+				Email string `json:"email"`
+			} `json:"recipient"`
+		} `json:"email"`
+	} `json:"data"`
+}
+
+// verifyMailerSend checks the Signature header, a hex encoded HMAC-SHA256 of the raw body keyed with the signing secret
+// of the webhook.
+func verifyMailerSend(secret string, header http.Header, body []byte) error {
+	signature, err := hex.DecodeString(header.Get("Signature"))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: missing or malformed signature header", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func parseMailerSend(body []byte) ([]*event, error) {
+	var e mailerSendEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	var reason string
+	switch e.Type {
+	case "activity.hard_bounced":
+		reason = reasonBounce
+	case "activity.spam_complaint":
+		reason = reasonComplaint
+	default:
+		return nil, nil
+	}
+
+	if e.Data.Email.Recipient.Email == "" {
+		return nil, fmt.Errorf("%w: event without recipient", ErrInvalidPayload)
+	}
+
+	return []*event{{address: e.Data.Email.Recipient.Email, reason: reason}}, nil
+}
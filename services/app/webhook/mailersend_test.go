@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func hmacHex(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyMailerSend(t *testing.T) {
+	const (
+		secret = "Rx9LlTeUbTyVDUapc7iF7zEzLGpdzzb6"
+		body   = `{"type":"activity.hard_bounced"}`
+	)
+
+	valid := hmacHex(secret, body)
+
+	tests := []struct {
+		name      string
+		signature string
+		body      string
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			signature: valid,
+			body:      body,
+		},
+		{
+			name:      "tampered body",
+			signature: valid,
+			body:      `{"type":"activity.spam_complaint"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "other secret",
+			signature: hmacHex("another secret", body),
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:      "not hex",
+			signature: "not a signature",
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:    "missing",
+			body:    body,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.signature != "" {
+				header.Set("Signature", tt.signature)
+			}
+
+			err := verifyMailerSend(secret, header, []byte(tt.body))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("verifyMailerSend() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+// The payloads follow the examples of https://developers.mailersend.com/api/v1/webhooks.html.
+func TestParseMailerSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []*event
+		wantErr bool
+	}{
+		{
+			name: "hard bounce",
+			body: `{
+  "type": "activity.hard_bounced",
+  "domain_id": "7z3m5jgrogdpyo6n",
+  "created_at": "2022-01-01T12:00:00.000000Z",
+  "webhook_id": "k68zxl2en3lj9pdq",
+  "url": "https://example.com/webhook",
+  "data": {
+    "object": "activity",
+    "id": "62b2e0a8b6d8c0b0e4b6e0a1",
+    "type": "hard_bounced",
+    "created_at": "2022-01-01T12:00:00.000000Z",
+    "email": {
+      "object": "email",
+      "id": "62b2e0a8b6d8c0b0e4b6e0a2",
+      "from": "test@example.com",
+      "subject": "Test subject",
+      "status": "rejected",
+      "tags": null,
+      "headers": null,
+      "recipient": {
+        "object": "recipient",
+        "id": "62b2e0a8b6d8c0b0e4b6e0a3",
+        "email": "test@example.com",
+        "created_at": "2022-01-01T12:00:00.000000Z"
+      }
+    },
+    "morph": {
+      "object": "recipient_bounce",
+      "reason": "Host or domain name not found"
+    }
+  }
+}`,
+			want: []*event{{address: "test@example.com", reason: reasonBounce}},
+		},
+		{
+			name: "spam complaint",
+			body: `{
+  "type": "activity.spam_complaint",
+  "data": {
+    "object": "activity",
+    "type": "spam_complaint",
+    "email": {
+      "object": "email",
+      "recipient": {"object": "recipient", "email": "test@example.com"}
+    },
+    "morph": {"object": "spam_complaint", "reason": null}
+  }
+}`,
+			want: []*event{{address: "test@example.com", reason: reasonComplaint}},
+		},
+		{
+			name: "soft bounce",
+			body: `{"type": "activity.soft_bounced", "data": {"email": {"recipient": {"email": "test@example.com"}}}}`,
+		},
+		{
+			name:    "bounce without recipient",
+			body:    `{"type": "activity.hard_bounced", "data": {"email": {}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			body:    `[]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMailerSend([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMailerSend() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			checkEvents(t, got, tt.want)
+		})
+	}
+}
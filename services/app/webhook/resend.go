@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// svixTolerance is the maximum age of a Svix timestamp, which guards against replayed requests.
+const svixTolerance = 5 * time.Minute
+
+type resendEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		To     []string `json:"to"`
+		Bounce *struct {
+			Type string `json:"type"`
+		} `json:"bounce"`
+	} `json:"data"`
+}
+
+// verifySvix verifies a webhook signed by Svix, which Resend uses for delivery. The signed content is
+// "<svix-id>.<svix-timestamp>.<body>", the svix-signature header carries one or more space separated "v1,<base64>"
+// signatures, one per active secret.
+func verifySvix(secret string, header http.Header, body []byte, now time.Time) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		return fmt.Errorf("decode webhook secret: %w", err)
+	}
+
+	id, timestamp, signatures := header.Get("svix-id"), header.Get("svix-timestamp"), header.Get("svix-signature")
+	if id == "" || timestamp == "" || signatures == "" {
+		return fmt.Errorf("%w: missing svix headers", ErrInvalidSignature)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > svixTolerance || d < -svixTolerance {
+		return fmt.Errorf("%w: timestamp outside of tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, candidate := range strings.Fields(signatures) {
+		version, sig, ok := strings.Cut(candidate, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(sig)
+		if err != nil {
+			continue
+		}
+
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func parseResend(body []byte) ([]*event, error) {
+	var e resendEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	var reason string
+	switch e.Type {
+	case "email.bounced":
+		// email.bounced is only sent for hard bounces, older payloads don't carry the bounce details at all.
+		if e.Data.Bounce != nil && e.Data.Bounce.Type != "" && !strings.EqualFold(e.Data.Bounce.Type, "Permanent") {
+			return nil, nil
+		}
+		reason = reasonBounce
+	case "email.complained":
+		reason = reasonComplaint
+	default:
+		return nil, nil
+	}
+
+	// The event doesn't tell which of several recipients bounced or complained. Digests are sent to one recipient
+	// each, so anything else wasn't sent by us anyway.
+	if len(e.Data.To) != 1 {
+		return nil, nil
+	}
+
+	return []*event{{address: e.Data.To[0], reason: reason}}, nil
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySvix(t *testing.T) {
+	// The example of the Svix documentation, https://docs.svix.com/receiving/verifying-payloads/how-manual.
+	const (
+		secret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+		id     = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+		body   = `{"test": 2432232314}`
+	)
+	timestamp := time.Unix(1614265330, 0)
+
+	sign := func(id string, ts time.Time, body string) string {
+		key, _ := base64.StdEncoding.DecodeString(secret[len("whsec_"):])
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(id + "." + strconv.FormatInt(ts.Unix(), 10) + "." + body))
+		return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		timestamp string
+		signature string
+		body      string
+		now       time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "documented example",
+			id:        id,
+			timestamp: "1614265330",
+			signature: "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      body,
+			now:       timestamp,
+		},
+		{
+			name:      "one of several signatures",
+			id:        id,
+			timestamp: "1614265330",
+			signature: "v1,Ceo5qEr07ixe2NLpvHk3FH9bwy/WavXrAFQ/9tdO6mc= v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      body,
+			now:       timestamp,
+		},
+		{
+			name:      "tampered body",
+			id:        id,
+			timestamp: "1614265330",
+			signature: "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      `{"test": 2432232315}`,
+			now:       timestamp,
+			wantErr:   true,
+		},
+		{
+			name:      "other message id",
+			id:        "msg_other",
+			timestamp: "1614265330",
+			signature: "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      body,
+			now:       timestamp,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown version",
+			id:        id,
+			timestamp: "1614265330",
+			signature: "v1a,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      body,
+			now:       timestamp,
+			wantErr:   true,
+		},
+		{
+			name:      "stale timestamp",
+			id:        id,
+			timestamp: strconv.FormatInt(timestamp.Add(-10*time.Minute).Unix(), 10),
+			signature: sign(id, timestamp.Add(-10*time.Minute), body),
+			body:      body,
+			now:       timestamp,
+			wantErr:   true,
+		},
+		{
+			name:      "timestamp in the future",
+			id:        id,
+			timestamp: strconv.FormatInt(timestamp.Add(10*time.Minute).Unix(), 10),
+			signature: sign(id, timestamp.Add(10*time.Minute), body),
+			body:      body,
+			now:       timestamp,
+			wantErr:   true,
+		},
+		{
+			name:      "timestamp within tolerance",
+			id:        id,
+			timestamp: strconv.FormatInt(timestamp.Add(-4*time.Minute).Unix(), 10),
+			signature: sign(id, timestamp.Add(-4*time.Minute), body),
+			body:      body,
+			now:       timestamp,
+		},
+		{
+			name:      "missing headers",
+			signature: "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			body:      body,
+			now:       timestamp,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("svix-id", tt.id)
+			header.Set("svix-timestamp", tt.timestamp)
+			header.Set("svix-signature", tt.signature)
+
+			err := verifySvix(secret, header, []byte(tt.body), tt.now)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("verifySvix() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+// The payloads follow the examples of https://resend.com/docs/dashboard/webhooks/event-types.
+func TestParseResend(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []*event
+		wantErr bool
+	}{
+		{
+			name: "hard bounce",
+			body: `{
+  "type": "email.bounced",
+  "created_at": "2024-11-22T23:41:12.126Z",
+  "data": {
+    "broadcast_id": "8b146471-e88e-4322-86af-016cd36fd216",
+    "created_at": "2024-11-22T23:41:11.894719+00:00",
+    "email_id": "56761188-7520-42d8-8898-ff6fc54ce618",
+    "from": "Acme <onboarding@resend.dev>",
+    "to": ["delivered@resend.dev"],
+    "subject": "Sending this example",
+    "bounce": {
+      "message": "The recipient's email address is on the suppression list because it has a recent history of producing hard bounces.",
+      "subType": "Suppressed",
+      "type": "Permanent"
+    }
+  }
+}`,
+			want: []*event{{address: "delivered@resend.dev", reason: reasonBounce}},
+		},
+		{
+			name: "bounce without details",
+			body: `{"type": "email.bounced", "data": {"to": ["delivered@resend.dev"]}}`,
+			want: []*event{{address: "delivered@resend.dev", reason: reasonBounce}},
+		},
+		{
+			name: "soft bounce",
+			body: `{"type": "email.bounced", "data": {"to": ["delivered@resend.dev"], "bounce": {"type": "Transient", "subType": "MailboxFull"}}}`,
+		},
+		{
+			name: "complaint",
+			body: `{
+  "type": "email.complained",
+  "created_at": "2024-11-22T23:41:12.126Z",
+  "data": {
+    "created_at": "2024-11-22T23:41:11.894719+00:00",
+    "email_id": "56761188-7520-42d8-8898-ff6fc54ce618",
+    "from": "Acme <onboarding@resend.dev>",
+    "to": ["delivered@resend.dev"],
+    "subject": "Sending this example"
+  }
+}`,
+			want: []*event{{address: "delivered@resend.dev", reason: reasonComplaint}},
+		},
+		{
+			name: "several recipients",
+			body: `{"type": "email.bounced", "data": {"to": ["a@resend.dev", "b@resend.dev"], "bounce": {"type": "Permanent"}}}`,
+		},
+		{
+			name: "delivered",
+			body: `{"type": "email.delivered", "data": {"to": ["delivered@resend.dev"]}}`,
+		},
+		{
+			name:    "malformed",
+			body:    `{"type":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResend([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResend() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			checkEvents(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"net/http"
+	"time"
+)
+
+type (
+	Servicer interface {
+		IngestSES(ctx context.Context, in *IngestInput) (*IngestOutput, error)
+		IngestResend(ctx context.Context, in *IngestInput) (*IngestOutput, error)
+		IngestMailerSend(ctx context.Context, in *IngestInput) (*IngestOutput, error)
+	}
+
+	Service struct {
+		db         persistence.Persistence
+		config     *config.Email
+		httpClient *http.Client
+		certs      *certificateCache
+	}
+)
+
+var _ Servicer = (*Service)(nil)
+
+func NewService(
+	db persistence.Persistence,
+	config *config.Email,
+) (Servicer, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	return &Service{
+		db:         db,
+		config:     config,
+		httpClient: httpClient,
+		certs:      newCertificateCache(httpClient),
+	}, nil
+}
+
+func (s *Service) IngestSES(ctx context.Context, in *IngestInput) (*IngestOutput, error) {
+	if len(s.config.SESTopicARNs) == 0 {
+		return nil, ErrDisabled
+	}
+
+	events, err := s.parseSNS(ctx, in.Body, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.suppress(ctx, "ses", events)
+}
+
+func (s *Service) IngestResend(ctx context.Context, in *IngestInput) (*IngestOutput, error) {
+	if s.config.ResendWebhookSecret == "" {
+		return nil, ErrDisabled
+	}
+
+	if err := verifySvix(s.config.ResendWebhookSecret, in.Header, in.Body, time.Now()); err != nil {
+		return nil, err
+	}
+
+	events, err := parseResend(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.suppress(ctx, "resend", events)
+}
+
+func (s *Service) IngestMailerSend(ctx context.Context, in *IngestInput) (*IngestOutput, error) {
+	if s.config.MailerSendWebhookSecret == "" {
+		return nil, ErrDisabled
+	}
+
+	if err := verifyMailerSend(s.config.MailerSendWebhookSecret, in.Header, in.Body); err != nil {
+		return nil, err
+	}
+
+	events, err := parseMailerSend(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.suppress(ctx, "mailersend", events)
+}
+
+// suppress marks the recipients of events as suppressed, one update per reason.
+func (s *Service) suppress(ctx context.Context, provider string, events []*event) (*IngestOutput, error) {
+	byReason := make(map[string][]string)
+	for _, e := range events {
+		byReason[e.reason] = append(byReason[e.reason], e.address)
+	}
+
+	out := &IngestOutput{
+		Events: len(events),
+	}
+
+	for reason, addresses := range byReason {
+		res, err := s.db.SuppressRecipients(ctx, &persistence.SuppressRecipientsInput{
+			Addresses: addresses,
+			Reason:    fmt.Sprintf("%s: %s", provider, reason),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("suppress recipients: %w", err)
+		}
+		out.Suppressed += res.Suppressed
+	}
+
+	return out, nil
+}
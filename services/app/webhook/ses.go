@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snsHost matches the hosts SNS serves signing certificates and subscription URLs from. Anything else is rejected before
+// a request is made, otherwise a forged message could point us at an arbitrary certificate.
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsTolerance is the maximum age of an SNS message, which guards against replayed messages. The timestamp is the
+// publish time and stays the same for redeliveries, so it's longer than svixTolerance to cover SNS's retry policy.
+const snsTolerance = time.Hour
+
+type (
+	snsMessage struct {
+		Type             string `json:"Type"`
+		MessageID        string `json:"MessageId"`
+		Token            string `json:"Token"`
+		TopicArn         string `json:"TopicArn"`
+		Subject          string `json:"Subject"`
+		Message          string `json:"Message"`
+		Timestamp        string `json:"Timestamp"`
+		SignatureVersion string `json:"SignatureVersion"`
+		Signature        string `json:"Signature"`
+		SigningCertURL   string `json:"SigningCertURL"`
+		SubscribeURL     string `json:"SubscribeURL"`
+	}
+
+	// sesNotification covers both SES notification formats: identity notifications set notificationType, event
+	// publishing through a configuration set sets eventType.
+	sesNotification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Bounce           *struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint *struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+)
+
+// parseSNS verifies an SNS message and returns the bounces and complaints of the SES notification it carries.
+// Subscription confirmations of allowed topics are confirmed and yield no events. Messages published more than
+// snsTolerance before now are rejected.
+func (s *Service) parseSNS(ctx context.Context, body []byte, now time.Time) ([]*event, error) {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	if !slices.Contains(s.config.SESTopicARNs, msg.TopicArn) {
+		return nil, fmt.Errorf("%w: topic %q is not allowed", ErrInvalidSignature, msg.TopicArn)
+	}
+
+	if err := s.verifySNS(ctx, &msg); err != nil {
+		return nil, err
+	}
+
+	published, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if d := now.Sub(published); d > snsTolerance || d < -snsTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside of tolerance", ErrInvalidSignature)
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		return nil, s.confirmSubscription(ctx, msg.SubscribeURL)
+	case "Notification":
+		return parseSESNotification(msg.Message)
+	default:
+		return nil, nil
+	}
+}
+
+func parseSESNotification(message string) ([]*event, error) {
+	var n sesNotification
+	if err := json.Unmarshal([]byte(message), &n); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	kind := n.NotificationType
+	if kind == "" {
+		kind = n.EventType
+	}
+
+	var events []*event
+	switch kind {
+	case "Bounce":
+		// Transient bounces (full mailbox, greylisting, ...) may resolve on their own, only permanent ones suppress.
+		if n.Bounce == nil || n.Bounce.BounceType != "Permanent" {
+			return nil, nil
+		}
+		for _, r := range n.Bounce.BouncedRecipients {
+			events = append(events, &event{address: r.EmailAddress, reason: reasonBounce})
+		}
+	case "Complaint":
+		if n.Complaint == nil {
+			return nil, nil
+		}
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, &event{address: r.EmailAddress, reason: reasonComplaint})
+		}
+	}
+
+	return events, nil
+}
+
+// verifySNS checks the signature of msg against the certificate it references, see
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html.
+func (s *Service) verifySNS(ctx context.Context, msg *snsMessage) error {
+	var (
+		hash crypto.Hash
+		sum  []byte
+	)
+
+	stringToSign := msg.stringToSign()
+	switch msg.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+		h := sha1.Sum([]byte(stringToSign))
+		sum = h[:]
+	case "2":
+		hash = crypto.SHA256
+		h := sha256.Sum256([]byte(stringToSign))
+		sum = h[:]
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrInvalidSignature, msg.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	cert, err := s.certs.get(ctx, msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: unexpected certificate key type", ErrInvalidSignature)
+	}
+
+	if err = rsa.VerifyPKCS1v15(key, hash, sum, signature); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+// stringToSign builds the canonical representation SNS signs. The field set depends on the message type, Subject is
+// only part of it if present.
+func (m *snsMessage) stringToSign() string {
+	var b strings.Builder
+	field := func(name, value string) {
+		b.WriteString(name)
+		b.WriteString("\n")
+		b.WriteString(value)
+		b.WriteString("\n")
+	}
+
+	field("Message", m.Message)
+	field("MessageId", m.MessageID)
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			field("Subject", m.Subject)
+		}
+	} else {
+		field("SubscribeURL", m.SubscribeURL)
+	}
+	field("Timestamp", m.Timestamp)
+	if m.Type != "Notification" {
+		field("Token", m.Token)
+	}
+	field("TopicArn", m.TopicArn)
+	field("Type", m.Type)
+
+	return b.String()
+}
+
+func (s *Service) confirmSubscription(ctx context.Context, subscribeURL string) error {
+	if err := checkSNSURL(subscribeURL); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("confirm subscription: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("confirm subscription: unexpected status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func checkSNSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+		return fmt.Errorf("%w: untrusted url %q", ErrInvalidSignature, raw)
+	}
+	return nil
+}
+
+// certificateCache keeps the SNS signing certificates, which rotate rarely but would otherwise be fetched for every
+// notification.
+type certificateCache struct {
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newCertificateCache(httpClient *http.Client) *certificateCache {
+	return &certificateCache{
+		httpClient: httpClient,
+		certs:      make(map[string]*x509.Certificate),
+	}
+}
+
+func (c *certificateCache) get(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(certURL, ".pem") {
+		return nil, fmt.Errorf("%w: untrusted certificate url %q", ErrInvalidSignature, certURL)
+	}
+
+	c.mu.Lock()
+	cert, ok := c.certs[certURL]
+	c.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing certificate: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch signing certificate: unexpected status %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing certificate is not PEM encoded")
+	}
+
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.certs[certURL] = cert
+	c.mu.Unlock()
+
+	return cert, nil
+}
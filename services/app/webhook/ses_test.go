@@ -0,0 +1,460 @@
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testTopicARN = "arn:aws:sns:us-west-2:123456789012:ses-notifications"
+	testCertURL  = "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem"
+)
+
+// snsServer stands in for SNS: it serves the signing certificate and records the subscription confirmations.
+type snsServer struct {
+	key *rsa.PrivateKey
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func newSNSServer(t *testing.T) *snsServer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	s := &snsServer{key: key}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requested = append(s.requested, "https://"+r.Host+r.URL.RequestURI())
+		s.mu.Unlock()
+
+		if r.URL.Path == "/SimpleNotificationService-0000000000000000000000.pem" {
+			_, _ = w.Write(certPEM)
+		}
+	}))
+	t.Cleanup(s.srv.Close)
+
+	return s
+}
+
+// client sends every request to the test server, whatever its URL.
+func (s *snsServer) client() *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Host = r.URL.Host
+			r.URL.Scheme = "http"
+			r.URL.Host = s.srv.Listener.Addr().String()
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+}
+
+func (s *snsServer) service() *Service {
+	client := s.client()
+	return &Service{
+		config:     &config.Email{SESTopicARNs: config.List{testTopicARN}},
+		httpClient: client,
+		certs:      newCertificateCache(client),
+	}
+}
+
+// sign signs msg like SNS would with the given signature version.
+func (s *snsServer) sign(t *testing.T, msg *snsMessage, version string) {
+	t.Helper()
+
+	msg.SignatureVersion = version
+	if msg.SigningCertURL == "" {
+		msg.SigningCertURL = testCertURL
+	}
+
+	var (
+		hash crypto.Hash
+		sum  []byte
+	)
+	switch version {
+	case "1":
+		h := sha1.Sum([]byte(msg.stringToSign()))
+		hash, sum = crypto.SHA1, h[:]
+	default:
+		h := sha256.Sum256([]byte(msg.stringToSign()))
+		hash, sum = crypto.SHA256, h[:]
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, hash, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Signature = base64.StdEncoding.EncodeToString(signature)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSNSMessageStringToSign(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *snsMessage
+		want string
+	}{
+		{
+			name: "notification",
+			msg: &snsMessage{
+				Type:      "Notification",
+				MessageID: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+				TopicArn:  "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Subject:   "My First Message",
+				Message:   "Hello world!",
+				Timestamp: "2012-05-02T00:54:06.655Z",
+			},
+			want: "Message\nHello world!\nMessageId\n22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324\nSubject\nMy First Message\n" +
+				"Timestamp\n2012-05-02T00:54:06.655Z\nTopicArn\narn:aws:sns:us-west-2:123456789012:MyTopic\nType\nNotification\n",
+		},
+		{
+			name: "notification without subject",
+			msg: &snsMessage{
+				Type:      "Notification",
+				MessageID: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+				TopicArn:  "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Message:   "Hello world!",
+				Timestamp: "2012-05-02T00:54:06.655Z",
+			},
+			want: "Message\nHello world!\nMessageId\n22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324\n" +
+				"Timestamp\n2012-05-02T00:54:06.655Z\nTopicArn\narn:aws:sns:us-west-2:123456789012:MyTopic\nType\nNotification\n",
+		},
+		{
+			name: "subscription confirmation",
+			msg: &snsMessage{
+				Type:         "SubscriptionConfirmation",
+				MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+				Token:        "2336412f37f",
+				TopicArn:     "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Subject:      "ignored",
+				Message:      "You have chosen to subscribe to the topic.",
+				SubscribeURL: "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&Token=2336412f37f",
+				Timestamp:    "2012-04-26T20:45:04.751Z",
+			},
+			want: "Message\nYou have chosen to subscribe to the topic.\nMessageId\n165545c9-2a5c-472c-8df2-7ff2be2b3b1b\n" +
+				"SubscribeURL\nhttps://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&Token=2336412f37f\n" +
+				"Timestamp\n2012-04-26T20:45:04.751Z\nToken\n2336412f37f\nTopicArn\narn:aws:sns:us-west-2:123456789012:MyTopic\n" +
+				"Type\nSubscriptionConfirmation\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.stringToSign(); got != tt.want {
+				t.Errorf("stringToSign() = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceParseSNS(t *testing.T) {
+	sns := newSNSServer(t)
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+
+	notification := func(message string) *snsMessage {
+		return &snsMessage{
+			Type:      "Notification",
+			MessageID: "f6b5c8a2-0c4e-5f5e-9f3e-4a3b2c1d0e9f",
+			TopicArn:  testTopicARN,
+			Message:   message,
+			Timestamp: now.Add(-time.Minute).Format(time.RFC3339Nano),
+		}
+	}
+	bounce := `{"notificationType":"Bounce","bounce":{"bounceType":"Permanent","bouncedRecipients":[{"emailAddress":"alice@example.com"}]}}`
+
+	tests := []struct {
+		name    string
+		msg     *snsMessage
+		version string
+		tamper  func(msg *snsMessage)
+		events  int
+		wantErr error
+	}{
+		{
+			name:    "signature version 1",
+			msg:     notification(bounce),
+			version: "1",
+			events:  1,
+		},
+		{
+			name:    "signature version 2",
+			msg:     notification(bounce),
+			version: "2",
+			events:  1,
+		},
+		{
+			name:    "tampered message",
+			msg:     notification(bounce),
+			version: "2",
+			tamper: func(msg *snsMessage) {
+				msg.Message = `{"notificationType":"Bounce","bounce":{"bounceType":"Permanent","bouncedRecipients":[{"emailAddress":"bob@example.com"}]}}`
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "unknown topic",
+			msg:     notification(bounce),
+			version: "2",
+			tamper: func(msg *snsMessage) {
+				msg.TopicArn = "arn:aws:sns:us-west-2:123456789012:other"
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "unsupported signature version",
+			msg:     notification(bounce),
+			version: "2",
+			tamper: func(msg *snsMessage) {
+				msg.SignatureVersion = "3"
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "untrusted certificate url",
+			msg: func() *snsMessage {
+				msg := notification(bounce)
+				msg.SigningCertURL = "https://attacker.example.com/cert.pem"
+				return msg
+			}(),
+			version: "2",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "stale timestamp",
+			msg: func() *snsMessage {
+				msg := notification(bounce)
+				msg.Timestamp = now.Add(-2 * time.Hour).Format(time.RFC3339Nano)
+				return msg
+			}(),
+			version: "2",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "timestamp in the future",
+			msg: func() *snsMessage {
+				msg := notification(bounce)
+				msg.Timestamp = now.Add(2 * time.Hour).Format(time.RFC3339Nano)
+				return msg
+			}(),
+			version: "2",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "invalid notification",
+			msg:     notification("not json"),
+			version: "2",
+			wantErr: ErrInvalidPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sns.sign(t, tt.msg, tt.version)
+			if tt.tamper != nil {
+				tt.tamper(tt.msg)
+			}
+
+			body, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := sns.service().parseSNS(context.Background(), body, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseSNS() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(events) != tt.events {
+				t.Errorf("got %d events, want %d", len(events), tt.events)
+			}
+		})
+	}
+}
+
+func TestServiceParseSNSSubscriptionConfirmation(t *testing.T) {
+	sns := newSNSServer(t)
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+
+	subscribeURL := "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=" + testTopicARN + "&Token=2336412f37f"
+	msg := &snsMessage{
+		Type:         "SubscriptionConfirmation",
+		MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "2336412f37f",
+		TopicArn:     testTopicARN,
+		Message:      "You have chosen to subscribe to the topic " + testTopicARN + ".",
+		SubscribeURL: subscribeURL,
+		Timestamp:    now.Format(time.RFC3339Nano),
+	}
+	sns.sign(t, msg, "1")
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := sns.service().parseSNS(context.Background(), body, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %d events, want none", len(events))
+	}
+
+	sns.mu.Lock()
+	defer sns.mu.Unlock()
+	if !slices.Contains(sns.requested, subscribeURL) {
+		t.Errorf("subscription wasn't confirmed, requested %v", sns.requested)
+	}
+}
+
+// The notifications are the samples of
+// https://docs.aws.amazon.com/ses/latest/dg/notification-examples.html and
+// https://docs.aws.amazon.com/ses/latest/dg/event-publishing-retrieving-sns-examples.html, shortened to the fields
+// that matter.
+func TestParseSESNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []*event
+		wantErr bool
+	}{
+		{
+			name: "permanent bounce",
+			message: `{
+  "notificationType": "Bounce",
+  "bounce": {
+    "feedbackId": "000001378603177f-7a5433e7-8edb-42ae-af10-f0181f34d6ee-000000",
+    "bounceType": "Permanent",
+    "bounceSubType": "General",
+    "bouncedRecipients": [
+      {"emailAddress": "jane@example.com"},
+      {"emailAddress": "richard@example.com"}
+    ],
+    "timestamp": "2016-01-27T14:59:38.237Z",
+    "remoteMtaIp": "127.0.2.0",
+    "reportingMTA": "dsn; a8-70.smtp-out.amazonses.com"
+  },
+  "mail": {
+    "timestamp": "2016-01-27T14:59:38.237Z",
+    "messageId": "00000137860315fd-34208509-5b74-41f3-95c5-22c1edc3c924-000000",
+    "source": "john@example.com",
+    "destination": ["jane@example.com", "mary@example.com", "richard@example.com"]
+  }
+}`,
+			want: []*event{
+				{address: "jane@example.com", reason: reasonBounce},
+				{address: "richard@example.com", reason: reasonBounce},
+			},
+		},
+		{
+			name: "transient bounce",
+			message: `{
+  "notificationType": "Bounce",
+  "bounce": {
+    "bounceType": "Transient",
+    "bounceSubType": "MailboxFull",
+    "bouncedRecipients": [{"emailAddress": "jane@example.com", "status": "4.2.2", "action": "failed"}]
+  }
+}`,
+		},
+		{
+			name: "complaint",
+			message: `{
+  "notificationType": "Complaint",
+  "complaint": {
+    "userAgent": "AnyCompany Feedback Loop (V0.01)",
+    "complainedRecipients": [{"emailAddress": "richard@example.com"}],
+    "complaintFeedbackType": "abuse",
+    "arrivalDate": "2016-01-27T14:59:38.237Z",
+    "timestamp": "2016-01-27T14:59:38.237Z",
+    "feedbackId": "000001378603177f-18c07c78-fa81-4a58-9dd1-fedc3cb8f49a-000000"
+  }
+}`,
+			want: []*event{{address: "richard@example.com", reason: reasonComplaint}},
+		},
+		{
+			name: "event publishing bounce",
+			message: `{
+  "eventType": "Bounce",
+  "bounce": {
+    "bounceType": "Permanent",
+    "bounceSubType": "General",
+    "bouncedRecipients": [{"emailAddress": "recipient@example.com", "action": "failed", "status": "5.1.1"}]
+  }
+}`,
+			want: []*event{{address: "recipient@example.com", reason: reasonBounce}},
+		},
+		{
+			name:    "delivery",
+			message: `{"notificationType": "Delivery", "delivery": {"recipients": ["jane@example.com"]}}`,
+		},
+		{
+			name:    "malformed",
+			message: `{"notificationType":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSESNotification(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSESNotification() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			checkEvents(t, got, tt.want)
+		})
+	}
+}
+
+func checkEvents(t *testing.T, got, want []*event) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, *got[i], *want[i])
+		}
+	}
+}
@@ -1,33 +1,43 @@
 package digester
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/forbiddencoding/reddit-post-notifier/common/config"
 	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
 	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
 	"github.com/google/uuid"
-	"html/template"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 	"sort"
 	"time"
 )
 
 type Activities struct {
-	mailer      mail.Mailer
+	config      *config.Config
 	persistence persistence.Persistence
+	notifiers   map[string]notify.Notifier
 }
 
 func NewActivities(ctx context.Context, persistence persistence.Persistence, conf *config.Config) (*Activities, error) {
-	mailer, err := mail.New(ctx, &conf.Mailer)
+	email, err := newEmailNotifier(ctx, conf, persistence)
 	if err != nil {
 		return nil, err
 	}
 
+	notifiers, err := notify.New(&conf.Notify)
+	if err != nil {
+		return nil, err
+	}
+	notifiers[notify.ChannelEmail] = email
+
 	return &Activities{
-		mailer:      mailer,
+		config:      conf,
 		persistence: persistence,
+		notifiers:   notifiers,
 	}, nil
 }
 
@@ -80,25 +90,31 @@ type (
 	}
 
 	SendNotificationOutput struct {
+		Results []*DeliveryResult `json:"results"`
 	}
 )
 
 const SendNotificationActivityName = "send_notification"
 
+// SendNotification sends the queued posts as digest to every recipient. The activity is idempotent per workflow run:
+// posts are claimed and deliveries reserved under the run's idempotency key before anything is sent, so a retried
+// attempt sends the same digest and skips every recipient that already received it.
 func (a *Activities) SendNotification(ctx context.Context, in *SendNotificationInput) (*SendNotificationOutput, error) {
-	var addresses = make([]string, 0, len(in.Recipients))
-	for _, recipient := range in.Recipients {
-		addresses = append(addresses, recipient.Address)
-	}
+	logger := activity.GetLogger(ctx)
+	key := idempotencyKey(ctx)
 
-	items, err := a.persistence.GetPosts(ctx, &persistence.GetPostsInput{
+	items, err := a.persistence.ClaimPosts(ctx, &persistence.ClaimPostsInput{
 		ConfigurationID: in.ConfigurationID,
+		IdempotencyKey:  key,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("get posts from queue: %w", err)
+		return nil, fmt.Errorf("claim posts from queue: %w", err)
 	}
 
-	var posts = make([]persistence.Post, 0, len(items.Items))
+	var (
+		posts   = make([]persistence.Post, 0, len(items.Items))
+		postIDs = make([]string, 0, len(items.Items))
+	)
 	for _, item := range items.Items {
 		var post persistence.Post
 		if err = json.Unmarshal(item.Post, &post); err != nil {
@@ -106,6 +122,7 @@ func (a *Activities) SendNotification(ctx context.Context, in *SendNotificationI
 		}
 
 		posts = append(posts, post)
+		postIDs = append(postIDs, post.ID)
 	}
 
 	sort.SliceStable(posts, func(i, j int) bool {
@@ -115,37 +132,69 @@ func (a *Activities) SendNotification(ctx context.Context, in *SendNotificationI
 		return iCreated.Before(jCreated)
 	})
 
-	data := map[string]any{
-		"Title": "New Reddit Posts Notification",
-		"Posts": posts,
+	sent, err := a.reserveDeliveries(ctx, in.ConfigurationID, key, postIDs, in.Recipients)
+	if err != nil {
+		return nil, fmt.Errorf("reserve deliveries: %w", err)
 	}
 
-	tmpl, err := template.ParseGlob("templates/*.html")
-	if err != nil {
-		return nil, fmt.Errorf("parse email template: %w", err)
+	var (
+		pending = make([]*persistence.Recipient, 0, len(in.Recipients))
+		results = make([]*DeliveryResult, 0, len(in.Recipients))
+	)
+	for _, recipient := range in.Recipients {
+		if d, ok := sent[recipient.ID]; ok {
+			logger.Info("skipping recipient, digest already delivered", "recipient", recipient.ID)
+			results = append(results, &DeliveryResult{
+				RecipientID: d.RecipientID,
+				Channel:     d.Channel,
+				Address:     d.Address,
+				MessageID:   d.MessageID,
+				Provider:    d.Provider,
+			})
+			continue
+		}
+		pending = append(pending, recipient)
 	}
 
-	var body bytes.Buffer
-	if err = tmpl.ExecuteTemplate(&body, "email", data); err != nil {
-		return nil, fmt.Errorf("execute email template: %w", err)
+	if len(pending) > 0 {
+		delivered := a.notify(ctx, &notify.Digest{
+			ScheduleID:     in.ConfigurationID,
+			Keyword:        in.Keyword,
+			Posts:          posts,
+			IdempotencyKey: key,
+		}, pending)
+
+		// Without the record a retry can't tell which recipients were served, so this has to succeed before the
+		// posts are popped.
+		if err = a.recordDeliveries(ctx, in.ConfigurationID, key, postIDs, delivered); err != nil {
+			return nil, fmt.Errorf("record deliveries: %w", err)
+		}
+
+		a.suppressGone(ctx, delivered)
+
+		results = append(results, delivered...)
 	}
 
-	if err = a.mailer.SendMail(
-		ctx,
-		addresses,
-		in.Keyword,
-		body.String(),
-	); err != nil {
-		return nil, fmt.Errorf("send mail: %w", err)
+	if err = deliveryError(results, isLastAttempt(ctx)); err != nil {
+		return nil, notificationError(err)
+	}
+
+	for _, result := range results {
+		if result.err != nil {
+			logger.Warn("failed to deliver notification", "recipient", result.RecipientID, "error", result.err)
+		}
 	}
 
 	if _, err = a.persistence.PopPosts(ctx, &persistence.PopPostsInput{
 		ConfigurationID: in.ConfigurationID,
+		IdempotencyKey:  key,
 	}); err != nil {
 		return nil, fmt.Errorf("pop posts from queue: %w", err)
 	}
 
-	return nil, nil
+	return &SendNotificationOutput{
+		Results: results,
+	}, nil
 }
 
 type (
@@ -176,3 +225,23 @@ func (a *Activities) UpdateState(ctx context.Context, in *UpdateStateInput) (*Up
 
 	return nil, nil
 }
+
+// notificationError translates provider and channel errors into Temporal application errors, so permanent failures
+// aren't retried and rate limited providers are retried no earlier than they asked for.
+func notificationError(err error) error {
+	if !errors.As(err, new(*mail.SendError)) && !errors.As(err, new(*notify.Error)) {
+		return fmt.Errorf("send notification: %w", err)
+	}
+
+	retryable, retryAfter := retryInfo(err)
+	opts := temporal.ApplicationErrorOptions{
+		Cause:        err,
+		NonRetryable: !retryable,
+	}
+
+	if retryable && retryAfter > 0 {
+		opts.NextRetryDelay = retryAfter
+	}
+
+	return temporal.NewApplicationErrorWithOptions("send notification", "notification", opts)
+}
@@ -0,0 +1,260 @@
+package digester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+	"time"
+)
+
+// suppressionReasonGone is stored with recipients whose target doesn't exist anymore.
+const suppressionReasonGone = "gone"
+
+type DeliveryResult struct {
+	RecipientID uuid.UUID `json:"recipient_id"`
+	Channel     string    `json:"channel,omitempty"`
+	Address     string    `json:"address"`
+	MessageID   string    `json:"message_id,omitempty"`
+	Provider    string    `json:"provider,omitempty"`
+	Error       string    `json:"error,omitempty"`
+
+	err error
+}
+
+// notify sends digest to every recipient on its channel. There's exactly one result per recipient, in the order of
+// recipients.
+func (a *Activities) notify(ctx context.Context, digest *notify.Digest, recipients []*persistence.Recipient) []*DeliveryResult {
+	var (
+		channels []string
+		targets  = make(map[string][]*notify.Target)
+	)
+	for _, recipient := range recipients {
+		target := recipientTarget(recipient)
+
+		if _, ok := targets[target.Channel]; !ok {
+			channels = append(channels, target.Channel)
+		}
+		targets[target.Channel] = append(targets[target.Channel], target)
+	}
+
+	byRecipient := make(map[uuid.UUID]*DeliveryResult, len(recipients))
+	for _, channel := range channels {
+		notifier, ok := a.notifiers[channel]
+		if !ok {
+			for _, target := range targets[channel] {
+				byRecipient[target.RecipientID] = newDeliveryResult(&notify.Result{
+					Target: target,
+					Err:    &notify.Error{Channel: channel, Message: notify.ErrUnsupportedChannel.Error()},
+				})
+			}
+			continue
+		}
+
+		for _, result := range notifier.Notify(ctx, digest, targets[channel]) {
+			byRecipient[result.Target.RecipientID] = newDeliveryResult(result)
+		}
+	}
+
+	results := make([]*DeliveryResult, 0, len(recipients))
+	for _, recipient := range recipients {
+		results = append(results, byRecipient[recipient.ID])
+	}
+
+	return results
+}
+
+func recipientTarget(recipient *persistence.Recipient) *notify.Target {
+	channel := recipient.Channel
+	if channel == "" {
+		channel = notify.ChannelEmail
+	}
+
+	return &notify.Target{
+		RecipientID: recipient.ID,
+		Channel:     channel,
+		Address:     recipient.Address,
+		Settings:    recipient.Settings,
+	}
+}
+
+// loggedAddress is the address kept in the workflow history and the delivery log. Its secrets are redacted like those
+// of the recipients of a schedule, the address of most channels is a credential.
+func loggedAddress(target *notify.Target) string {
+	return notify.RedactTarget(target).Address
+}
+
+// suppressGone suppresses the recipients whose target doesn't exist anymore, like expired push subscriptions. They
+// would fail every digest from now on. A failure is only logged, the digest went out regardless. The recipients are
+// matched by ID, the address of the result is redacted.
+func (a *Activities) suppressGone(ctx context.Context, results []*DeliveryResult) {
+	var ids []uuid.UUID
+	for _, result := range results {
+		if target, ok := errors.AsType[*notify.Error](result.err); ok && target.Gone {
+			ids = append(ids, result.RecipientID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	if _, err := a.persistence.SuppressRecipients(ctx, &persistence.SuppressRecipientsInput{
+		RecipientIDs: ids,
+		Reason:       suppressionReasonGone,
+	}); err != nil {
+		activity.GetLogger(ctx).Warn("failed to suppress gone recipients", "error", err)
+	}
+}
+
+func newDeliveryResult(result *notify.Result) *DeliveryResult {
+	r := &DeliveryResult{
+		RecipientID: result.Target.RecipientID,
+		Channel:     result.Target.Channel,
+		Address:     loggedAddress(result.Target),
+		MessageID:   result.MessageID,
+		Provider:    result.Provider,
+		err:         result.Err,
+	}
+
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+
+	return r
+}
+
+// deliveryError returns the error the activity fails with, if any. Once at least one recipient got the digest, or on
+// the last attempt, the failures are only recorded and the posts are popped. Failing then would skip PopPosts and
+// UpdateState, and the next run would send the same posts to every recipient again. Otherwise the first retryable
+// failure is returned, so Temporal retries the activity, or the first permanent one if none is retryable.
+func deliveryError(results []*DeliveryResult, lastAttempt bool) error {
+	if lastAttempt {
+		return nil
+	}
+
+	var retryable, permanent error
+	for _, result := range results {
+		if result.err == nil {
+			return nil
+		}
+
+		if ok, _ := retryInfo(result.err); ok {
+			if retryable == nil {
+				retryable = result.err
+			}
+		} else if permanent == nil {
+			permanent = result.err
+		}
+	}
+
+	if retryable != nil {
+		return retryable
+	}
+	return permanent
+}
+
+// retryInfo extracts the retry decision from the error types of the mail providers and the notification channels.
+// Any other error is considered transient.
+func retryInfo(err error) (bool, time.Duration) {
+	if target, ok := errors.AsType[*mail.SendError](err); ok {
+		return target.Retryable, target.RetryAfter
+	}
+	if target, ok := errors.AsType[*notify.Error](err); ok {
+		return target.Retryable, target.RetryAfter
+	}
+	return true, 0
+}
+
+// idempotencyKey identifies the digest of one workflow run. It's the same for every attempt of the activity, but
+// differs between runs of the schedule.
+func idempotencyKey(ctx context.Context) string {
+	info := activity.GetInfo(ctx)
+	return info.WorkflowExecution.ID + "/" + info.WorkflowExecution.RunID
+}
+
+// isLastAttempt reports whether Temporal won't retry the activity if this attempt fails.
+func isLastAttempt(ctx context.Context) bool {
+	info := activity.GetInfo(ctx)
+	return info.RetryPolicy != nil && info.RetryPolicy.MaximumAttempts > 0 &&
+		info.Attempt >= info.RetryPolicy.MaximumAttempts
+}
+
+// reserveDeliveries records a pending delivery for every recipient that has none for this key yet. It returns the
+// deliveries that already went out in an earlier attempt, keyed by recipient.
+func (a *Activities) reserveDeliveries(ctx context.Context, configurationID uuid.UUID, key string, postIDs []string, recipients []*persistence.Recipient) (map[uuid.UUID]*persistence.Delivery, error) {
+	deliveries := make([]*persistence.Delivery, 0, len(recipients))
+	for _, recipient := range recipients {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("generate delivery ID: %w", err)
+		}
+
+		target := recipientTarget(recipient)
+
+		deliveries = append(deliveries, &persistence.Delivery{
+			ID:              id,
+			ConfigurationID: configurationID,
+			RecipientID:     recipient.ID,
+			Channel:         target.Channel,
+			Address:         loggedAddress(target),
+			PostIDs:         postIDs,
+			Status:          persistence.DeliveryStatusPending,
+		})
+	}
+
+	res, err := a.persistence.ReserveDeliveries(ctx, &persistence.ReserveDeliveriesInput{
+		IdempotencyKey: key,
+		Deliveries:     deliveries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sent := make(map[uuid.UUID]*persistence.Delivery)
+	for _, d := range res.Deliveries {
+		if d.Status == persistence.DeliveryStatusSent {
+			sent[d.RecipientID] = d
+		}
+	}
+
+	return sent, nil
+}
+
+// recordDeliveries writes the outcome of every delivery to the delivery log.
+func (a *Activities) recordDeliveries(ctx context.Context, configurationID uuid.UUID, key string, postIDs []string, results []*DeliveryResult) error {
+	deliveries := make([]*persistence.Delivery, 0, len(results))
+	for _, result := range results {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate delivery ID: %w", err)
+		}
+
+		status := persistence.DeliveryStatusSent
+		if result.err != nil {
+			status = persistence.DeliveryStatusFailed
+		}
+
+		deliveries = append(deliveries, &persistence.Delivery{
+			ID:              id,
+			ConfigurationID: configurationID,
+			IdempotencyKey:  key,
+			RecipientID:     result.RecipientID,
+			Channel:         result.Channel,
+			Address:         result.Address,
+			Provider:        result.Provider,
+			MessageID:       result.MessageID,
+			PostIDs:         postIDs,
+			Status:          status,
+			Error:           result.Error,
+		})
+	}
+
+	_, err := a.persistence.RecordDeliveries(ctx, &persistence.RecordDeliveriesInput{
+		Deliveries: deliveries,
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package digester
+
+import (
+	"errors"
+	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
+	"testing"
+)
+
+func TestDeliveryError(t *testing.T) {
+	var (
+		sent      = &DeliveryResult{}
+		outage    = &DeliveryResult{err: &mail.SendError{Provider: "smtp", Message: "connection refused", Retryable: true}}
+		throttled = &DeliveryResult{err: &notify.Error{Channel: notify.ChannelDiscord, StatusCode: 429, Retryable: true}}
+		rejected  = &DeliveryResult{err: &mail.SendError{Provider: "smtp", StatusCode: 550, Message: "no such user"}}
+		gone      = &DeliveryResult{err: &notify.Error{Channel: notify.ChannelWebPush, StatusCode: 410, Gone: true}}
+		unknown   = &DeliveryResult{err: errors.New("unexpected")}
+	)
+
+	tests := []struct {
+		name        string
+		results     []*DeliveryResult
+		lastAttempt bool
+		want        *DeliveryResult
+	}{
+		{
+			name:    "all sent",
+			results: []*DeliveryResult{sent, sent},
+		},
+		{
+			name:    "no recipients",
+			results: nil,
+		},
+		{
+			name:    "retryable",
+			results: []*DeliveryResult{outage},
+			want:    outage,
+		},
+		{
+			name:    "unknown errors are retryable",
+			results: []*DeliveryResult{unknown},
+			want:    unknown,
+		},
+		{
+			name:    "permanent",
+			results: []*DeliveryResult{rejected, gone},
+			want:    rejected,
+		},
+		{
+			name:    "retryable before permanent",
+			results: []*DeliveryResult{rejected, throttled, outage},
+			want:    throttled,
+		},
+		{
+			name:    "mixed with a delivery",
+			results: []*DeliveryResult{outage, sent, rejected},
+		},
+		{
+			name:        "retryable on the last attempt",
+			results:     []*DeliveryResult{outage, throttled},
+			lastAttempt: true,
+		},
+		{
+			name:        "permanent on the last attempt",
+			results:     []*DeliveryResult{rejected},
+			lastAttempt: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := deliveryError(tt.results, tt.lastAttempt)
+
+			var want error
+			if tt.want != nil {
+				want = tt.want.err
+			}
+			if err != want {
+				t.Errorf("deliveryError() = %v, want %v", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package digester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/notify"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"go.temporal.io/sdk/activity"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Values of config.Email.DeliveryMode. Individual is the default, as it's the only mode that works with every provider
+// and never discloses one recipient's address to another.
+const (
+	deliveryModeIndividual = "individual"
+	deliveryModeBCC        = "bcc"
+	deliveryModeShared     = "shared"
+)
+
+// emailNotifier is the email channel. It renders the digest with the HTML templates and sends it through the configured
+// mail provider(s).
+type emailNotifier struct {
+	config     *config.Config
+	mailer     mail.Mailer
+	httpClient *http.Client
+	templates  *emailTemplates
+}
+
+var _ notify.Notifier = (*emailNotifier)(nil)
+
+func newEmailNotifier(ctx context.Context, conf *config.Config, persistence persistence.Persistence) (*emailNotifier, error) {
+	tmpls, err := newEmailTemplates(conf.Email.TemplateDir, persistence)
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := mail.New(ctx, conf.Mailer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &emailNotifier{
+		config:     conf,
+		mailer:     mailer,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		templates:  tmpls,
+	}, nil
+}
+
+func (e *emailNotifier) Notify(ctx context.Context, digest *notify.Digest, targets []*notify.Target) []*notify.Result {
+	msg, err := e.render(ctx, digest)
+	if err != nil {
+		results := make([]*notify.Result, 0, len(targets))
+		for _, target := range targets {
+			results = append(results, &notify.Result{Target: target, Err: err})
+		}
+		return results
+	}
+
+	return e.deliver(ctx, digest.IdempotencyKey, targets, msg)
+}
+
+// render builds the message that is sent to every recipient, only the recipient fields differ between them.
+func (e *emailNotifier) render(ctx context.Context, digest *notify.Digest) (*mail.Message, error) {
+	views, thumbnails := e.postViews(ctx, digest.Posts)
+
+	data := &templates.Digest{
+		Title: "New Reddit Posts Notification",
+		Posts: views,
+	}
+
+	tmpl, err := e.templates.lookup(ctx, digest.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err = templates.Render(ctx, &body, tmpl, data); err != nil {
+		if tmpl == e.templates.def {
+			return nil, fmt.Errorf("execute email template: %w", err)
+		}
+
+		// Custom templates are validated with sample posts only, real ones can still break them.
+		activity.GetLogger(ctx).Warn("failed to execute email template, using the default", "error", err)
+		body.Reset()
+		if err = templates.Render(ctx, &body, e.templates.def, data); err != nil {
+			return nil, fmt.Errorf("execute email template: %w", err)
+		}
+	}
+
+	return &mail.Message{
+		Subject:     fmt.Sprintf("%s %s", e.config.Email.SubjectPrefix, digest.Keyword),
+		HTML:        body.String(),
+		Text:        renderText(digest.Posts),
+		Attachments: thumbnails,
+	}, nil
+}
+
+// deliver sends msg to every target according to the configured delivery mode. msg is used as template, its recipient
+// fields and idempotency key are overwritten. There's exactly one result per target, in the order of targets.
+func (e *emailNotifier) deliver(ctx context.Context, idempotencyKey string, targets []*notify.Target, msg *mail.Message) []*notify.Result {
+	mode := e.config.Email.DeliveryMode
+	if mode == "" {
+		mode = deliveryModeIndividual
+	}
+
+	if mode == deliveryModeBCC && !mail.Supports(e.mailer, mail.FeatureBCC) {
+		activity.GetLogger(ctx).Warn("mail provider does not support bcc, falling back to individual delivery")
+		mode = deliveryModeIndividual
+	}
+
+	results := make([]*notify.Result, 0, len(targets))
+
+	switch mode {
+	case deliveryModeBCC, deliveryModeShared:
+		addresses := make([]string, 0, len(targets))
+		for _, target := range targets {
+			addresses = append(addresses, target.Address)
+		}
+
+		m := *msg
+		m.IdempotencyKey = idempotencyKey + "/" + mode
+		if mode == deliveryModeBCC {
+			// Some servers reject messages without a To header, so the digest is addressed to the sender itself. That's
+			// the sender of the primary mailer, also if a fallback with a sender of its own takes over.
+			m.To, m.Cc, m.Bcc = []string{e.config.Mailer[0].SenderEmail}, nil, addresses
+		} else {
+			m.To, m.Cc, m.Bcc = addresses, nil, nil
+		}
+
+		out, err := e.mailer.SendMail(ctx, &m)
+		for _, target := range targets {
+			results = append(results, newEmailResult(target, out, err))
+		}
+	default:
+		for _, target := range targets {
+			m := *msg
+			m.IdempotencyKey = idempotencyKey + "/" + target.RecipientID.String()
+			m.To, m.Cc, m.Bcc = []string{target.Address}, nil, nil
+
+			out, err := e.mailer.SendMail(ctx, &m)
+			results = append(results, newEmailResult(target, out, err))
+		}
+	}
+
+	return results
+}
+
+func newEmailResult(target *notify.Target, out *mail.SendMailOutput, err error) *notify.Result {
+	result := &notify.Result{
+		Target: target,
+		Err:    err,
+	}
+
+	if err == nil && out != nil {
+		result.MessageID = out.MessageID
+		result.Provider = out.Provider
+	}
+
+	return result
+}
+
+// renderText renders the plain text alternative of the digest for clients that don't display HTML.
+func renderText(posts []persistence.Post) string {
+	if len(posts) == 0 {
+		return "Your scheduled digest has no new posts matching your criteria.\n"
+	}
+
+	var b strings.Builder
+	for _, post := range posts {
+		b.WriteString(post.Title)
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "r/%s • Upvotes %d • Downs %d • %s", post.Subreddit, post.Ups, post.Downs, post.Created)
+		if post.NSFW {
+			b.WriteString(" • NSFW")
+		}
+		if post.Spoiler {
+			b.WriteString(" • Spoiler")
+		}
+		b.WriteString("\n")
+		b.WriteString(post.Permalink)
+		b.WriteString("\n\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package digester
+
+import (
+	"context"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+	"html/template"
+	"os"
+	"sync"
+)
+
+// emailTemplates resolves the template a schedule's digest is rendered with: the version of its custom template that
+// is active, or the default templates.
+type emailTemplates struct {
+	persistence persistence.Persistence
+	// base is never executed, the default and every custom template are parsed on top of clones of it.
+	base *template.Template
+	def  *template.Template
+
+	mu sync.Mutex
+	// custom caches the parsed custom templates by version ID. Versions can't be changed, so entries never go stale.
+	// Versions that fail to parse are cached as the default.
+	custom map[uuid.UUID]*template.Template
+}
+
+func newEmailTemplates(dir string, persistence persistence.Persistence) (*emailTemplates, error) {
+	base, err := parseEmailTemplates(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := base.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("clone email templates: %w", err)
+	}
+
+	return &emailTemplates{
+		persistence: persistence,
+		base:        base,
+		def:         def,
+		custom:      make(map[uuid.UUID]*template.Template),
+	}, nil
+}
+
+// parseEmailTemplates parses the embedded templates and, if dir is set, the templates of dir on top of them. A
+// template defined in dir replaces the default one of the same name.
+func parseEmailTemplates(dir string) (*template.Template, error) {
+	tmpl, err := templates.Default()
+	if err != nil {
+		return nil, fmt.Errorf("parse email templates: %w", err)
+	}
+
+	if dir != "" {
+		if tmpl, err = tmpl.ParseFS(os.DirFS(dir), "*.html"); err != nil {
+			return nil, fmt.Errorf("parse email templates of %s: %w", dir, err)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// lookup returns the template of the schedule. A custom template that doesn't parse on top of the templates of this
+// worker, e.g. because the override directory differs from the defaults it was validated with, falls back to the
+// default.
+func (t *emailTemplates) lookup(ctx context.Context, scheduleID uuid.UUID) (*template.Template, error) {
+	res, err := t.persistence.GetActiveEmailTemplate(ctx, &persistence.GetActiveEmailTemplateInput{
+		ConfigurationID: scheduleID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get active email template: %w", err)
+	}
+	if res.Template == nil {
+		return t.def, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tmpl, ok := t.custom[res.Template.ID]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := templates.Extend(t.base, res.Template.Source)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("failed to parse email template, using the default",
+			"template", res.Template.ID, "error", err)
+		tmpl = t.def
+	}
+	t.custom[res.Template.ID] = tmpl
+
+	return tmpl, nil
+}
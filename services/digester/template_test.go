@@ -0,0 +1,137 @@
+package digester
+
+import (
+	"bytes"
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// activeTemplates serves the active email template of every schedule.
+type activeTemplates struct {
+	persistence.Persistence
+	active map[uuid.UUID]*persistence.EmailTemplate
+}
+
+func (a *activeTemplates) GetActiveEmailTemplate(_ context.Context, in *persistence.GetActiveEmailTemplateInput) (*persistence.GetActiveEmailTemplateOutput, error) {
+	return &persistence.GetActiveEmailTemplateOutput{Template: a.active[in.ConfigurationID]}, nil
+}
+
+func TestParseEmailTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "embedded", want: "Example post with a thumbnail"},
+		{
+			name:  "override",
+			files: map[string]string{"post.html": `{{define "post"}}<p class="override">{{.Title}}</p>{{end}}`},
+			want:  `<p class="override">Example post with a thumbnail</p>`,
+		},
+		{
+			name:    "syntax error",
+			files:   map[string]string{"post.html": `{{define "post"}}{{.Title}{{end}}`},
+			wantErr: true,
+		},
+		{
+			name:    "no templates",
+			files:   map[string]string{"README.md": "nothing to parse"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dir string
+			if tt.files != nil {
+				dir = t.TempDir()
+				for name, content := range tt.files {
+					if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			tmpl, err := parseEmailTemplates(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEmailTemplates() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var out bytes.Buffer
+			if err = templates.Render(context.Background(), &out, tmpl, templates.Sample()); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output doesn't contain %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailTemplatesLookup(t *testing.T) {
+	var (
+		defaultSchedule = uuid.MustParse("0199f2a0-0000-7000-8000-000000000001")
+		customSchedule  = uuid.MustParse("0199f2a0-0000-7000-8000-000000000002")
+	)
+
+	store := &activeTemplates{active: map[uuid.UUID]*persistence.EmailTemplate{
+		customSchedule: {
+			ID:              uuid.MustParse("019a0c1e-0000-7000-8000-000000000001"),
+			ConfigurationID: customSchedule,
+			Source:          `{{define "post"}}<p class="custom">{{.Title}}</p>{{end}}`,
+		},
+	}}
+
+	emailTemplates, err := newEmailTemplates("", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := emailTemplates.lookup(context.Background(), defaultSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != emailTemplates.def {
+		t.Error("schedule without a custom template doesn't get the default")
+	}
+
+	custom, err := emailTemplates.lookup(context.Background(), customSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = templates.Render(context.Background(), &out, custom, templates.Sample()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<p class="custom">`) {
+		t.Error("custom template isn't used")
+	}
+
+	// Versions never change, the parsed template is reused.
+	again, err := emailTemplates.lookup(context.Background(), customSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != custom {
+		t.Error("custom template was parsed again")
+	}
+
+	// Rendering the custom template leaves the default alone.
+	out.Reset()
+	if err = templates.Render(context.Background(), &out, emailTemplates.def, templates.Sample()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `<p class="custom">`) {
+		t.Error("default template was changed by the custom one")
+	}
+}
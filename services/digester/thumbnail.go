@@ -0,0 +1,177 @@
+package digester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"go.temporal.io/sdk/activity"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInlineThumbnailMaxSize  = 256 << 10
+	defaultInlineThumbnailMaxTotal = 2 << 20
+	// thumbnailDeadlineShare is the share of the time left to the activity that may be spent downloading thumbnails,
+	// the rest is reserved for rendering and sending the digest.
+	thumbnailDeadlineShare = 4
+)
+
+var errThumbnailTooLarge = errors.New("thumbnail exceeds size limit")
+
+// postViews prepares the posts for rendering. If inline thumbnails are enabled and the mailer can embed them, the
+// thumbnails are downloaded and returned as inline attachments. Every thumbnail that can't be embedded stays a link.
+func (e *emailNotifier) postViews(ctx context.Context, posts []persistence.Post) ([]*templates.Post, []*mail.Attachment) {
+	logger := activity.GetLogger(ctx)
+
+	views := make([]*templates.Post, 0, len(posts))
+	for _, post := range posts {
+		views = append(views, &templates.Post{
+			Post:      post,
+			Thumbnail: thumbnailURL(post.Thumbnail),
+		})
+	}
+
+	if !e.config.Email.InlineThumbnails {
+		return views, nil
+	}
+	if !mail.Supports(e.mailer, mail.FeatureInline) {
+		logger.Warn("mail provider does not support inline images, thumbnails are linked instead")
+		return views, nil
+	}
+
+	maxSize, maxTotal := e.config.Email.InlineThumbnailMaxSize, e.config.Email.InlineThumbnailMaxTotal
+	if maxSize == 0 {
+		maxSize = defaultInlineThumbnailMaxSize
+	}
+	if maxTotal == 0 {
+		maxTotal = defaultInlineThumbnailMaxTotal
+	}
+
+	// The downloads run one after another, so they get an overall deadline on top of the timeout per request. Without
+	// it a handful of slow thumbnails would use up the whole activity and the digest would never be sent.
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/thumbnailDeadlineShare)
+		defer cancel()
+	}
+
+	var (
+		attachments []*mail.Attachment
+		total       int64
+		// Crossposts share thumbnails, every image is embedded once.
+		embedded = make(map[string]string)
+	)
+	for _, view := range views {
+		if view.Thumbnail == "" {
+			continue
+		}
+
+		source := string(view.Thumbnail)
+		if cid, ok := embedded[source]; ok {
+			view.Thumbnail = template.URL("cid:" + cid)
+			continue
+		}
+
+		remaining := min(maxSize, maxTotal-total)
+		if remaining <= 0 {
+			logger.Info("inline thumbnail budget exhausted, linking remaining thumbnails")
+			break
+		}
+		if ctx.Err() != nil {
+			logger.Info("inline thumbnail time budget exhausted, linking remaining thumbnails")
+			break
+		}
+
+		data, contentType, err := e.downloadThumbnail(ctx, source, remaining)
+		if err != nil {
+			logger.Warn("failed to embed thumbnail, falling back to link", "post", view.ID, "error", err)
+			continue
+		}
+
+		cid := fmt.Sprintf("thumbnail-%s@%s", view.ID, senderDomain(e.config.Mailer[0].SenderEmail))
+		filename := "thumbnail-" + view.ID
+		if ext, _ := mime.ExtensionsByType(contentType); len(ext) > 0 {
+			filename += ext[0]
+		}
+
+		attachments = append(attachments, &mail.Attachment{
+			Filename:    filename,
+			ContentType: contentType,
+			Data:        data,
+			ContentID:   cid,
+		})
+		total += int64(len(data))
+		embedded[source] = cid
+		view.Thumbnail = template.URL("cid:" + cid)
+	}
+
+	return views, attachments
+}
+
+// downloadThumbnail fetches an image of at most limit bytes. Anything that isn't an image is rejected, so an
+// unexpected HTML error page never ends up in a digest.
+func (e *emailNotifier) downloadThumbnail(ctx context.Context, source string, limit int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", e.config.Reddit.UserAgent)
+
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	if res.ContentLength > limit {
+		return nil, "", errThumbnailTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limit {
+		return nil, "", errThumbnailTooLarge
+	}
+
+	contentType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+
+	return data, contentType, nil
+}
+
+// thumbnailURL only lets remote thumbnails through, placeholders like "self" or "nsfw" render as posts without one.
+func thumbnailURL(raw string) template.URL {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return ""
+	}
+	return template.URL(raw)
+}
+
+func senderDomain(address string) string {
+	if at := strings.LastIndexByte(address, '@'); at >= 0 && at < len(address)-1 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,232 @@
+package digester
+
+import (
+	"bytes"
+	"context"
+	"github.com/forbiddencoding/reddit-post-notifier/common/config"
+	"github.com/forbiddencoding/reddit-post-notifier/common/mail"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"github.com/forbiddencoding/reddit-post-notifier/templates"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testPNG is the start of a PNG file, enough for content sniffing.
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestThumbnailURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want template.URL
+	}{
+		{raw: "https://b.thumbs.redditmedia.com/abc.jpg", want: "https://b.thumbs.redditmedia.com/abc.jpg"},
+		{raw: "http://b.thumbs.redditmedia.com/abc.jpg", want: "http://b.thumbs.redditmedia.com/abc.jpg"},
+		{raw: "self"},
+		{raw: "nsfw"},
+		{raw: "default"},
+		{raw: ""},
+		{raw: "javascript:alert(1)"},
+		{raw: "https:///no-host.jpg"},
+	}
+
+	for _, tt := range tests {
+		if got := thumbnailURL(tt.raw); got != tt.want {
+			t.Errorf("thumbnailURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// thumbnailServer serves the images of a test by path. Paths starting with /chunked/ are sent without
+// Content-Length.
+func thumbnailServer(t *testing.T, images map[string]*testImage) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != "digest-test" {
+			t.Errorf("user agent = %q", got)
+		}
+
+		image, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if image.contentType != "" {
+			w.Header().Set("Content-Type", image.contentType)
+		}
+		if !strings.HasPrefix(r.URL.Path, "/chunked/") {
+			w.Header().Set("Content-Length", strconv.Itoa(len(image.data)))
+		}
+		_, _ = w.Write(image.data)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+type testImage struct {
+	contentType string
+	data        []byte
+}
+
+func testEmailNotifier(t *testing.T, email config.Email) *emailNotifier {
+	t.Helper()
+
+	mailer, err := mail.New(context.Background(), []config.MailerConfig{{Provider: "log", SenderEmail: "digest@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &emailNotifier{
+		config: &config.Config{
+			Reddit: config.Reddit{UserAgent: "digest-test"},
+			Mailer: []config.MailerConfig{{Provider: "log", SenderEmail: "digest@example.com"}},
+			Email:  email,
+		},
+		mailer:     mailer,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func TestEmailNotifierDownloadThumbnail(t *testing.T) {
+	srv := thumbnailServer(t, map[string]*testImage{
+		"/image.png":         {contentType: "image/png", data: testPNG},
+		"/sniffed.png":       {contentType: "application/octet-stream", data: testPNG},
+		"/page.html":         {contentType: "text/html", data: []byte("<html><body>Not found</body></html>")},
+		"/large.png":         {contentType: "image/png", data: bytes.Repeat(testPNG, 10)},
+		"/chunked/large.png": {contentType: "image/png", data: bytes.Repeat(testPNG, 10)},
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		wantErr     bool
+	}{
+		{name: "image", path: "/image.png", contentType: "image/png"},
+		{name: "sniffed content type", path: "/sniffed.png", contentType: "image/png"},
+		{name: "html", path: "/page.html", wantErr: true},
+		{name: "not found", path: "/missing.png", wantErr: true},
+		{name: "too large", path: "/large.png", wantErr: true},
+		{name: "too large without content length", path: "/chunked/large.png", wantErr: true},
+	}
+
+	e := testEmailNotifier(t, config.Email{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := e.downloadThumbnail(context.Background(), srv.URL+tt.path, int64(len(testPNG)*2))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadThumbnail() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.contentType)
+			}
+			if !bytes.Equal(data, testPNG) {
+				t.Errorf("data = %q", data)
+			}
+		})
+	}
+}
+
+func TestEmailNotifierPostViews(t *testing.T) {
+	srv := thumbnailServer(t, map[string]*testImage{
+		"/a.png":     {contentType: "image/png", data: testPNG},
+		"/b.png":     {contentType: "image/png", data: testPNG},
+		"/large.png": {contentType: "image/png", data: bytes.Repeat(testPNG, 10)},
+	})
+
+	posts := []persistence.Post{
+		{ID: "a", Thumbnail: srv.URL + "/a.png"},
+		{ID: "self", Thumbnail: "self"},
+		{ID: "crosspost", Thumbnail: srv.URL + "/a.png"},
+		{ID: "large", Thumbnail: srv.URL + "/large.png"},
+		{ID: "missing", Thumbnail: srv.URL + "/missing.png"},
+		{ID: "b", Thumbnail: srv.URL + "/b.png"},
+	}
+
+	tests := []struct {
+		name        string
+		email       config.Email
+		thumbnails  []string
+		attachments int
+	}{
+		{
+			name:       "linked",
+			email:      config.Email{},
+			thumbnails: []string{srv.URL + "/a.png", "", srv.URL + "/a.png", srv.URL + "/large.png", srv.URL + "/missing.png", srv.URL + "/b.png"},
+		},
+		{
+			name:  "inline",
+			email: config.Email{InlineThumbnails: true, InlineThumbnailMaxSize: int64(len(testPNG) * 2)},
+			thumbnails: []string{
+				"cid:thumbnail-a@example.com", "", "cid:thumbnail-a@example.com", srv.URL + "/large.png",
+				srv.URL + "/missing.png", "cid:thumbnail-b@example.com",
+			},
+			attachments: 2,
+		},
+		{
+			name: "total budget",
+			email: config.Email{
+				InlineThumbnails:        true,
+				InlineThumbnailMaxSize:  int64(len(testPNG) * 2),
+				InlineThumbnailMaxTotal: int64(len(testPNG)),
+			},
+			thumbnails: []string{
+				"cid:thumbnail-a@example.com", "", "cid:thumbnail-a@example.com", srv.URL + "/large.png",
+				srv.URL + "/missing.png", srv.URL + "/b.png",
+			},
+			attachments: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testEmailNotifier(t, tt.email)
+
+			var (
+				views       []*templates.Post
+				attachments []*mail.Attachment
+			)
+			// postViews logs through the activity logger, so it has to run inside an activity.
+			env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(func(ctx context.Context) error {
+				views, attachments = e.postViews(ctx, posts)
+				return nil
+			}, activity.RegisterOptions{Name: "postViews"})
+			if _, err := env.ExecuteActivity("postViews"); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(views) != len(tt.thumbnails) {
+				t.Fatalf("got %d views, want %d", len(views), len(tt.thumbnails))
+			}
+			for i, view := range views {
+				if string(view.Thumbnail) != tt.thumbnails[i] {
+					t.Errorf("thumbnail of %s = %q, want %q", view.ID, view.Thumbnail, tt.thumbnails[i])
+				}
+			}
+
+			if len(attachments) != tt.attachments {
+				t.Fatalf("got %d attachments, want %d", len(attachments), tt.attachments)
+			}
+			for _, a := range attachments {
+				if a.ContentType != "image/png" || !strings.HasSuffix(a.Filename, ".png") || !bytes.Equal(a.Data, testPNG) {
+					t.Errorf("unexpected attachment %s (%s, %d bytes)", a.Filename, a.ContentType, len(a.Data))
+				}
+			}
+		})
+	}
+}
@@ -76,9 +76,11 @@ func DigestWorkflow(ctx workflow.Context, in *DigestWorkflowInput) (*DigestWorkf
 		configuration.Subreddits[i].Before = result.Before
 	}
 
+	// Chat channels pace the messages of a digest to stay within their rate limits, which takes a while for long
+	// digests sent to several recipients.
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		TaskQueue:           "digest",
-		StartToCloseTimeout: time.Minute,
+		StartToCloseTimeout: 5 * time.Minute,
 		RetryPolicy: &temporal.RetryPolicy{
 			InitialInterval:    5 * time.Second,
 			BackoffCoefficient: 2.0,
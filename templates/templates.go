@@ -0,0 +1,237 @@
+// Package templates ships the default email templates with the binary, so the digest worker doesn't depend on its
+// working directory, and defines what custom templates of a schedule are rendered with.
+package templates
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/forbiddencoding/reddit-post-notifier/common/persistence"
+	"html/template"
+	"io"
+	"sync"
+	"time"
+)
+
+// Names of the templates that can be replaced. EmailTemplate renders the whole digest and calls PostTemplate for every
+// post of it.
+const (
+	EmailTemplate = "email"
+	PostTemplate  = "post"
+)
+
+// FS holds the default templates. index.html defines the "email" template, post.html the "post" block it renders
+// every post with.
+//
+//go:embed *.html
+var FS embed.FS
+
+// Limits of a single render. Custom templates are user input, a loop over a large range must not tie up the API or
+// the worker.
+const (
+	MaxRenderTime   = 5 * time.Second
+	MaxRenderOutput = 1 << 20
+)
+
+var (
+	// ErrNoTemplates is returned for custom templates that define neither "email" nor "post".
+	ErrNoTemplates = errors.New(`template has to define "email" or "post"`)
+	// ErrRenderTimeout is returned if a template takes longer than MaxRenderTime.
+	ErrRenderTimeout = errors.New("template took too long to render")
+	// ErrOutputTooLarge is returned if a template renders more than MaxRenderOutput bytes.
+	ErrOutputTooLarge = errors.New("template output is too large")
+)
+
+type (
+	// Digest is the data EmailTemplate is executed with.
+	Digest struct {
+		Title string
+		Posts []*Post
+	}
+
+	// Post is a post as rendered by PostTemplate. Thumbnail shadows the field of the embedded post, it's either the
+	// original https URL or a cid: URL of an embedded image.
+	Post struct {
+		persistence.Post
+		Thumbnail template.URL
+	}
+)
+
+// Default parses the embedded templates.
+func Default() (*template.Template, error) {
+	return template.ParseFS(FS, "*.html")
+}
+
+// Extend parses the custom template source on top of a clone of base. Every template the source defines replaces the
+// one of base with the same name, so it only has to define the templates it changes. base is left untouched, it must
+// not have been executed.
+func Extend(base *template.Template, source string) (*template.Template, error) {
+	custom, err := template.New("custom").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if custom.Lookup(EmailTemplate) == nil && custom.Lookup(PostTemplate) == nil {
+		return nil, ErrNoTemplates
+	}
+
+	tmpl, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl.New("custom").Parse(source)
+}
+
+// Render executes the digest template of tmpl, for at most MaxRenderTime and MaxRenderOutput bytes. Templates can't be
+// interrupted, so one that runs out of time is abandoned. It stops at its next write, nothing reaches w after Render
+// returned.
+func Render(ctx context.Context, w io.Writer, tmpl *template.Template, digest *Digest) error {
+	ctx, cancel := context.WithTimeout(ctx, MaxRenderTime)
+	defer cancel()
+
+	lw := &limitedWriter{ctx: ctx, w: w, remaining: MaxRenderOutput}
+	defer lw.close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.ExecuteTemplate(lw, EmailTemplate, digest)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrRenderTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+// limitedWriter is the writer templates are executed with. It fails the execution once the output is too large or the
+// render was given up on.
+type limitedWriter struct {
+	ctx       context.Context
+	w         io.Writer
+	remaining int
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrRenderTimeout
+	}
+	if err := l.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > l.remaining {
+		return 0, ErrOutputTooLarge
+	}
+
+	l.remaining -= len(p)
+	return l.w.Write(p)
+}
+
+func (l *limitedWriter) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+}
+
+// Validate renders tmpl with the sample digest and with an empty one, so templates that only fail for some posts or
+// without any are rejected before they're used.
+func Validate(ctx context.Context, tmpl *template.Template) error {
+	sample := Sample()
+	if err := Render(ctx, io.Discard, tmpl, sample); err != nil {
+		return fmt.Errorf("render sample digest: %w", err)
+	}
+	if err := Render(ctx, io.Discard, tmpl, &Digest{Title: sample.Title}); err != nil {
+		return fmt.Errorf("render empty digest: %w", err)
+	}
+
+	return nil
+}
+
+// Sample returns a digest with posts covering every case of the default templates: with and without thumbnail, NSFW,
+// spoiler and a title long enough to wrap.
+func Sample() *Digest {
+	return &Digest{
+		Title: "New Reddit Posts Notification",
+		Posts: []*Post{
+			{
+				Post: persistence.Post{
+					ID:        "1o2abcd",
+					Title:     "Example post with a thumbnail",
+					URL:       "https://example.com/image.png",
+					Subreddit: "example",
+					Ups:       1204,
+					Created:   "17 Oct 26 09:12 UTC",
+					Permalink: "https://www.reddit.com/r/example/comments/1o2abcd/example_post_with_a_thumbnail/",
+				},
+				Thumbnail: "https://example.com/thumbnail.jpg",
+			},
+			{
+				Post: persistence.Post{
+					ID:        "1o2efgh",
+					Title:     "Example text post without a thumbnail",
+					URL:       "https://www.reddit.com/r/example/comments/1o2efgh/example_text_post_without_a_thumbnail/",
+					Subreddit: "example",
+					Ups:       87,
+					Downs:     3,
+					Created:   "17 Oct 26 10:40 UTC",
+					Permalink: "https://www.reddit.com/r/example/comments/1o2efgh/example_text_post_without_a_thumbnail/",
+				},
+			},
+			{
+				Post: persistence.Post{
+					ID:        "1o2ijkl",
+					Title:     "Example NSFW post",
+					URL:       "https://example.com/image.png",
+					Subreddit: "example",
+					NSFW:      true,
+					Ups:       15,
+					Downs:     2,
+					Created:   "17 Oct 26 11:05 UTC",
+					Permalink: "https://www.reddit.com/r/example/comments/1o2ijkl/example_nsfw_post/",
+				},
+				Thumbnail: "https://example.com/thumbnail.jpg",
+			},
+			{
+				Post: persistence.Post{
+					ID:        "1o2mnop",
+					Title:     "Example spoiler post",
+					URL:       "https://example.com/image.png",
+					Subreddit: "example",
+					Spoiler:   true,
+					Ups:       42,
+					Created:   "17 Oct 26 11:30 UTC",
+					Permalink: "https://www.reddit.com/r/example/comments/1o2mnop/example_spoiler_post/",
+				},
+				Thumbnail: "https://example.com/thumbnail.jpg",
+			},
+			{
+				Post: persistence.Post{
+					ID: "1o2qrst",
+					Title: "Example post with a very long title, as long as Reddit allows, to check how the template " +
+						"wraps titles that don't fit on a single line, on small screens as well as on large ones, and " +
+						"that nothing next to the title is pushed out of view or overlaps it when it spans several lines",
+					URL:       "https://www.reddit.com/r/example/comments/1o2qrst/example_post_with_a_very_long_title/",
+					Subreddit: "example",
+					NSFW:      true,
+					Spoiler:   true,
+					Ups:       3,
+					Downs:     1,
+					Created:   "17 Oct 26 12:15 UTC",
+					Permalink: "https://www.reddit.com/r/example/comments/1o2qrst/example_post_with_a_very_long_title/",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,150 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtend(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr bool
+		is      error
+	}{
+		{
+			name:   "post only",
+			source: `{{define "post"}}<p class="custom">{{.Title}}</p>{{end}}`,
+			want:   `<p class="custom">Example post with a thumbnail</p>`,
+		},
+		{
+			name:   "email only",
+			source: `{{define "email"}}<h1>{{.Title}}</h1>{{range .Posts}}{{template "post" .}}{{end}}{{end}}`,
+			want:   "<h1>New Reddit Posts Notification</h1>",
+		},
+		{
+			name:    "nothing replaced",
+			source:  `{{define "footer"}}footer{{end}}`,
+			wantErr: true,
+			is:      ErrNoTemplates,
+		},
+		{
+			name:    "syntax error",
+			source:  `{{define "post"}}{{.Title}{{end}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := Default()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tmpl, err := Extend(base, tt.source)
+			if tt.wantErr {
+				if err == nil || (tt.is != nil && !errors.Is(err, tt.is)) {
+					t.Fatalf("Extend() error = %v, want %v", err, tt.is)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var custom bytes.Buffer
+			if err = Render(context.Background(), &custom, tmpl, Sample()); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(custom.String(), tt.want) {
+				t.Errorf("output doesn't contain %q", tt.want)
+			}
+
+			// The defaults are cloned, not changed.
+			var def bytes.Buffer
+			if err = Render(context.Background(), &def, base, Sample()); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(def.String(), tt.want) {
+				t.Error("base template was changed")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{name: "valid", source: `{{define "post"}}<p>{{.Title}}</p>{{end}}`},
+		{name: "unknown field", source: `{{define "post"}}{{.Author}}{{end}}`, wantErr: "render sample digest"},
+		{
+			// Only fails for a digest without posts.
+			name:    "first post",
+			source:  `{{define "email"}}{{(index .Posts 0).Title}}{{end}}`,
+			wantErr: "render empty digest",
+		},
+		{
+			name:    "output too large",
+			source:  `{{define "email"}}{{range 10000000}}0123456789{{end}}{{end}}`,
+			wantErr: ErrOutputTooLarge.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := Default()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := Extend(base, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = Validate(context.Background(), tmpl)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	base, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The loop doesn't write anything, only the deadline stops it.
+	tmpl, err := Extend(base, `{{define "email"}}{{range 1000000000}}{{end}}done{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out bytes.Buffer
+	start := time.Now()
+	if err = Render(ctx, &out, tmpl, Sample()); !errors.Is(err, ErrRenderTimeout) {
+		t.Fatalf("Render() error = %v, want %v", err, ErrRenderTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Render() returned after %s", elapsed)
+	}
+	if out.Len() != 0 {
+		t.Errorf("abandoned render wrote %q", out.String())
+	}
+}